@@ -0,0 +1,115 @@
+package cabundle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// FilePublisher publishes a CA bundle read from a PEM file, e.g. the ca.crt
+// of a certificate mounted by the cert-manager CSI driver, to the
+// ca-bundle.crt key of a configmap, from which it is synced like a generated
+// CA bundle.
+//
+// The file is polled and published whenever it differs from the configmap.
+type FilePublisher struct {
+	client        kubernetes.Interface
+	namespace     string
+	configMapName string
+	file          string
+	interval      time.Duration
+	clock         clock.WithTicker
+}
+
+// NewFilePublisher creates a publisher that reads file every interval, which
+// must be positive, and writes it to the named configmap.
+func NewFilePublisher(client kubernetes.Interface, namespace, configMapName, file string, interval time.Duration) *FilePublisher {
+	return &FilePublisher{
+		client:        client,
+		namespace:     namespace,
+		configMapName: configMapName,
+		file:          file,
+		interval:      interval,
+		clock:         clock.RealClock{},
+	}
+}
+
+// UseClock sets the clock that drives polling. Defaults to the real clock.
+// Must be called before Start.
+func (p *FilePublisher) UseClock(clk clock.WithTicker) {
+	p.clock = clk
+}
+
+// Start publishes the CA bundle and polls the file for changes until ctx is cancelled.
+func (p *FilePublisher) Start(ctx context.Context) error {
+	if err := p.publish(ctx); err != nil {
+		klog.Warningf("Initial CA bundle publish failed (will retry): %v", err)
+	}
+
+	klog.Infof("CA bundle publisher started watching file %s", p.file)
+
+	ticker := p.clock.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			if err := p.publish(ctx); err != nil {
+				klog.Warningf("CA bundle publish failed (will retry): %v", err)
+			}
+		}
+	}
+}
+
+// publish writes the CA bundle in the file to the configmap unless it already
+// holds it, so that a configmap that was edited or deleted is repaired on the
+// next poll. The published CA bundle is kept if the file is missing or holds
+// no valid certificates.
+func (p *FilePublisher) publish(ctx context.Context) error {
+	caBundle, err := os.ReadFile(p.file)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file: %w", err)
+	}
+	if _, err := cert.ParseCertsPEM(caBundle); err != nil {
+		return fmt.Errorf("failed to parse CA bundle from %s: %w", p.file, err)
+	}
+
+	cm, err := p.client.CoreV1().ConfigMaps(p.namespace).Get(ctx, p.configMapName, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: p.configMapName, Namespace: p.namespace},
+			Data:       map[string]string{ConfigMapKey: string(caBundle)},
+		}
+		if _, err := p.client.CoreV1().ConfigMaps(p.namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create configmap %s/%s: %w", p.namespace, p.configMapName, err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get configmap %s/%s: %w", p.namespace, p.configMapName, err)
+	case cm.Data[ConfigMapKey] == string(caBundle):
+		return nil
+	default:
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[ConfigMapKey] = string(caBundle)
+		if _, err := p.client.CoreV1().ConfigMaps(p.namespace).Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update configmap %s/%s: %w", p.namespace, p.configMapName, err)
+		}
+	}
+
+	klog.Infof("CA bundle published from %s to configmap %s/%s", p.file, p.namespace, p.configMapName)
+	return nil
+}
@@ -0,0 +1,197 @@
+package cabundle
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/crypto"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestFilePublisher_publish(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	client := fake.NewClientset()
+	publisher := NewFilePublisher(client, "test-ns", "ca-bundle", caFile, time.Second)
+	ctx := context.Background()
+
+	// Missing file
+	if err := publisher.publish(ctx); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	caBundle1 := newTestCABundle(t)
+	writeTestFile(t, caFile, caBundle1)
+	if err := publisher.publish(ctx); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if got := getConfigMapCABundle(t, client); got != string(caBundle1) {
+		t.Errorf("CA bundle after create: got %q, want %q", got, caBundle1)
+	}
+
+	// Invalid content keeps the published CA bundle
+	writeTestFile(t, caFile, []byte("not a certificate"))
+	if err := publisher.publish(ctx); err == nil {
+		t.Error("Expected error for invalid CA bundle")
+	}
+	if got := getConfigMapCABundle(t, client); got != string(caBundle1) {
+		t.Errorf("CA bundle after invalid file: got %q, want %q", got, caBundle1)
+	}
+
+	caBundle2 := newTestCABundle(t)
+	writeTestFile(t, caFile, caBundle2)
+	if err := publisher.publish(ctx); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if got := getConfigMapCABundle(t, client); got != string(caBundle2) {
+		t.Errorf("CA bundle after update: got %q, want %q", got, caBundle2)
+	}
+}
+
+func TestFilePublisher_publish_ExistingConfigMap(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caBundle := newTestCABundle(t)
+	writeTestFile(t, caFile, caBundle)
+
+	client := fake.NewClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", Namespace: "test-ns"},
+	})
+	publisher := NewFilePublisher(client, "test-ns", "ca-bundle", caFile, time.Second)
+
+	if err := publisher.publish(context.Background()); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if got := getConfigMapCABundle(t, client); got != string(caBundle) {
+		t.Errorf("CA bundle: got %q, want %q", got, caBundle)
+	}
+}
+
+func TestFilePublisher_publish_Repair(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caBundle := newTestCABundle(t)
+	writeTestFile(t, caFile, caBundle)
+
+	client := fake.NewClientset()
+	publisher := NewFilePublisher(client, "test-ns", "ca-bundle", caFile, time.Second)
+	ctx := context.Background()
+	if err := publisher.publish(ctx); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	// Unchanged configmap is not written again
+	client.ClearActions()
+	if err := publisher.publish(ctx); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("Unexpected %s of an up to date configmap", action.GetVerb())
+		}
+	}
+
+	// Manual edit
+	cm, err := client.CoreV1().ConfigMaps("test-ns").Get(ctx, "ca-bundle", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get configmap: %v", err)
+	}
+	cm.Data[ConfigMapKey] = "edited"
+	if _, err := client.CoreV1().ConfigMaps("test-ns").Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update configmap: %v", err)
+	}
+	if err := publisher.publish(ctx); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if got := getConfigMapCABundle(t, client); got != string(caBundle) {
+		t.Errorf("CA bundle after edit: got %q, want %q", got, caBundle)
+	}
+
+	// Deletion
+	if err := client.CoreV1().ConfigMaps("test-ns").Delete(ctx, "ca-bundle", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete configmap: %v", err)
+	}
+	if err := publisher.publish(ctx); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if got := getConfigMapCABundle(t, client); got != string(caBundle) {
+		t.Errorf("CA bundle after delete: got %q, want %q", got, caBundle)
+	}
+}
+
+func TestFilePublisher_Start_Clock(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	caBundle1 := newTestCABundle(t)
+	writeTestFile(t, caFile, caBundle1)
+
+	client := fake.NewClientset()
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	publisher := NewFilePublisher(client, "test-ns", "ca-bundle", caFile, time.Hour)
+	publisher.UseClock(fakeClock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- publisher.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		if err := <-errCh; err != nil {
+			t.Errorf("Start returned error: %v", err)
+		}
+	}()
+
+	// The ticker is created after the initial publish
+	deadline := time.Now().Add(5 * time.Second)
+	for !fakeClock.HasWaiters() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := getConfigMapCABundle(t, client); got != string(caBundle1) {
+		t.Errorf("CA bundle after start: got %q, want %q", got, caBundle1)
+	}
+
+	caBundle2 := newTestCABundle(t)
+	writeTestFile(t, caFile, caBundle2)
+	fakeClock.Step(time.Hour)
+
+	deadline = time.Now().Add(5 * time.Second)
+	for getConfigMapCABundle(t, client) != string(caBundle2) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the CA bundle to be published")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newTestCABundle(t *testing.T) []byte {
+	t.Helper()
+	ca, err := crypto.MakeSelfSignedCAConfigForDuration("test-ca", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	caBundle, err := crypto.EncodeCertificates(ca.Certs...)
+	if err != nil {
+		t.Fatalf("Failed to encode CA: %v", err)
+	}
+	return caBundle
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func getConfigMapCABundle(t *testing.T, client *fake.Clientset) string {
+	t.Helper()
+	cm, err := client.CoreV1().ConfigMaps("test-ns").Get(context.Background(), "ca-bundle", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get configmap: %v", err)
+	}
+	return cm.Data[ConfigMapKey]
+}
@@ -0,0 +1,213 @@
+package cabundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// CustomResourceDefinitionResource is the apiextensions.k8s.io/v1 CustomResourceDefinition resource.
+	CustomResourceDefinitionResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+	// APIServiceResource is the apiregistration.k8s.io/v1 APIService resource.
+	APIServiceResource = schema.GroupVersionResource{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}
+)
+
+// resourceKinds maps the webhook types patched through the dynamic client to
+// the kind and resource of their objects.
+//
+// CustomResourceDefinitions and APIServices are patched through the dynamic
+// client rather than the typed apiextensions-apiserver and kube-aggregator
+// clientsets. The typed clientsets would pin two more k8s.io modules to the
+// client-go version for two fields. The dynamic client also backs the
+// cert-manager resources, so a single WithDynamicClient option and fake client
+// cover all of them.
+var resourceKinds = map[WebhookType]struct {
+	kind     string
+	resource schema.GroupVersionResource
+}{
+	CRDConversionWebhook: {kind: "CustomResourceDefinition", resource: CustomResourceDefinitionResource},
+	APIServiceWebhook:    {kind: "APIService", resource: APIServiceResource},
+}
+
+// isResourceType reports whether objects of webhookType are patched through
+// the dynamic client.
+func isResourceType(webhookType WebhookType) bool {
+	_, ok := resourceKinds[webhookType]
+	return ok
+}
+
+// resourceTypeOf returns the webhook type of obj, or false if obj is not a
+// kind patched through the dynamic client.
+func resourceTypeOf(obj *unstructured.Unstructured) (WebhookType, bool) {
+	for webhookType, kind := range resourceKinds {
+		if obj.GetKind() == kind.kind && obj.GroupVersionKind().Group == kind.resource.Group {
+			return webhookType, true
+		}
+	}
+	return "", false
+}
+
+// resourceTarget is the caBundle field of a CustomResourceDefinition or
+// APIService.
+type resourceTarget struct {
+	// caBundleFields is the path of the caBundle field.
+	caBundleFields []string
+	// caBundle is the current value of the caBundle field.
+	caBundle []byte
+}
+
+// resourceTargetOf returns the caBundle field of obj, or an error if obj does
+// not reference the matched service.
+func (s *Syncer) resourceTargetOf(webhookType WebhookType, obj *unstructured.Unstructured) (*resourceTarget, error) {
+	switch webhookType {
+	case CRDConversionWebhook:
+		return s.conversionTarget(obj)
+	case APIServiceWebhook:
+		return s.apiServiceTarget(obj)
+	default:
+		return nil, fmt.Errorf("unknown webhook type: %s", webhookType)
+	}
+}
+
+// conversionTarget returns the conversion webhook caBundle field of a
+// CustomResourceDefinition. A conversion webhook using a URL is always targeted.
+func (s *Syncer) conversionTarget(crd *unstructured.Unstructured) (*resourceTarget, error) {
+	strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+	if strategy != "Webhook" {
+		return nil, fmt.Errorf("CustomResourceDefinition %s does not use a conversion webhook", crd.GetName())
+	}
+
+	clientConfig := []string{"spec", "conversion", "webhook", "clientConfig"}
+	if service, found, _ := unstructured.NestedMap(crd.Object, append(clientConfig, "service")...); found {
+		if !s.matchesService(service) {
+			return nil, fmt.Errorf("CustomResourceDefinition %s does not reference service %s/%s", crd.GetName(), s.namespace, s.serviceName)
+		}
+	} else if _, found, _ := unstructured.NestedString(crd.Object, append(clientConfig, "url")...); !found {
+		return nil, fmt.Errorf("CustomResourceDefinition %s has no conversion webhook client config", crd.GetName())
+	}
+
+	caBundleFields := append(clientConfig, "caBundle")
+	caBundle, err := nestedBytes(crd, caBundleFields...)
+	if err != nil {
+		return nil, err
+	}
+	return &resourceTarget{caBundleFields: caBundleFields, caBundle: caBundle}, nil
+}
+
+// apiServiceTarget returns the caBundle field of an APIService.
+func (s *Syncer) apiServiceTarget(apiService *unstructured.Unstructured) (*resourceTarget, error) {
+	service, found, _ := unstructured.NestedMap(apiService.Object, "spec", "service")
+	if !found {
+		return nil, fmt.Errorf("APIService %s is served locally", apiService.GetName())
+	}
+	if !s.matchesService(service) {
+		return nil, fmt.Errorf("APIService %s does not reference service %s/%s", apiService.GetName(), s.namespace, s.serviceName)
+	}
+	if skip, _, _ := unstructured.NestedBool(apiService.Object, "spec", "insecureSkipTLSVerify"); skip {
+		return nil, fmt.Errorf("APIService %s sets insecureSkipTLSVerify, which does not allow a caBundle", apiService.GetName())
+	}
+
+	caBundle, err := nestedBytes(apiService, "spec", "caBundle")
+	if err != nil {
+		return nil, err
+	}
+	return &resourceTarget{caBundleFields: []string{"spec", "caBundle"}, caBundle: caBundle}, nil
+}
+
+// matchesService reports whether the service reference of an unstructured
+// object references the matched service.
+func (s *Syncer) matchesService(service map[string]interface{}) bool {
+	if s.serviceName == "" {
+		return true
+	}
+	return service["namespace"] == s.namespace && service["name"] == s.serviceName
+}
+
+// nestedBytes returns the base64 encoded bytes field of obj at fields, or nil
+// if it is not set.
+func nestedBytes(obj *unstructured.Unstructured, fields ...string) ([]byte, error) {
+	value, found, err := unstructured.NestedString(obj.Object, fields...)
+	if err != nil || !found {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s of %s %s: %w", strings.Join(fields, "."), obj.GetKind(), obj.GetName(), err)
+	}
+	return data, nil
+}
+
+// resourceApplyConfiguration returns the apply configuration setting the
+// caBundle of target in current. It carries the resourceVersion of current,
+// so the apply fails instead of trusting another webhook backend if the
+// object changed since its service reference was checked.
+func resourceApplyConfiguration(current *unstructured.Unstructured, target *resourceTarget, caBundle []byte) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(current.GetAPIVersion())
+	obj.SetKind(current.GetKind())
+	obj.SetName(current.GetName())
+	obj.SetResourceVersion(current.GetResourceVersion())
+	if err := unstructured.SetNestedField(obj.Object, base64.StdEncoding.EncodeToString(caBundle), target.caBundleFields...); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// patchResource applies the caBundle field of a CustomResourceDefinition or APIService.
+func (s *Syncer) patchResource(ctx context.Context, ref WebhookRef, caBundle []byte) (bool, error) {
+	if s.dynamicClient == nil {
+		return false, fmt.Errorf("a dynamic client is required to patch %s %s", ref.Type.Kind(), ref.Name)
+	}
+	client := s.dynamicClient.Resource(resourceKinds[ref.Type].resource)
+
+	current, err := client.Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			klog.V(4).Infof("%s %s not found", ref.Type.Kind(), ref.Name)
+			return false, nil
+		}
+		return false, err
+	}
+
+	target, err := s.resourceTargetOf(ref.Type, current)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(target.caBundle, caBundle) {
+		return false, nil
+	}
+	config, err := resourceApplyConfiguration(current, target, caBundle)
+	if err != nil {
+		return false, fmt.Errorf("failed to build apply configuration: %w", err)
+	}
+
+	return true, s.applyCABundle(ref, func(opts metav1.ApplyOptions) error {
+		_, err := client.Apply(ctx, ref.Name, config, opts)
+		return err
+	})
+}
+
+// resourceInformers returns the informers watching the kinds of the
+// referenced CustomResourceDefinitions and APIServices.
+func (s *Syncer) resourceInformers(factory dynamicinformer.DynamicSharedInformerFactory) []cache.SharedIndexInformer {
+	var result []cache.SharedIndexInformer
+	for _, webhookType := range []WebhookType{CRDConversionWebhook, APIServiceWebhook} {
+		if slices.ContainsFunc(s.webhookRefs, func(ref WebhookRef) bool { return ref.Type == webhookType }) {
+			result = append(result, factory.ForResource(resourceKinds[webhookType].resource).Informer())
+		}
+	}
+	return result
+}
@@ -0,0 +1,375 @@
+package cabundle
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/clock"
+
+	"github.com/jimyag/auto-cert-webhook/internal/dynamictest"
+)
+
+func newFakeDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamictest.NewClient(map[schema.GroupVersionResource]string{
+		CustomResourceDefinitionResource: "CustomResourceDefinition",
+		APIServiceResource:               "APIService",
+	}, objects...)
+}
+
+func newTestCRD(name string, clientConfig map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec": map[string]interface{}{
+			"conversion": map[string]interface{}{
+				"strategy": "Webhook",
+				"webhook": map[string]interface{}{
+					"clientConfig":             clientConfig,
+					"conversionReviewVersions": []interface{}{"v1"},
+				},
+			},
+		},
+	}}
+}
+
+func newTestAPIService(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiregistration.k8s.io/v1",
+		"kind":       "APIService",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       spec,
+	}}
+}
+
+func testService(namespace, name string) map[string]interface{} {
+	return map[string]interface{}{"namespace": namespace, "name": name, "port": int64(443)}
+}
+
+func getCABundle(t *testing.T, client *dynamicfake.FakeDynamicClient, gvr schema.GroupVersionResource, name string, fields ...string) string {
+	t.Helper()
+
+	obj, err := client.Resource(gvr).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get %s %s: %v", gvr.Resource, name, err)
+	}
+	value, _, err := unstructured.NestedString(obj.Object, fields...)
+	if err != nil {
+		t.Fatalf("Invalid caBundle: %v", err)
+	}
+	caBundle, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatalf("Invalid caBundle encoding: %v", err)
+	}
+	return string(caBundle)
+}
+
+var conversionCABundle = []string{"spec", "conversion", "webhook", "clientConfig", "caBundle"}
+
+func TestSyncer_patchResource(t *testing.T) {
+	dynamicClient := newFakeDynamicClient(
+		newTestCRD("widgets.example.com", map[string]interface{}{"service": testService("test-ns", "test-svc")}),
+		newTestCRD("gadgets.example.com", map[string]interface{}{"url": "https://webhook.example.com/convert"}),
+		newTestCRD("others.example.com", map[string]interface{}{"service": testService("test-ns", "other-svc")}),
+		newTestAPIService("v1.metrics.example.com", map[string]interface{}{"service": testService("test-ns", "test-svc")}),
+		newTestAPIService("v1.local.example.com", map[string]interface{}{}),
+	)
+	syncer := NewSyncer(fake.NewClientset(), "test-ns", "ca-bundle", nil)
+	syncer.UseDynamicClient(dynamicClient)
+	syncer.MatchService("test-svc")
+
+	tests := []struct {
+		name    string
+		ref     WebhookRef
+		gvr     schema.GroupVersionResource
+		fields  []string
+		wantErr bool
+	}{
+		{
+			name:   "conversion webhook referencing the service",
+			ref:    WebhookRef{Name: "widgets.example.com", Type: CRDConversionWebhook},
+			gvr:    CustomResourceDefinitionResource,
+			fields: conversionCABundle,
+		},
+		{
+			name:   "conversion webhook using a URL",
+			ref:    WebhookRef{Name: "gadgets.example.com", Type: CRDConversionWebhook},
+			gvr:    CustomResourceDefinitionResource,
+			fields: conversionCABundle,
+		},
+		{
+			name:    "conversion webhook referencing another service",
+			ref:     WebhookRef{Name: "others.example.com", Type: CRDConversionWebhook},
+			wantErr: true,
+		},
+		{
+			name:   "APIService referencing the service",
+			ref:    WebhookRef{Name: "v1.metrics.example.com", Type: APIServiceWebhook},
+			gvr:    APIServiceResource,
+			fields: []string{"spec", "caBundle"},
+		},
+		{
+			name:    "local APIService",
+			ref:     WebhookRef{Name: "v1.local.example.com", Type: APIServiceWebhook},
+			wantErr: true,
+		},
+		{
+			name: "not found",
+			ref:  WebhookRef{Name: "missing.example.com", Type: CRDConversionWebhook},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := syncer.patchWebhook(context.Background(), tt.ref, []byte("new-ca"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("patchWebhook error: got %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.fields == nil {
+				return
+			}
+			if got := getCABundle(t, dynamicClient, tt.gvr, tt.ref.Name, tt.fields...); got != "new-ca" {
+				t.Errorf("caBundle: got %q, want %q", got, "new-ca")
+			}
+
+			// An up-to-date caBundle is not patched again.
+			patched, err := syncer.patchWebhook(context.Background(), tt.ref, []byte("new-ca"))
+			if err != nil || patched {
+				t.Errorf("patchWebhook of an up-to-date caBundle: got (%v, %v), want (false, nil)", patched, err)
+			}
+		})
+	}
+}
+
+func TestSyncer_patchResource_NoDynamicClient(t *testing.T) {
+	syncer := NewSyncer(fake.NewClientset(), "test-ns", "ca-bundle", nil)
+
+	ref := WebhookRef{Name: "widgets.example.com", Type: CRDConversionWebhook}
+	if _, err := syncer.patchWebhook(context.Background(), ref, []byte("ca")); err == nil {
+		t.Error("Expected error without a dynamic client")
+	}
+}
+
+func TestSyncer_patchResource_ServerSideApply(t *testing.T) {
+	crd := newTestCRD("widgets.example.com", map[string]interface{}{"service": testService("test-ns", "test-svc")})
+	crd.SetResourceVersion("42")
+	dynamicClient := newFakeDynamicClient(crd)
+	syncer := NewSyncer(fake.NewClientset(), "test-ns", "ca-bundle", nil)
+	syncer.UseDynamicClient(dynamicClient)
+	recorder := events.NewInMemoryRecorder("test", clock.RealClock{})
+	syncer.eventRecorder = recorder
+
+	var applies []clienttesting.PatchActionImpl
+	dynamicClient.PrependReactor("patch", "customresourcedefinitions", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		apply := action.(clienttesting.PatchActionImpl)
+		applies = append(applies, apply)
+		return false, nil, nil
+	})
+
+	ref := WebhookRef{Name: "widgets.example.com", Type: CRDConversionWebhook}
+	if _, err := syncer.patchWebhook(context.Background(), ref, []byte("ca")); err != nil {
+		t.Fatalf("patchWebhook failed: %v", err)
+	}
+	if len(applies) != 1 {
+		t.Fatalf("Expected a single apply, got %d", len(applies))
+	}
+	apply := applies[0]
+	if apply.GetPatchType() != types.ApplyPatchType || apply.PatchOptions.FieldManager != FieldManager {
+		t.Errorf("Expected an apply by %q, got %s by %q", FieldManager, apply.GetPatchType(), apply.PatchOptions.FieldManager)
+	}
+
+	// The apply configuration only holds the caBundle and is guarded by the
+	// resourceVersion of the object whose service reference was checked.
+	var config unstructured.Unstructured
+	if err := config.UnmarshalJSON(apply.GetPatch()); err != nil {
+		t.Fatalf("Invalid apply configuration: %v", err)
+	}
+	if got := config.GetResourceVersion(); got != "42" {
+		t.Errorf("resourceVersion: got %q, want %q", got, "42")
+	}
+	spec, _, _ := unstructured.NestedMap(config.Object, "spec")
+	want := map[string]interface{}{"conversion": map[string]interface{}{"webhook": map[string]interface{}{
+		"clientConfig": map[string]interface{}{"caBundle": base64.StdEncoding.EncodeToString([]byte("ca"))},
+	}}}
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("spec: got %v, want %v", spec, want)
+	}
+	if recorded := recorder.Events(); len(recorded) != 0 {
+		t.Errorf("Expected no events without a conflict, got %v", recorded)
+	}
+}
+
+func TestSyncer_patchResource_Conflict(t *testing.T) {
+	fieldManagerConflict := apierrors.NewApplyConflict([]metav1.StatusCause{{
+		Type:  metav1.CauseTypeFieldManagerConflict,
+		Field: ".spec.caBundle",
+	}}, `conflict with "gitops"`)
+	staleResourceVersion := apierrors.NewConflict(APIServiceResource.GroupResource(), "v1.metrics.example.com", fmt.Errorf("the object has been modified"))
+
+	tests := []struct {
+		name       string
+		err        error
+		wantErr    bool
+		wantForced bool
+	}{
+		{name: "caBundle owned by another field manager", err: fieldManagerConflict, wantForced: true},
+		{name: "stale resourceVersion", err: staleResourceVersion, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamicClient := newFakeDynamicClient(newTestAPIService("v1.metrics.example.com", map[string]interface{}{"service": testService("test-ns", "test-svc")}))
+			syncer := NewSyncer(fake.NewClientset(), "test-ns", "ca-bundle", nil)
+			syncer.UseDynamicClient(dynamicClient)
+			recorder := events.NewInMemoryRecorder("test", clock.RealClock{})
+			syncer.eventRecorder = recorder
+
+			var forced bool
+			dynamicClient.PrependReactor("patch", "apiservices", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				force := action.(clienttesting.PatchActionImpl).PatchOptions.Force
+				if force == nil || !*force {
+					return true, nil, tt.err
+				}
+				forced = true
+				return false, nil, nil
+			})
+
+			ref := WebhookRef{Name: "v1.metrics.example.com", Type: APIServiceWebhook}
+			_, err := syncer.patchWebhook(context.Background(), ref, []byte("ca"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("patchWebhook error: got %v, wantErr %v", err, tt.wantErr)
+			}
+			if forced != tt.wantForced {
+				t.Errorf("forced apply: got %v, want %v", forced, tt.wantForced)
+			}
+			wantEvents := 0
+			if tt.wantForced {
+				wantEvents = 1
+			}
+			if recorded := recorder.Events(); len(recorded) != wantEvents {
+				t.Errorf("Expected %d CABundleConflict events, got %v", wantEvents, recorded)
+			}
+		})
+	}
+}
+
+func TestSyncer_onWebhookConfigUpdate_Resource(t *testing.T) {
+	apiService := newTestAPIService("v1.metrics.example.com", map[string]interface{}{"service": testService("test-ns", "test-svc")})
+	dynamicClient := newFakeDynamicClient(apiService)
+	refs := []WebhookRef{{Name: "v1.metrics.example.com", Type: APIServiceWebhook}}
+	syncer := NewSyncer(fake.NewClientset(), "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
+	syncer.UseDynamicClient(dynamicClient)
+	recorder := events.NewInMemoryRecorder("test", clock.RealClock{})
+	syncer.eventRecorder = recorder
+	var synced int
+	syncer.OnSynced(func(WebhookRef, error) { synced++ })
+
+	ctx := context.Background()
+	syncer.onCABundle(ctx, []byte("ca"))
+	processQueue(ctx, syncer)
+	if synced != 1 {
+		t.Fatalf("syncs after the CA bundle is known: got %d, want 1", synced)
+	}
+
+	current, err := dynamicClient.Resource(APIServiceResource).Get(ctx, "v1.metrics.example.com", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get APIService: %v", err)
+	}
+	syncer.onWebhookConfigUpdate(ctx, current)
+	processQueue(ctx, syncer)
+	if synced != 1 {
+		t.Fatalf("syncs for an up-to-date APIService: got %d, want 1", synced)
+	}
+
+	unstructured.RemoveNestedField(current.Object, "spec", "caBundle")
+	if _, err := dynamicClient.Resource(APIServiceResource).Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update APIService: %v", err)
+	}
+	syncer.onWebhookConfigUpdate(ctx, current)
+	processQueue(ctx, syncer)
+	if synced != 2 {
+		t.Fatalf("syncs after drift: got %d, want 2", synced)
+	}
+	if got := getCABundle(t, dynamicClient, APIServiceResource, "v1.metrics.example.com", "spec", "caBundle"); got != "ca" {
+		t.Errorf("caBundle: got %q, want %q", got, "ca")
+	}
+	if recorded := recorder.Events(); len(recorded) != 1 || recorded[0].Reason != "CABundleDriftRepaired" {
+		t.Errorf("Expected a single CABundleDriftRepaired event, got %v", recorded)
+	}
+}
+
+func TestSyncer_Start_RepairsResourceDrift(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", Namespace: "test-ns"},
+		Data:       map[string]string{"ca-bundle.crt": "test-ca-bundle-data"},
+	}
+	dynamicClient := newFakeDynamicClient(newTestCRD("widgets.example.com", map[string]interface{}{"service": testService("test-ns", "test-svc")}))
+	refs := []WebhookRef{{Name: "widgets.example.com", Type: CRDConversionWebhook}}
+	syncer := NewSyncer(fake.NewClientset(cm), "test-ns", "ca-bundle", refs)
+	syncer.UseDynamicClient(dynamicClient)
+	syncer.eventRecorder = events.NewInMemoryRecorder("test", clock.RealClock{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = syncer.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitForCABundle := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			got := getCABundle(t, dynamicClient, CustomResourceDefinitionResource, "widgets.example.com", conversionCABundle...)
+			if got == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("caBundle: got %q, want %q", got, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForCABundle("test-ca-bundle-data")
+
+	// Simulate a GitOps tool resetting the caBundle
+	current, err := dynamicClient.Resource(CustomResourceDefinitionResource).Get(ctx, "widgets.example.com", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get CRD: %v", err)
+	}
+	unstructured.RemoveNestedField(current.Object, conversionCABundle...)
+	if _, err := dynamicClient.Resource(CustomResourceDefinitionResource).Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to reset caBundle: %v", err)
+	}
+	waitForCABundle("test-ca-bundle-data")
+}
+
+func TestSyncer_Start_RequiresDynamicClient(t *testing.T) {
+	refs := []WebhookRef{{Name: "v1.metrics.example.com", Type: APIServiceWebhook}}
+	syncer := NewSyncer(fake.NewClientset(), "test-ns", "ca-bundle", refs)
+
+	if err := syncer.Start(context.Background()); err == nil {
+		t.Error("Expected Start to fail without a dynamic client")
+	}
+}
@@ -0,0 +1,106 @@
+package cabundle
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ConfigMapKey is the configmap key holding the CA bundle.
+	ConfigMapKey = "ca-bundle.crt"
+	// SecretKey is the secret key holding the CA bundle, as written by cert-manager.
+	SecretKey = "ca.crt"
+)
+
+// SourceKind is the kind of object the CA bundle is read from.
+type SourceKind string
+
+const (
+	// SourceConfigMap reads the CA bundle from the ca-bundle.crt key of a configmap.
+	SourceConfigMap SourceKind = "ConfigMap"
+	// SourceSecret reads the CA bundle from the ca.crt key of a secret.
+	SourceSecret SourceKind = "Secret"
+)
+
+// Source identifies the object the CA bundle is read from.
+type Source struct {
+	// Kind is the kind of the object.
+	Kind SourceKind
+	// Name is the name of the object.
+	Name string
+}
+
+// ConfigMapSource returns a source reading the CA bundle from the named configmap.
+func ConfigMapSource(name string) Source {
+	return Source{Kind: SourceConfigMap, Name: name}
+}
+
+// SecretSource returns a source reading the CA bundle from the named secret.
+func SecretSource(name string) Source {
+	return Source{Kind: SourceSecret, Name: name}
+}
+
+// String returns a human readable description of the source, e.g. "configmap my-ca-bundle".
+func (s Source) String() string {
+	switch s.Kind {
+	case SourceSecret:
+		return "secret " + s.Name
+	default:
+		return "configmap " + s.Name
+	}
+}
+
+// Get returns the CA bundle, or nil if the object does not exist yet.
+func (s Source) Get(ctx context.Context, client kubernetes.Interface, namespace string) ([]byte, error) {
+	var (
+		obj interface{}
+		err error
+	)
+	switch s.Kind {
+	case SourceSecret:
+		obj, err = client.CoreV1().Secrets(namespace).Get(ctx, s.Name, metav1.GetOptions{})
+	default:
+		obj, err = client.CoreV1().ConfigMaps(namespace).Get(ctx, s.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	caBundle, _ := s.FromObject(obj)
+	return caBundle, nil
+}
+
+// FromObject returns the CA bundle held by obj and whether obj is the source object.
+func (s Source) FromObject(obj interface{}) ([]byte, bool) {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		if s.Kind == SourceSecret || o.Name != s.Name {
+			return nil, false
+		}
+		return []byte(o.Data[ConfigMapKey]), true
+	case *corev1.Secret:
+		if s.Kind != SourceSecret || o.Name != s.Name {
+			return nil, false
+		}
+		return o.Data[SecretKey], true
+	default:
+		return nil, false
+	}
+}
+
+// Informer returns the informer watching objects of the source kind.
+func (s Source) Informer(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+	if s.Kind == SourceSecret {
+		return factory.Core().V1().Secrets().Informer()
+	}
+	return factory.Core().V1().ConfigMaps().Informer()
+}
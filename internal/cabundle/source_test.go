@@ -0,0 +1,46 @@
+package cabundle
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSource_FromObject(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle"},
+		Data:       map[string]string{ConfigMapKey: "cm-ca"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle"},
+		Data:       map[string][]byte{SecretKey: []byte("secret-ca")},
+	}
+
+	tests := []struct {
+		name   string
+		source Source
+		obj    interface{}
+		want   string
+		wantOK bool
+	}{
+		{"configmap", ConfigMapSource("ca-bundle"), cm, "cm-ca", true},
+		{"configmap other name", ConfigMapSource("other"), cm, "", false},
+		{"configmap source ignores secret", ConfigMapSource("ca-bundle"), secret, "", false},
+		{"secret", SecretSource("ca-bundle"), secret, "secret-ca", true},
+		{"secret source ignores configmap", SecretSource("ca-bundle"), cm, "", false},
+		{"unknown type", SecretSource("ca-bundle"), "ca-bundle", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.source.FromObject(tt.obj)
+			if ok != tt.wantOK {
+				t.Errorf("ok: got %v, want %v", ok, tt.wantOK)
+			}
+			if string(got) != tt.want {
+				t.Errorf("caBundle: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
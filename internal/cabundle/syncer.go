@@ -1,20 +1,46 @@
 package cabundle
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
-	corev1 "k8s.io/api/core/v1"
+	"github.com/openshift/library-go/pkg/operator/events"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	admissionregistrationv1ac "k8s.io/client-go/applyconfigurations/admissionregistration/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
 )
 
+const (
+	// defaultResyncPeriod is the interval at which all refs are synced again.
+	defaultResyncPeriod = 10 * time.Minute
+
+	// syncBaseDelay and syncMaxDelay bound the exponential backoff of failed syncs.
+	syncBaseDelay = 500 * time.Millisecond
+	syncMaxDelay  = 5 * time.Minute
+)
+
+// FieldManager is the server-side apply field manager owning the caBundle
+// fields of the webhook configurations.
+const FieldManager = "auto-cert-webhook"
+
 // WebhookType represents the type of webhook.
 type WebhookType string
 
@@ -23,36 +49,128 @@ const (
 	ValidatingWebhook WebhookType = "validating"
 	// MutatingWebhook represents a mutating admission webhook.
 	MutatingWebhook WebhookType = "mutating"
+	// CRDConversionWebhook represents the conversion webhook of a CustomResourceDefinition.
+	CRDConversionWebhook WebhookType = "conversion"
+	// APIServiceWebhook represents an aggregated APIService.
+	APIServiceWebhook WebhookType = "apiservice"
 )
 
-// WebhookRef references a webhook configuration to update.
+// Kind returns the kind of the objects of the webhook type.
+func (t WebhookType) Kind() string {
+	switch t {
+	case ValidatingWebhook:
+		return "ValidatingWebhookConfiguration"
+	case MutatingWebhook:
+		return "MutatingWebhookConfiguration"
+	default:
+		return resourceKinds[t].kind
+	}
+}
+
+// WebhookRef references a webhook configuration, CustomResourceDefinition or
+// APIService to update.
 type WebhookRef struct {
-	// Name is the name of the webhook configuration.
+	// Name is the name of the object.
 	Name string
-	// Type is the type of webhook (validating or mutating).
+	// Type is the type of webhook.
 	Type WebhookType
+	// WebhookName is the name of the webhook entry to patch. If empty, all
+	// entries referencing the service are patched. Unused for
+	// CustomResourceDefinitions and APIServices.
+	WebhookName string
 }
 
-// Syncer synchronizes CA bundle to webhook configurations.
+// Syncer synchronizes CA bundle to webhook configurations. The webhook
+// configurations are watched as well, so a caBundle reset outside the
+// framework, e.g. by Helm or a GitOps tool, is repaired right away.
+//
+// Refs are synced from a rate-limited work queue: failed syncs are retried
+// with exponential backoff, and all refs are synced again every resync period.
 type Syncer struct {
-	client                kubernetes.Interface
-	namespace             string
-	caBundleConfigMapName string
-	webhookRefs           []WebhookRef
+	client        kubernetes.Interface
+	dynamicClient dynamic.Interface // for CustomResourceDefinitions and APIServices
+	namespace     string
+	source        Source
+	webhookRefs   []WebhookRef
+	eventRecorder events.Recorder // created by Start if nil
+
+	// serviceName restricts patches to entries referencing this service in
+	// namespace, or "" to patch all entries.
+	serviceName string
+
+	// queue holds the refs to sync, created by Start.
+	queue workqueue.TypedRateLimitingInterface[WebhookRef]
+	// resyncPeriod is the interval at which all refs are synced again.
+	resyncPeriod time.Duration
+
+	// mu guards caBundle and drifted.
+	mu sync.Mutex
+	// caBundle is the last CA bundle read from the source, empty until it has data.
+	caBundle []byte
+	// drifted holds the refs queued because their caBundle drifted, until
+	// they are repaired.
+	drifted map[WebhookRef]bool
+
+	// onSynced is called after each webhook configuration was patched, or nil.
+	onSynced func(ref WebhookRef, err error)
 }
 
-// NewSyncer creates a new CA bundle syncer.
+// NewSyncer creates a new CA bundle syncer reading the CA bundle from a configmap.
 func NewSyncer(client kubernetes.Interface, namespace, caBundleConfigMapName string, webhookRefs []WebhookRef) *Syncer {
+	return NewSyncerFromSource(client, namespace, ConfigMapSource(caBundleConfigMapName), webhookRefs)
+}
+
+// NewSyncerFromSource creates a new CA bundle syncer reading the CA bundle from source.
+func NewSyncerFromSource(client kubernetes.Interface, namespace string, source Source, webhookRefs []WebhookRef) *Syncer {
 	return &Syncer{
-		client:                client,
-		namespace:             namespace,
-		caBundleConfigMapName: caBundleConfigMapName,
-		webhookRefs:           webhookRefs,
+		client:       client,
+		namespace:    namespace,
+		source:       source,
+		webhookRefs:  webhookRefs,
+		resyncPeriod: defaultResyncPeriod,
+		drifted:      make(map[WebhookRef]bool),
 	}
 }
 
-// Start starts watching the CA bundle configmap and syncing to webhook configurations.
+// MatchService restricts patches to webhook entries whose clientConfig
+// references the named service in the syncer namespace, so entries of shared
+// configurations pointing at other services are left untouched. Entries using
+// a URL are only patched when a WebhookRef names them. Must be called before Start.
+func (s *Syncer) MatchService(serviceName string) {
+	s.serviceName = serviceName
+}
+
+// UseDynamicClient sets the client used to patch CustomResourceDefinitions
+// and APIServices. Required when any WebhookRef has type
+// CRDConversionWebhook or APIServiceWebhook. Must be called before Start.
+func (s *Syncer) UseDynamicClient(client dynamic.Interface) {
+	s.dynamicClient = client
+}
+
+// OnSynced registers fn to be called after the CA bundle was patched into a
+// webhook configuration, with the error if patching failed. It is called
+// for every attempt, including retries. Must be called before Start.
+func (s *Syncer) OnSynced(fn func(ref WebhookRef, err error)) {
+	s.onSynced = fn
+}
+
+// Start starts watching the CA bundle source and syncing to webhook configurations.
 func (s *Syncer) Start(ctx context.Context) error {
+	if s.dynamicClient == nil && slices.ContainsFunc(s.webhookRefs, func(ref WebhookRef) bool { return isResourceType(ref.Type) }) {
+		return fmt.Errorf("a dynamic client is required to sync CustomResourceDefinitions and APIServices")
+	}
+
+	s.queue = newSyncQueue()
+	defer s.queue.ShutDown()
+
+	if s.eventRecorder == nil {
+		controllerRef, err := events.GetControllerReferenceForCurrentPod(ctx, s.client, s.namespace, nil)
+		if err != nil {
+			klog.V(4).Infof("Unable to get controller reference: %v", err)
+		}
+		s.eventRecorder = events.NewRecorder(s.client.CoreV1().Events(s.namespace), s.namespace, controllerRef, clock.RealClock{})
+	}
+
 	// Try to sync initially
 	if err := s.syncCABundle(ctx); err != nil {
 		klog.Warningf("Initial CA bundle sync failed (will retry via informer): %v", err)
@@ -65,41 +183,58 @@ func (s *Syncer) Start(ctx context.Context) error {
 		informers.WithNamespace(s.namespace),
 	)
 
-	cmInformer := factory.Core().V1().ConfigMaps().Informer()
+	informer := s.source.Informer(factory)
 
-	_, err := cmInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
-			cm, ok := obj.(*corev1.ConfigMap)
-			if !ok {
-				klog.Warningf("unexpected object type in AddFunc: %T", obj)
-				return
-			}
-			if cm.Name == s.caBundleConfigMapName {
-				s.onConfigMapUpdate(ctx, cm)
-			}
+			s.onObjectUpdate(ctx, obj)
 		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
-			cm, ok := newObj.(*corev1.ConfigMap)
-			if !ok {
-				klog.Warningf("unexpected object type in UpdateFunc: %T", newObj)
-				return
-			}
-			if cm.Name == s.caBundleConfigMapName {
-				s.onConfigMapUpdate(ctx, cm)
-			}
+			s.onObjectUpdate(ctx, newObj)
 		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to add event handler: %w", err)
 	}
 
+	// Watch the webhook configurations to repair caBundle drift
+	configFactory := informers.NewSharedInformerFactory(s.client, 0)
+	configInformers := s.webhookConfigInformers(configFactory)
+	var resourceFactory dynamicinformer.DynamicSharedInformerFactory
+	if s.dynamicClient != nil {
+		resourceFactory = dynamicinformer.NewDynamicSharedInformerFactory(s.dynamicClient, 0)
+		configInformers = append(configInformers, s.resourceInformers(resourceFactory)...)
+	}
+	synced := []cache.InformerSynced{informer.HasSynced}
+	for _, configInformer := range configInformers {
+		_, err := configInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				s.onWebhookConfigUpdate(ctx, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				s.onWebhookConfigUpdate(ctx, newObj)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add webhook configuration event handler: %w", err)
+		}
+		synced = append(synced, configInformer.HasSynced)
+	}
+
 	factory.Start(ctx.Done())
+	configFactory.Start(ctx.Done())
+	if resourceFactory != nil {
+		resourceFactory.Start(ctx.Done())
+	}
 
-	if !cache.WaitForCacheSync(ctx.Done(), cmInformer.HasSynced) {
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
 		return fmt.Errorf("failed to sync informer cache")
 	}
 
-	klog.Infof("CA bundle syncer started watching configmap %s/%s", s.namespace, s.caBundleConfigMapName)
+	go wait.UntilWithContext(ctx, s.runWorker, time.Second)
+	go wait.UntilWithContext(ctx, func(context.Context) { s.enqueueAll() }, s.resyncPeriod)
+
+	klog.Infof("CA bundle syncer started watching %s in namespace %s", s.source, s.namespace)
 
 	<-ctx.Done()
 	return nil
@@ -107,99 +242,361 @@ func (s *Syncer) Start(ctx context.Context) error {
 
 // syncCABundle syncs the CA bundle to all webhook configurations.
 func (s *Syncer) syncCABundle(ctx context.Context) error {
-	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.caBundleConfigMapName, metav1.GetOptions{})
+	caBundle, err := s.source.Get(ctx, s.client, s.namespace)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			klog.V(4).Infof("CA bundle configmap %s/%s not found yet", s.namespace, s.caBundleConfigMapName)
-			return nil
-		}
 		return err
 	}
 
-	s.onConfigMapUpdate(ctx, cm)
+	s.onCABundle(ctx, caBundle)
 	return nil
 }
 
-// onConfigMapUpdate handles configmap updates.
-func (s *Syncer) onConfigMapUpdate(ctx context.Context, cm *corev1.ConfigMap) {
-	caBundle, ok := cm.Data["ca-bundle.crt"]
-	if !ok || len(caBundle) == 0 {
-		klog.V(4).Infof("ConfigMap %s/%s has no ca-bundle.crt data yet", s.namespace, s.caBundleConfigMapName)
+// onObjectUpdate handles updates of watched objects.
+func (s *Syncer) onObjectUpdate(ctx context.Context, obj interface{}) {
+	caBundle, ok := s.source.FromObject(obj)
+	if !ok {
+		return
+	}
+	s.onCABundle(ctx, caBundle)
+}
+
+// onCABundle queues all webhook configurations to get the CA bundle.
+func (s *Syncer) onCABundle(ctx context.Context, caBundle []byte) {
+	if len(caBundle) == 0 {
+		klog.V(4).Infof("CA bundle %s in namespace %s has no data yet", s.source, s.namespace)
+		return
+	}
+
+	s.mu.Lock()
+	s.caBundle = caBundle
+	s.mu.Unlock()
+
+	s.enqueueAll()
+}
+
+// newSyncQueue returns the work queue of the refs to sync, retrying failed
+// syncs with exponential backoff.
+func newSyncQueue() workqueue.TypedRateLimitingInterface[WebhookRef] {
+	return workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[WebhookRef](syncBaseDelay, syncMaxDelay),
+		workqueue.TypedRateLimitingQueueConfig[WebhookRef]{Name: "cabundle"},
+	)
+}
+
+// enqueueAll queues all webhook configurations for sync.
+func (s *Syncer) enqueueAll() {
+	for _, ref := range s.webhookRefs {
+		s.queue.Add(ref)
+	}
+}
+
+// runWorker processes the queue until it is shut down.
+func (s *Syncer) runWorker(ctx context.Context) {
+	for s.processNextWorkItem(ctx) {
+	}
+}
+
+// processNextWorkItem syncs the next queued ref and requeues it with
+// backoff if the sync failed. It returns false once the queue is shut down.
+func (s *Syncer) processNextWorkItem(ctx context.Context) bool {
+	ref, shutdown := s.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer s.queue.Done(ref)
+
+	if err := s.syncRef(ctx, ref); err != nil {
+		klog.Errorf("Failed to patch webhook %s (%s), retrying: %v", ref.Name, ref.Type, err)
+		s.queue.AddRateLimited(ref)
+		return true
+	}
+	s.queue.Forget(ref)
+	return true
+}
+
+// syncRef patches the current CA bundle into the webhook configuration of
+// ref if it does not hold it yet. Writes, failures and drift repairs are
+// reported; a ref that is already up to date, e.g. on a resync, is not.
+func (s *Syncer) syncRef(ctx context.Context, ref WebhookRef) error {
+	s.mu.Lock()
+	caBundle, drifted := s.caBundle, s.drifted[ref]
+	s.mu.Unlock()
+	if len(caBundle) == 0 {
+		return nil
+	}
+
+	patched, err := s.patchWebhook(ctx, ref, caBundle)
+	metrics.ObserveCABundleSync(ref.Name, string(ref.Type), err)
+	if err == nil {
+		if drifted {
+			s.mu.Lock()
+			delete(s.drifted, ref)
+			s.mu.Unlock()
+		}
+		if !patched {
+			klog.V(4).Infof("CA bundle of webhook %s (%s) is up to date", ref.Name, ref.Type)
+			return nil
+		}
+		klog.Infof("Updated CA bundle for webhook %s (%s)", ref.Name, ref.Type)
+		if drifted {
+			metrics.RecordCABundleDriftRepair(ref.Name, string(ref.Type))
+			s.eventRecorder.Warningf("CABundleDriftRepaired", "Restored the caBundle of %s %q, which no longer matched %s", ref.Type.Kind(), ref.Name, s.source)
+		}
+	}
+	if s.onSynced != nil {
+		s.onSynced(ref, err)
+	}
+	return err
+}
+
+// webhookConfigInformers returns the informers watching the kinds of the
+// referenced webhook configurations.
+func (s *Syncer) webhookConfigInformers(factory informers.SharedInformerFactory) []cache.SharedIndexInformer {
+	var result []cache.SharedIndexInformer
+	if slices.ContainsFunc(s.webhookRefs, func(ref WebhookRef) bool { return ref.Type == ValidatingWebhook }) {
+		result = append(result, factory.Admissionregistration().V1().ValidatingWebhookConfigurations().Informer())
+	}
+	if slices.ContainsFunc(s.webhookRefs, func(ref WebhookRef) bool { return ref.Type == MutatingWebhook }) {
+		result = append(result, factory.Admissionregistration().V1().MutatingWebhookConfigurations().Informer())
+	}
+	return result
+}
+
+// onWebhookConfigUpdate queues a referenced webhook configuration,
+// CustomResourceDefinition or APIService whose targeted caBundle fields no
+// longer match the current CA bundle.
+func (s *Syncer) onWebhookConfigUpdate(ctx context.Context, obj interface{}) {
+	var (
+		name        string
+		webhookType WebhookType
+		entries     []webhookEntry
+		resource    *unstructured.Unstructured
+	)
+	switch config := obj.(type) {
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		name, webhookType, entries = config.Name, ValidatingWebhook, validatingEntries(config.Webhooks)
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		name, webhookType, entries = config.Name, MutatingWebhook, mutatingEntries(config.Webhooks)
+	case *unstructured.Unstructured:
+		var ok bool
+		if webhookType, ok = resourceTypeOf(config); !ok {
+			return
+		}
+		name, resource = config.GetName(), config
+	default:
+		return
+	}
+
+	s.mu.Lock()
+	caBundle := s.caBundle
+	s.mu.Unlock()
+	if len(caBundle) == 0 {
 		return
 	}
 
 	for _, ref := range s.webhookRefs {
-		if err := s.patchWebhook(ctx, ref, []byte(caBundle)); err != nil {
-			klog.Errorf("Failed to patch webhook %s (%s): %v", ref.Name, ref.Type, err)
+		if ref.Name != name || ref.Type != webhookType {
+			continue
+		}
+		var drifted bool
+		if resource != nil {
+			target, err := s.resourceTargetOf(webhookType, resource)
+			drifted = err == nil && !bytes.Equal(target.caBundle, caBundle)
 		} else {
-			klog.Infof("Updated CA bundle for webhook %s (%s)", ref.Name, ref.Type)
+			drifted = slices.ContainsFunc(s.targetedEntries(ref, entries), func(i int) bool {
+				return !bytes.Equal(entries[i].clientConfig.CABundle, caBundle)
+			})
 		}
+		if !drifted {
+			continue
+		}
+
+		klog.Warningf("CA bundle of webhook %s (%s) drifted, repairing", ref.Name, ref.Type)
+		s.mu.Lock()
+		s.drifted[ref] = true
+		s.mu.Unlock()
+		s.queue.Add(ref)
 	}
 }
 
-// patchWebhook patches the caBundle field of a webhook configuration.
-func (s *Syncer) patchWebhook(ctx context.Context, ref WebhookRef, caBundle []byte) error {
+// patchWebhook patches the caBundle field of a webhook configuration and
+// reports whether it was written: a configuration that is not found or
+// already holds caBundle is left unchanged.
+func (s *Syncer) patchWebhook(ctx context.Context, ref WebhookRef, caBundle []byte) (bool, error) {
 	switch ref.Type {
 	case ValidatingWebhook:
-		return s.patchValidatingWebhook(ctx, ref.Name, caBundle)
+		return s.patchValidatingWebhook(ctx, ref, caBundle)
 	case MutatingWebhook:
-		return s.patchMutatingWebhook(ctx, ref.Name, caBundle)
+		return s.patchMutatingWebhook(ctx, ref, caBundle)
+	case CRDConversionWebhook, APIServiceWebhook:
+		return s.patchResource(ctx, ref, caBundle)
 	default:
-		return fmt.Errorf("unknown webhook type: %s", ref.Type)
+		return false, fmt.Errorf("unknown webhook type: %s", ref.Type)
 	}
 }
 
-// buildCABundlePatch builds a JSON patch for updating caBundle on all webhooks.
-func buildCABundlePatch(webhookCount int, caBundle []byte) ([]byte, error) {
-	var patches []map[string]interface{}
-	for i := 0; i < webhookCount; i++ {
-		patches = append(patches, map[string]interface{}{
-			"op":    "replace",
-			"path":  fmt.Sprintf("/webhooks/%d/clientConfig/caBundle", i),
-			"value": caBundle,
-		})
+// webhookEntry is an entry of the webhooks list of a webhook configuration.
+type webhookEntry struct {
+	name         string
+	clientConfig admissionregistrationv1.WebhookClientConfig
+}
+
+// validatingEntries returns the entries of a ValidatingWebhookConfiguration.
+func validatingEntries(webhooks []admissionregistrationv1.ValidatingWebhook) []webhookEntry {
+	entries := make([]webhookEntry, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		entries = append(entries, webhookEntry{name: webhook.Name, clientConfig: webhook.ClientConfig})
+	}
+	return entries
+}
+
+// mutatingEntries returns the entries of a MutatingWebhookConfiguration.
+func mutatingEntries(webhooks []admissionregistrationv1.MutatingWebhook) []webhookEntry {
+	entries := make([]webhookEntry, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		entries = append(entries, webhookEntry{name: webhook.Name, clientConfig: webhook.ClientConfig})
 	}
-	return json.Marshal(patches)
+	return entries
 }
 
-// patchValidatingWebhook patches a ValidatingWebhookConfiguration.
-func (s *Syncer) patchValidatingWebhook(ctx context.Context, name string, caBundle []byte) error {
-	current, err := s.client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+// targetedEntries returns the indices of the entries ref targets: the entry
+// named by ref, or all entries if it names none, restricted to entries
+// referencing the matched service.
+func (s *Syncer) targetedEntries(ref WebhookRef, entries []webhookEntry) []int {
+	var indices []int
+	for i, entry := range entries {
+		if ref.WebhookName != "" && entry.name != ref.WebhookName {
+			continue
+		}
+		if s.serviceName != "" {
+			service := entry.clientConfig.Service
+			switch {
+			case service != nil:
+				if service.Namespace != s.namespace || service.Name != s.serviceName {
+					continue
+				}
+			case ref.WebhookName == "":
+				continue
+			}
+		}
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// appliedEntries returns the indices of the entries whose caBundle is applied
+// for ref. Server-side apply removes the fields of the field manager that are
+// omitted, so the entries targeted by every ref to the same configuration are
+// applied together.
+func (s *Syncer) appliedEntries(ref WebhookRef, entries []webhookEntry) ([]int, error) {
+	if len(s.targetedEntries(ref, entries)) == 0 {
+		if ref.WebhookName != "" {
+			return nil, fmt.Errorf("webhook %q not found or not referencing service %s/%s", ref.WebhookName, s.namespace, s.serviceName)
+		}
+		return nil, fmt.Errorf("no webhook references service %s/%s", s.namespace, s.serviceName)
+	}
+
+	var indices []int
+	for _, other := range s.webhookRefs {
+		if other.Name != ref.Name || other.Type != ref.Type {
+			continue
+		}
+		indices = append(indices, s.targetedEntries(other, entries)...)
+	}
+	if !slices.Contains(s.webhookRefs, ref) {
+		indices = append(indices, s.targetedEntries(ref, entries)...)
+	}
+	slices.Sort(indices)
+	return slices.Compact(indices), nil
+}
+
+// caBundleApplied reports whether the entries at indices all hold caBundle.
+func caBundleApplied(entries []webhookEntry, indices []int, caBundle []byte) bool {
+	for _, i := range indices {
+		if !bytes.Equal(entries[i].clientConfig.CABundle, caBundle) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyCABundle calls apply without force first. If another field manager
+// owns a caBundle field, e.g. Helm or a GitOps tool setting it from a
+// manifest, the conflict is reported and the field is taken over with force.
+// Other conflicts, such as a stale resourceVersion, are returned.
+func (s *Syncer) applyCABundle(ref WebhookRef, apply func(opts metav1.ApplyOptions) error) error {
+	err := apply(metav1.ApplyOptions{FieldManager: FieldManager})
+	if !errors.IsConflict(err) || !errors.HasStatusCause(err, metav1.CauseTypeFieldManagerConflict) {
+		return err
+	}
+
+	klog.Warningf("CA bundle of webhook %s (%s) is managed by another field manager, taking ownership: %v", ref.Name, ref.Type, err)
+	if s.eventRecorder != nil {
+		s.eventRecorder.Warningf("CABundleConflict", "Took ownership of the caBundle of %s %q: %v", ref.Type.Kind(), ref.Name, err)
+	}
+	return apply(metav1.ApplyOptions{FieldManager: FieldManager, Force: true})
+}
+
+// patchValidatingWebhook applies the caBundle fields of a ValidatingWebhookConfiguration.
+func (s *Syncer) patchValidatingWebhook(ctx context.Context, ref WebhookRef, caBundle []byte) (bool, error) {
+	current, err := s.client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, ref.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			klog.V(4).Infof("ValidatingWebhookConfiguration %s not found", name)
-			return nil
+			klog.V(4).Infof("ValidatingWebhookConfiguration %s not found", ref.Name)
+			return false, nil
 		}
-		return err
+		return false, err
 	}
 
-	patchBytes, err := buildCABundlePatch(len(current.Webhooks), caBundle)
+	entries := validatingEntries(current.Webhooks)
+	indices, err := s.appliedEntries(ref, entries)
 	if err != nil {
-		return fmt.Errorf("failed to marshal patch: %w", err)
+		return false, err
+	}
+	if caBundleApplied(entries, indices, caBundle) {
+		return false, nil
 	}
 
-	_, err = s.client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Patch(
-		ctx, name, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
-	return err
+	config := admissionregistrationv1ac.ValidatingWebhookConfiguration(ref.Name)
+	for _, i := range indices {
+		config.WithWebhooks(admissionregistrationv1ac.ValidatingWebhook().
+			WithName(entries[i].name).
+			WithClientConfig(admissionregistrationv1ac.WebhookClientConfig().WithCABundle(caBundle...)))
+	}
+	return true, s.applyCABundle(ref, func(opts metav1.ApplyOptions) error {
+		_, err := s.client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Apply(ctx, config, opts)
+		return err
+	})
 }
 
-// patchMutatingWebhook patches a MutatingWebhookConfiguration.
-func (s *Syncer) patchMutatingWebhook(ctx context.Context, name string, caBundle []byte) error {
-	current, err := s.client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, name, metav1.GetOptions{})
+// patchMutatingWebhook applies the caBundle fields of a MutatingWebhookConfiguration.
+func (s *Syncer) patchMutatingWebhook(ctx context.Context, ref WebhookRef, caBundle []byte) (bool, error) {
+	current, err := s.client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, ref.Name, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			klog.V(4).Infof("MutatingWebhookConfiguration %s not found", name)
-			return nil
+			klog.V(4).Infof("MutatingWebhookConfiguration %s not found", ref.Name)
+			return false, nil
 		}
-		return err
+		return false, err
 	}
 
-	patchBytes, err := buildCABundlePatch(len(current.Webhooks), caBundle)
+	entries := mutatingEntries(current.Webhooks)
+	indices, err := s.appliedEntries(ref, entries)
 	if err != nil {
-		return fmt.Errorf("failed to marshal patch: %w", err)
+		return false, err
+	}
+	if caBundleApplied(entries, indices, caBundle) {
+		return false, nil
 	}
 
-	_, err = s.client.AdmissionregistrationV1().MutatingWebhookConfigurations().Patch(
-		ctx, name, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
-	return err
+	config := admissionregistrationv1ac.MutatingWebhookConfiguration(ref.Name)
+	for _, i := range indices {
+		config.WithWebhooks(admissionregistrationv1ac.MutatingWebhook().
+			WithName(entries[i].name).
+			WithClientConfig(admissionregistrationv1ac.WebhookClientConfig().WithCABundle(caBundle...)))
+	}
+	return true, s.applyCABundle(ref, func(opts metav1.ApplyOptions) error {
+		_, err := s.client.AdmissionregistrationV1().MutatingWebhookConfigurations().Apply(ctx, config, opts)
+		return err
+	})
 }
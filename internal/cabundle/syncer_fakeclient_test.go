@@ -2,12 +2,25 @@ package cabundle
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
 
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/clock"
+
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
 )
 
 func TestSyncer_syncCABundle_NotFound(t *testing.T) {
@@ -56,12 +69,14 @@ func TestSyncer_syncCABundle_Found(t *testing.T) {
 	}
 
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
 
 	ctx := context.Background()
 	err := syncer.syncCABundle(ctx)
 	if err != nil {
 		t.Fatalf("syncCABundle failed: %v", err)
 	}
+	processQueue(ctx, syncer)
 
 	// Verify webhook was patched
 	updated, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
@@ -75,6 +90,57 @@ func TestSyncer_syncCABundle_Found(t *testing.T) {
 	}
 }
 
+func TestSyncer_syncCABundle_SecretSource(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "webhook-tls",
+			Namespace: "test-ns",
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte("test-ca-bundle-data"),
+		},
+	}
+
+	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-webhook",
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{
+				Name: "test.webhook.svc",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					CABundle: []byte("old-ca-bundle"),
+				},
+			},
+		},
+	}
+
+	client := fake.NewClientset(secret, webhookConfig)
+
+	refs := []WebhookRef{
+		{Name: "test-webhook", Type: ValidatingWebhook},
+	}
+
+	syncer := NewSyncerFromSource(client, "test-ns", SecretSource("webhook-tls"), refs)
+	startQueue(t, syncer)
+
+	ctx := context.Background()
+	if err := syncer.syncCABundle(ctx); err != nil {
+		t.Fatalf("syncCABundle failed: %v", err)
+	}
+	processQueue(ctx, syncer)
+
+	updated, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get updated webhook: %v", err)
+	}
+
+	if string(updated.Webhooks[0].ClientConfig.CABundle) != "test-ca-bundle-data" {
+		t.Errorf("CABundle not updated: got %q, want %q",
+			string(updated.Webhooks[0].ClientConfig.CABundle), "test-ca-bundle-data")
+	}
+}
+
 func TestSyncer_patchValidatingWebhook(t *testing.T) {
 	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
@@ -100,7 +166,7 @@ func TestSyncer_patchValidatingWebhook(t *testing.T) {
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", nil)
 
 	ctx := context.Background()
-	err := syncer.patchValidatingWebhook(ctx, "test-validating-webhook", []byte("new-ca-bundle"))
+	_, err := syncer.patchValidatingWebhook(ctx, WebhookRef{Name: "test-validating-webhook", Type: ValidatingWebhook}, []byte("new-ca-bundle"))
 	if err != nil {
 		t.Fatalf("patchValidatingWebhook failed: %v", err)
 	}
@@ -138,7 +204,7 @@ func TestSyncer_patchMutatingWebhook(t *testing.T) {
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", nil)
 
 	ctx := context.Background()
-	err := syncer.patchMutatingWebhook(ctx, "test-mutating-webhook", []byte("new-ca-bundle"))
+	_, err := syncer.patchMutatingWebhook(ctx, WebhookRef{Name: "test-mutating-webhook", Type: MutatingWebhook}, []byte("new-ca-bundle"))
 	if err != nil {
 		t.Fatalf("patchMutatingWebhook failed: %v", err)
 	}
@@ -154,6 +220,140 @@ func TestSyncer_patchMutatingWebhook(t *testing.T) {
 	}
 }
 
+func TestSyncer_patchWebhook_ServerSideApply(t *testing.T) {
+	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-webhook"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "a.webhook.svc"},
+			{Name: "b.webhook.svc"},
+		},
+	}
+
+	client := fake.NewClientset()
+	ctx := context.Background()
+	if _, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Create(ctx, webhookConfig, metav1.CreateOptions{FieldManager: "helm"}); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	ref := WebhookRef{Name: "test-webhook", Type: ValidatingWebhook, WebhookName: "b.webhook.svc"}
+	syncer := NewSyncer(client, "test-ns", "ca-bundle", []WebhookRef{ref})
+	recorder := events.NewInMemoryRecorder("test", clock.RealClock{})
+	syncer.eventRecorder = recorder
+
+	if _, err := syncer.patchWebhook(ctx, ref, []byte("ca")); err != nil {
+		t.Fatalf("patchWebhook failed: %v", err)
+	}
+
+	updated, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	if got := string(updated.Webhooks[1].ClientConfig.CABundle); got != "ca" {
+		t.Errorf("CABundle of b.webhook.svc: got %q, want %q", got, "ca")
+	}
+	if got := updated.Webhooks[0].ClientConfig.CABundle; got != nil {
+		t.Errorf("CABundle of a.webhook.svc: got %q, want none", got)
+	}
+
+	// The field manager owns the caBundle of the targeted entry only.
+	applied := managedFieldsOf(t, updated.ManagedFields, FieldManager)
+	if applied.Operation != metav1.ManagedFieldsOperationApply {
+		t.Errorf("Operation: got %q, want %q", applied.Operation, metav1.ManagedFieldsOperationApply)
+	}
+	want := `{"f:webhooks":{"k:{\"name\":\"b.webhook.svc\"}":{".":{},"f:clientConfig":{"f:caBundle":{}},"f:name":{}}}}`
+	if got := string(applied.FieldsV1.Raw); got != want {
+		t.Errorf("Managed fields: got %s, want %s", got, want)
+	}
+	if recorded := recorder.Events(); len(recorded) != 0 {
+		t.Errorf("Expected no events without a conflict, got %v", recorded)
+	}
+}
+
+func TestSyncer_patchWebhook_Conflict(t *testing.T) {
+	webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-webhook"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "test.webhook.svc", ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: []byte("placeholder")}},
+		},
+	}
+
+	client := fake.NewClientset()
+	ctx := context.Background()
+	if _, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Create(ctx, webhookConfig, metav1.CreateOptions{FieldManager: "gitops"}); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	ref := WebhookRef{Name: "test-webhook", Type: MutatingWebhook}
+	syncer := NewSyncer(client, "test-ns", "ca-bundle", []WebhookRef{ref})
+	recorder := events.NewInMemoryRecorder("test", clock.RealClock{})
+	syncer.eventRecorder = recorder
+
+	// The caBundle owned by another manager is taken over with a warning.
+	if _, err := syncer.patchWebhook(ctx, ref, []byte("ca")); err != nil {
+		t.Fatalf("patchWebhook failed: %v", err)
+	}
+	updated, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	if got := string(updated.Webhooks[0].ClientConfig.CABundle); got != "ca" {
+		t.Errorf("CABundle: got %q, want %q", got, "ca")
+	}
+	if recorded := recorder.Events(); len(recorded) != 1 || recorded[0].Reason != "CABundleConflict" {
+		t.Fatalf("Expected a single CABundleConflict event, got %v", recorded)
+	}
+	if other := managedFieldsOf(t, updated.ManagedFields, "gitops"); strings.Contains(string(other.FieldsV1.Raw), "f:caBundle") {
+		t.Errorf("Expected gitops to lose ownership of the caBundle, got %s", other.FieldsV1.Raw)
+	}
+
+	// Once owned, later applies do not conflict.
+	if _, err := syncer.patchWebhook(ctx, ref, []byte("new-ca")); err != nil {
+		t.Fatalf("patchWebhook failed: %v", err)
+	}
+	if recorded := recorder.Events(); len(recorded) != 1 {
+		t.Errorf("Expected no further events, got %v", recorded)
+	}
+}
+
+func TestSyncer_patchWebhook_SharedConfiguration(t *testing.T) {
+	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-webhook"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "a.webhook.svc"},
+			{Name: "b.webhook.svc"},
+			{Name: "c.webhook.svc"},
+		},
+	}
+
+	client := fake.NewClientset(webhookConfig)
+	refs := []WebhookRef{
+		{Name: "test-webhook", Type: ValidatingWebhook, WebhookName: "a.webhook.svc"},
+		{Name: "test-webhook", Type: ValidatingWebhook, WebhookName: "c.webhook.svc"},
+	}
+	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
+	syncer.eventRecorder = events.NewInMemoryRecorder("test", clock.RealClock{})
+
+	// Applying the entry of one ref keeps the caBundle of the other.
+	ctx := context.Background()
+	syncer.onCABundle(ctx, []byte("ca"))
+	processQueue(ctx, syncer)
+	if _, err := syncer.patchWebhook(ctx, refs[1], []byte("ca")); err != nil {
+		t.Fatalf("patchWebhook failed: %v", err)
+	}
+
+	updated, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	want := map[string]string{"a.webhook.svc": "ca", "b.webhook.svc": "", "c.webhook.svc": "ca"}
+	for _, webhook := range updated.Webhooks {
+		if got := string(webhook.ClientConfig.CABundle); got != want[webhook.Name] {
+			t.Errorf("CABundle of %s: got %q, want %q", webhook.Name, got, want[webhook.Name])
+		}
+	}
+}
+
 func TestSyncer_patchWebhook_NotFound(t *testing.T) {
 	client := fake.NewClientset()
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", nil)
@@ -161,12 +361,12 @@ func TestSyncer_patchWebhook_NotFound(t *testing.T) {
 	ctx := context.Background()
 
 	// Should not return error if webhook not found
-	err := syncer.patchValidatingWebhook(ctx, "non-existent", []byte("ca"))
+	_, err := syncer.patchValidatingWebhook(ctx, WebhookRef{Name: "non-existent", Type: ValidatingWebhook}, []byte("ca"))
 	if err != nil {
 		t.Errorf("patchValidatingWebhook should not return error for not found: %v", err)
 	}
 
-	err = syncer.patchMutatingWebhook(ctx, "non-existent", []byte("ca"))
+	_, err = syncer.patchMutatingWebhook(ctx, WebhookRef{Name: "non-existent", Type: MutatingWebhook}, []byte("ca"))
 	if err != nil {
 		t.Errorf("patchMutatingWebhook should not return error for not found: %v", err)
 	}
@@ -177,16 +377,444 @@ func TestSyncer_patchWebhook_UnknownType(t *testing.T) {
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", nil)
 
 	ctx := context.Background()
-	err := syncer.patchWebhook(ctx, WebhookRef{Name: "test", Type: "unknown"}, []byte("ca"))
+	_, err := syncer.patchWebhook(ctx, WebhookRef{Name: "test", Type: "unknown"}, []byte("ca"))
 
 	if err == nil {
 		t.Error("patchWebhook should return error for unknown type")
 	}
 }
 
-func TestSyncer_onConfigMapUpdate_NoCABundle(t *testing.T) {
+func TestSyncer_OnSynced(t *testing.T) {
+	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-webhook",
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "test.webhook.svc"},
+		},
+	}
+
+	client := fake.NewClientset(webhookConfig)
+	refs := []WebhookRef{
+		{Name: "test-webhook", Type: ValidatingWebhook},
+		{Name: "test-webhook", Type: "unknown"},
+	}
+	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
+
+	var synced []WebhookRef
+	var errs []error
+	syncer.OnSynced(func(ref WebhookRef, err error) {
+		synced = append(synced, ref)
+		errs = append(errs, err)
+	})
+
+	syncer.onCABundle(context.Background(), []byte("ca"))
+	processQueue(context.Background(), syncer)
+
+	if len(synced) != 2 {
+		t.Fatalf("synced refs: got %d, want 2", len(synced))
+	}
+	if synced[0] != refs[0] || errs[0] != nil {
+		t.Errorf("first sync: got %v (%v), want %v without error", synced[0], errs[0], refs[0])
+	}
+	if synced[1] != refs[1] || errs[1] == nil {
+		t.Errorf("second sync: got %v (%v), want %v with error", synced[1], errs[1], refs[1])
+	}
+}
+
+func TestSyncer_onWebhookConfigUpdate(t *testing.T) {
+	webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-webhook",
+		},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "a.webhook.svc"},
+			{Name: "b.webhook.svc"},
+		},
+	}
+
+	client := fake.NewClientset(webhookConfig)
+	refs := []WebhookRef{{Name: "test-webhook", Type: MutatingWebhook}}
+	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
+	recorder := events.NewInMemoryRecorder("test", clock.RealClock{})
+	syncer.eventRecorder = recorder
+	var synced int
+	syncer.OnSynced(func(WebhookRef, error) { synced++ })
+
+	ctx := context.Background()
+
+	// Nothing is repaired before the CA bundle is known.
+	syncer.onWebhookConfigUpdate(ctx, webhookConfig)
+	processQueue(ctx, syncer)
+	if synced != 0 {
+		t.Fatalf("syncs before the CA bundle is known: got %d, want 0", synced)
+	}
+
+	syncer.onCABundle(ctx, []byte("ca"))
+	processQueue(ctx, syncer)
+	current, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	syncer.onWebhookConfigUpdate(ctx, current)
+	processQueue(ctx, syncer)
+	if synced != 1 {
+		t.Fatalf("syncs for an up-to-date configuration: got %d, want 1", synced)
+	}
+
+	// Unreferenced configurations are ignored.
+	other := current.DeepCopy()
+	other.Name = "other-webhook"
+	other.Webhooks[1].ClientConfig.CABundle = nil
+	syncer.onWebhookConfigUpdate(ctx, other)
+	processQueue(ctx, syncer)
+	if synced != 1 {
+		t.Fatalf("syncs for an unreferenced configuration: got %d, want 1", synced)
+	}
+
+	current.Webhooks[1].ClientConfig.CABundle = []byte("stale")
+	if _, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update webhook: %v", err)
+	}
+	syncer.onWebhookConfigUpdate(ctx, current)
+	processQueue(ctx, syncer)
+	if synced != 2 {
+		t.Fatalf("syncs after drift: got %d, want 2", synced)
+	}
+
+	repaired, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	for _, webhook := range repaired.Webhooks {
+		if string(webhook.ClientConfig.CABundle) != "ca" {
+			t.Errorf("CABundle of %s: got %q, want %q", webhook.Name, webhook.ClientConfig.CABundle, "ca")
+		}
+	}
+	// The update took ownership of the drifted caBundle, so the repair conflicts.
+	var reasons []string
+	for _, event := range recorder.Events() {
+		reasons = append(reasons, event.Reason)
+	}
+	if want := []string{"CABundleConflict", "CABundleDriftRepaired"}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("Event reasons: got %v, want %v", reasons, want)
+	}
+}
+
+func TestSyncer_Start_RepairsDrift(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca-bundle",
+			Namespace: "test-ns",
+		},
+		Data: map[string]string{
+			"ca-bundle.crt": "test-ca-bundle-data",
+		},
+	}
+	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-webhook",
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "test.webhook.svc"},
+		},
+	}
+
+	client := fake.NewClientset(cm, webhookConfig)
+	refs := []WebhookRef{{Name: "test-webhook", Type: ValidatingWebhook}}
+	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = syncer.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitForCABundle := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			current, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Failed to get webhook: %v", err)
+			}
+			if string(current.Webhooks[0].ClientConfig.CABundle) == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("CABundle: got %q, want %q", current.Webhooks[0].ClientConfig.CABundle, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForCABundle("test-ca-bundle-data")
+
+	// Simulate a GitOps tool resetting the caBundle
+	current, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	current.Webhooks[0].ClientConfig.CABundle = nil
+	if _, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to reset caBundle: %v", err)
+	}
+	waitForCABundle("test-ca-bundle-data")
+}
+
+func TestSyncer_processNextWorkItem_Retry(t *testing.T) {
+	metrics.Register()
+
+	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "retry-webhook"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "test.webhook.svc"},
+		},
+	}
+
+	client := fake.NewClientset(webhookConfig)
+	var failures int
+	client.PrependReactor("get", "validatingwebhookconfigurations", func(clienttesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			return false, nil, nil
+		}
+		failures++
+		return true, nil, apierrors.NewServiceUnavailable("etcd leader changed")
+	})
+
+	ref := WebhookRef{Name: "retry-webhook", Type: ValidatingWebhook}
+	syncer := NewSyncer(client, "test-ns", "ca-bundle", []WebhookRef{ref})
+	startQueue(t, syncer)
+	var errs []error
+	syncer.OnSynced(func(_ WebhookRef, err error) { errs = append(errs, err) })
+
+	ctx := context.Background()
+	syncer.onCABundle(ctx, []byte("ca"))
+	processQueue(ctx, syncer)
+	if len(errs) != 1 || errs[0] == nil {
+		t.Fatalf("Expected a failed sync, got %v", errs)
+	}
+	if got := syncer.queue.NumRequeues(ref); got != 1 {
+		t.Fatalf("NumRequeues after a failure: got %d, want 1", got)
+	}
+
+	// The failed ref is retried after the backoff.
+	start := time.Now()
+	syncer.processNextWorkItem(ctx)
+	if elapsed := time.Since(start); elapsed < syncBaseDelay/2 {
+		t.Errorf("Retry after %v, want a backoff of about %v", elapsed, syncBaseDelay)
+	}
+	if len(errs) != 2 || errs[1] != nil {
+		t.Fatalf("Expected a successful retry, got %v", errs)
+	}
+	if got := syncer.queue.NumRequeues(ref); got != 0 {
+		t.Errorf("NumRequeues after a success: got %d, want 0", got)
+	}
+
+	updated, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "retry-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	if got := string(updated.Webhooks[0].ClientConfig.CABundle); got != "ca" {
+		t.Errorf("CABundle: got %q, want %q", got, "ca")
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	output := rec.Body.String()
+	for _, want := range []string{
+		`admission_webhook_cabundle_sync_attempts_total{name="retry-webhook",type="validating"} 2`,
+		`admission_webhook_cabundle_sync_failures_total{name="retry-webhook",type="validating"} 1`,
+		`admission_webhook_cabundle_last_sync_success_timestamp_seconds{name="retry-webhook",type="validating"}`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("metric %q not found in output", want)
+		}
+	}
+}
+
+func TestSyncer_Start_Resync(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca-bundle",
+			Namespace: "test-ns",
+		},
+		Data: map[string]string{
+			"ca-bundle.crt": "test-ca-bundle-data",
+		},
+	}
+	webhookConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-webhook",
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "test.webhook.svc", ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: []byte("test-ca-bundle-data")}},
+		},
+	}
+
+	client := fake.NewClientset(cm, webhookConfig)
+	refs := []WebhookRef{{Name: "test-webhook", Type: ValidatingWebhook}}
+	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	syncer.resyncPeriod = 50 * time.Millisecond
+	synced := make(chan WebhookRef, 16)
+	syncer.OnSynced(func(ref WebhookRef, err error) {
+		if err == nil {
+			select {
+			case synced <- ref:
+			default:
+			}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = syncer.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Unchanged configurations are checked every resync period, but neither
+	// written nor reported.
+	deadline := time.Now().Add(5 * time.Second)
+	for countActions(client, "get", "validatingwebhookconfigurations") < 4 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for resyncs")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := countActions(client, "patch", "validatingwebhookconfigurations"); n != 0 {
+		t.Errorf("patches of an up-to-date configuration: got %d, want 0", n)
+	}
+	select {
+	case ref := <-synced:
+		t.Errorf("unexpected sync of up-to-date %+v", ref)
+	default:
+	}
+
+	// A reset caBundle is restored and reported.
+	reset := webhookConfig.DeepCopy()
+	reset.Webhooks[0].ClientConfig.CABundle = nil
+	if _, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Update(ctx, reset, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to reset caBundle: %v", err)
+	}
+	select {
+	case ref := <-synced:
+		if ref != refs[0] {
+			t.Errorf("synced ref: got %+v, want %+v", ref, refs[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the caBundle to be restored")
+	}
+}
+
+// countActions returns the number of verb actions on resource recorded by client.
+func countActions(client *fake.Clientset, verb, resource string) int {
+	n := 0
+	for _, action := range client.Actions() {
+		if action.Matches(verb, resource) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSyncer_MatchService(t *testing.T) {
+	url := "https://webhook.example.com/validate"
+	newConfig := func() *admissionregistrationv1.ValidatingWebhookConfiguration {
+		service := func(namespace, name string) admissionregistrationv1.WebhookClientConfig {
+			return admissionregistrationv1.WebhookClientConfig{
+				Service:  &admissionregistrationv1.ServiceReference{Namespace: namespace, Name: name},
+				CABundle: []byte("other-ca"),
+			}
+		}
+		return &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{
+				{Name: "ours.example.com", ClientConfig: service("test-ns", "test-svc")},
+				{Name: "other-service.example.com", ClientConfig: service("test-ns", "other-svc")},
+				{Name: "other-namespace.example.com", ClientConfig: service("other-ns", "test-svc")},
+				{Name: "also-ours.example.com", ClientConfig: service("test-ns", "test-svc")},
+				{Name: "url.example.com", ClientConfig: admissionregistrationv1.WebhookClientConfig{URL: &url, CABundle: []byte("other-ca")}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ref     WebhookRef
+		want    []string
+		wantErr bool
+	}{
+		{"all entries of the service", WebhookRef{Name: "shared", Type: ValidatingWebhook}, []string{"ours.example.com", "also-ours.example.com"}, false},
+		{"named entry", WebhookRef{Name: "shared", Type: ValidatingWebhook, WebhookName: "also-ours.example.com"}, []string{"also-ours.example.com"}, false},
+		{"named URL entry", WebhookRef{Name: "shared", Type: ValidatingWebhook, WebhookName: "url.example.com"}, []string{"url.example.com"}, false},
+		{"named entry of another service", WebhookRef{Name: "shared", Type: ValidatingWebhook, WebhookName: "other-service.example.com"}, nil, true},
+		{"missing entry", WebhookRef{Name: "shared", Type: ValidatingWebhook, WebhookName: "missing.example.com"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientset(newConfig())
+			syncer := NewSyncer(client, "test-ns", "ca-bundle", []WebhookRef{tt.ref})
+			startQueue(t, syncer)
+			syncer.MatchService("test-svc")
+
+			ctx := context.Background()
+			_, err := syncer.patchWebhook(ctx, tt.ref, []byte("ca"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("patchWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			updated, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "shared", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Failed to get webhook: %v", err)
+			}
+			var patched []string
+			for _, webhook := range updated.Webhooks {
+				switch string(webhook.ClientConfig.CABundle) {
+				case "ca":
+					patched = append(patched, webhook.Name)
+				case "other-ca":
+				default:
+					t.Errorf("CABundle of %s: got %q", webhook.Name, webhook.ClientConfig.CABundle)
+				}
+			}
+			if !reflect.DeepEqual(patched, tt.want) {
+				t.Errorf("patched entries: got %v, want %v", patched, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// Entries of other services are not drift.
+			syncer.eventRecorder = events.NewInMemoryRecorder("test", clock.RealClock{})
+			syncer.onCABundle(ctx, []byte("ca"))
+			processQueue(ctx, syncer)
+			var repaired int
+			syncer.OnSynced(func(WebhookRef, error) { repaired++ })
+			syncer.onWebhookConfigUpdate(ctx, updated)
+			processQueue(ctx, syncer)
+			if repaired != 0 {
+				t.Errorf("repairs of an up-to-date configuration: got %d, want 0", repaired)
+			}
+		})
+	}
+}
+
+func TestSyncer_onObjectUpdate_NoCABundle(t *testing.T) {
 	client := fake.NewClientset()
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", nil)
+	startQueue(t, syncer)
 
 	// ConfigMap without ca-bundle.crt
 	cm := &corev1.ConfigMap{
@@ -198,7 +826,8 @@ func TestSyncer_onConfigMapUpdate_NoCABundle(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	syncer.onConfigMapUpdate(ctx, cm)
+	syncer.onObjectUpdate(ctx, cm)
+	processQueue(ctx, syncer)
 	// Should not panic or error
 }
 
@@ -249,12 +878,14 @@ func TestSyncer_syncMultipleWebhooks(t *testing.T) {
 	}
 
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
 
 	ctx := context.Background()
 	err := syncer.syncCABundle(ctx)
 	if err != nil {
 		t.Fatalf("syncCABundle failed: %v", err)
 	}
+	processQueue(ctx, syncer)
 
 	// Verify mutating webhook was updated
 	updatedMutating, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "mutating-webhook", metav1.GetOptions{})
@@ -292,12 +923,14 @@ func TestSyncer_EmptyWebhookRefs(t *testing.T) {
 
 	// No webhook refs
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", nil)
+	startQueue(t, syncer)
 
 	ctx := context.Background()
 	err := syncer.syncCABundle(ctx)
 	if err != nil {
 		t.Errorf("syncCABundle with empty refs should not error: %v", err)
 	}
+	processQueue(ctx, syncer)
 }
 
 func TestSyncer_WebhookWithMultipleHooks(t *testing.T) {
@@ -345,12 +978,14 @@ func TestSyncer_WebhookWithMultipleHooks(t *testing.T) {
 	}
 
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
 
 	ctx := context.Background()
 	err := syncer.syncCABundle(ctx)
 	if err != nil {
 		t.Fatalf("syncCABundle failed: %v", err)
 	}
+	processQueue(ctx, syncer)
 
 	// Verify all hooks were updated
 	updated, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "multi-hook-webhook", metav1.GetOptions{})
@@ -399,12 +1034,14 @@ func TestSyncer_EmptyCABundleData(t *testing.T) {
 	}
 
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
 
 	ctx := context.Background()
 	err := syncer.syncCABundle(ctx)
 	if err != nil {
 		t.Fatalf("syncCABundle failed: %v", err)
 	}
+	processQueue(ctx, syncer)
 
 	// Webhook should NOT be updated because ca-bundle.crt is empty
 	updated, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
@@ -450,12 +1087,14 @@ func TestSyncer_MissingCABundleKey(t *testing.T) {
 	}
 
 	syncer := NewSyncer(client, "test-ns", "ca-bundle", refs)
+	startQueue(t, syncer)
 
 	ctx := context.Background()
 	err := syncer.syncCABundle(ctx)
 	if err != nil {
 		t.Fatalf("syncCABundle failed: %v", err)
 	}
+	processQueue(ctx, syncer)
 
 	// Webhook should NOT be updated because ca-bundle.crt key is missing
 	updated, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
@@ -467,3 +1106,32 @@ func TestSyncer_MissingCABundleKey(t *testing.T) {
 			string(updated.Webhooks[0].ClientConfig.CABundle), "original-ca")
 	}
 }
+
+// managedFieldsOf returns the managed fields entry of manager.
+func managedFieldsOf(t *testing.T, managedFields []metav1.ManagedFieldsEntry, manager string) metav1.ManagedFieldsEntry {
+	t.Helper()
+
+	for _, entry := range managedFields {
+		if entry.Manager == manager {
+			return entry
+		}
+	}
+	t.Fatalf("No managed fields of %s in %v", manager, managedFields)
+	return metav1.ManagedFieldsEntry{}
+}
+
+// startQueue sets up the work queue of syncer as Start does, without workers.
+func startQueue(t *testing.T, syncer *Syncer) {
+	t.Helper()
+
+	syncer.queue = newSyncQueue()
+	t.Cleanup(syncer.queue.ShutDown)
+}
+
+// processQueue syncs the queued refs until the queue is empty. Failed syncs
+// are requeued with backoff and not processed again.
+func processQueue(ctx context.Context, syncer *Syncer) {
+	for syncer.queue.Len() > 0 {
+		syncer.processNextWorkItem(ctx)
+	}
+}
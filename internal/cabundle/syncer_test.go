@@ -27,6 +27,14 @@ func TestWebhookType_Constants(t *testing.T) {
 	if MutatingWebhook != "mutating" {
 		t.Errorf("MutatingWebhook: got %q, want %q", MutatingWebhook, "mutating")
 	}
+
+	if CRDConversionWebhook != "conversion" {
+		t.Errorf("CRDConversionWebhook: got %q, want %q", CRDConversionWebhook, "conversion")
+	}
+
+	if APIServiceWebhook != "apiservice" {
+		t.Errorf("APIServiceWebhook: got %q, want %q", APIServiceWebhook, "apiservice")
+	}
 }
 
 func TestNewSyncer(t *testing.T) {
@@ -41,8 +49,8 @@ func TestNewSyncer(t *testing.T) {
 		t.Errorf("namespace: got %q, want %q", syncer.namespace, "test-ns")
 	}
 
-	if syncer.caBundleConfigMapName != "ca-bundle-cm" {
-		t.Errorf("caBundleConfigMapName: got %q, want %q", syncer.caBundleConfigMapName, "ca-bundle-cm")
+	if syncer.source != ConfigMapSource("ca-bundle-cm") {
+		t.Errorf("source: got %v, want %v", syncer.source, ConfigMapSource("ca-bundle-cm"))
 	}
 
 	if len(syncer.webhookRefs) != 2 {
@@ -0,0 +1,28 @@
+// Package certcheck validates certificates shared between the certificate
+// manager and the certificate providers.
+package certcheck
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// ValidateCA returns an error if cert cannot be used to sign serving
+// certificates at now: it is not a CA, is not allowed to sign certificates or
+// is not within its validity period.
+func ValidateCA(cert *x509.Certificate, now time.Time) error {
+	if !cert.BasicConstraintsValid || !cert.IsCA {
+		return fmt.Errorf("certificate %q is not a CA", cert.Subject.CommonName)
+	}
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("certificate %q is not allowed to sign certificates", cert.Subject.CommonName)
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("certificate %q is not valid before %v", cert.Subject.CommonName, cert.NotBefore)
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("certificate %q expired at %v", cert.Subject.CommonName, cert.NotAfter)
+	}
+	return nil
+}
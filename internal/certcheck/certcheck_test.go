@@ -0,0 +1,35 @@
+package certcheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestValidateCA(t *testing.T) {
+	now := time.Now()
+	ca, err := crypto.MakeSelfSignedCAConfigForDuration("ca", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeSelfSignedCAConfigForDuration failed: %v", err)
+	}
+	serving, err := (&crypto.CA{Config: ca, SerialGenerator: &crypto.RandomSerialGenerator{}}).
+		MakeServerCertForDuration(sets.New("test"), time.Hour)
+	if err != nil {
+		t.Fatalf("MakeServerCertForDuration failed: %v", err)
+	}
+
+	if err := ValidateCA(ca.Certs[0], now); err != nil {
+		t.Errorf("Expected valid CA, got %v", err)
+	}
+	if err := ValidateCA(ca.Certs[0], now.Add(2*time.Hour)); err == nil {
+		t.Error("Expected error for expired CA")
+	}
+	if err := ValidateCA(ca.Certs[0], now.Add(-time.Hour)); err == nil {
+		t.Error("Expected error for CA that is not yet valid")
+	}
+	if err := ValidateCA(serving.Certs[0], now); err == nil {
+		t.Error("Expected error for serving certificate")
+	}
+}
@@ -0,0 +1,431 @@
+package certmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	ocpcrypto "github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/certrotation"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
+)
+
+const (
+	// csrManagedByLabel is set on the CertificateSigningRequests submitted by the manager.
+	csrManagedByLabel = "app.kubernetes.io/managed-by"
+	// csrManagedByValue is the value of csrManagedByLabel.
+	csrManagedByValue = "auto-cert-webhook"
+
+	// csrRetryInterval is the delay before a new CertificateSigningRequest is
+	// submitted after one was denied or failed.
+	csrRetryInterval = 5 * time.Minute
+
+	// minCSRExpirationSeconds is the minimum expirationSeconds accepted by the API server.
+	minCSRExpirationSeconds = 600
+
+	// pendingCSRAnnotation names the CertificateSigningRequest submitted for
+	// the key in pendingKeyDataKey of the serving certificate secret.
+	pendingCSRAnnotation = "auto-cert-webhook/pending-csr"
+	// pendingKeyDataKey holds the private key of the pending
+	// CertificateSigningRequest, so that a new leader can collect it.
+	pendingKeyDataKey = "pending.key"
+)
+
+// pendingCSR is a submitted CertificateSigningRequest and the private key it
+// was generated for.
+type pendingCSR struct {
+	name      string
+	key       crypto.Signer
+	subject   pkix.Name
+	hostnames []string
+
+	// forced is the force-rotate annotation value the request was submitted for.
+	forced string
+
+	// failedAt is set when the request was denied or failed.
+	failedAt time.Time
+}
+
+// startCSRInformer watches the submitted CertificateSigningRequests and
+// triggers a sync when they are approved, issued, denied or failed.
+func (m *Manager) startCSRInformer(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(m.k8sClient, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = csrManagedByLabel + "=" + csrManagedByValue
+		}),
+	)
+
+	csrInformer := factory.Certificates().V1().CertificateSigningRequests().Informer()
+	if _, err := csrInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, _ interface{}) {
+			select {
+			case m.syncCh <- struct{}{}:
+			default:
+			}
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to add CSR event handler: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	if !toolscache.WaitForCacheSync(ctx.Done(), csrInformer.HasSynced) {
+		return fmt.Errorf("could not sync CSR informer cache")
+	}
+	return nil
+}
+
+// ensureServingCertFromCSR ensures the serving certificate exists and is
+// valid, submitting a CertificateSigningRequest for a new one when it is
+// missing, invalid or due for rotation, and storing the issued certificate.
+func (m *Manager) ensureServingCertFromCSR(ctx context.Context) error {
+	secret, err := m.secretLister.Secrets(m.config.Namespace).Get(m.config.CertSecretName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		secret, err = m.createSecret(ctx, m.config.Namespace, m.config.CertSecretName)
+		if err != nil {
+			return err
+		}
+	}
+
+	hostnames := m.hostnames()
+	subject := m.subject()
+	reason := m.needNewCSRCert(secret, subject, hostnames)
+
+	pending := m.pendingCSR
+	if pending == nil {
+		// The request may have been submitted by a previous leader.
+		pending, err = m.restorePendingCSR(ctx, secret)
+		if err != nil {
+			return err
+		}
+	}
+	if reason == "" {
+		m.pendingCSR = nil
+		if pending == nil {
+			return nil
+		}
+		klog.Infof("Abandoning CertificateSigningRequest %s that is no longer needed", pending.name)
+		if err := m.deleteCSR(ctx, pending.name); err != nil {
+			return err
+		}
+		if _, ok := secret.Annotations[pendingCSRAnnotation]; !ok {
+			return nil
+		}
+		_, err := m.updateSecret(ctx, withoutPendingCSR(secret))
+		return err
+	}
+
+	if pending != nil && (!slices.Equal(pending.hostnames, hostnames) || pending.subject.String() != subject.String()) {
+		klog.Infof("Abandoning CertificateSigningRequest %s after a configuration change", pending.name)
+		if err := m.deleteCSR(ctx, pending.name); err != nil {
+			return err
+		}
+		pending = nil
+	}
+	if pending != nil && !pending.failedAt.IsZero() {
+		if m.config.Clock.Since(pending.failedAt) < csrRetryInterval {
+			m.pendingCSR = pending
+			return nil
+		}
+		if err := m.deleteCSR(ctx, pending.name); err != nil {
+			return err
+		}
+		pending = nil
+	}
+	if pending == nil {
+		m.eventRecorder.Eventf("TargetUpdateRequired", "%q in %q requires a new target cert/key pair: %v", secret.Name, secret.Namespace, reason)
+
+		pending, secret, err = m.submitCSR(ctx, secret, subject, hostnames)
+		if err != nil {
+			return err
+		}
+	}
+	m.pendingCSR = pending
+
+	return m.collectCSR(ctx, secret, pending)
+}
+
+// needNewCSRCert returns the reason the serving certificate in secret must be
+// requested again, or "" if it is still valid.
+func (m *Manager) needNewCSRCert(secret *corev1.Secret, subject pkix.Name, hostnames []string) string {
+	if forced := pendingForceRotate(secret); forced != "" {
+		return fmt.Sprintf("rotation forced by annotation %s=%q", ForceRotateAnnotation, forced)
+	}
+
+	cert, err := ocpcrypto.GetTLSCertificateConfigFromBytes(secret.Data["tls.crt"], secret.Data["tls.key"])
+	if err != nil {
+		return fmt.Sprintf("invalid target cert/key pair: %v", err)
+	}
+	leaf := cert.Certs[0]
+
+	if reason := needRefresh(leaf, m.config.CertRefresh, m.config.Clock.Now()); reason != "" {
+		return reason
+	}
+	if reason := keyMismatch(leaf, m.config.CertKey); reason != "" {
+		return reason
+	}
+	return namesMismatch(leaf, subject, hostnames)
+}
+
+// submitCSR generates a key and submits a CertificateSigningRequest for it.
+// The key is stored in secret before the request is submitted, so that it is
+// not lost when the leader changes before the certificate is issued.
+func (m *Manager) submitCSR(ctx context.Context, secret *corev1.Secret, subject pkix.Name, hostnames []string) (*pendingCSR, *corev1.Secret, error) {
+	key, err := m.config.CertKey.generateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.CertificateRequest{Subject: subject}
+	for _, hostname := range hostnames {
+		if ip := net.ParseIP(hostname); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, hostname)
+		}
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	usages := []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageServerAuth}
+	if m.config.CertKey.WithDefaults().Algorithm == KeyAlgorithmRSA {
+		usages = append(usages, certificatesv1.UsageKeyEncipherment)
+	}
+	expirationSeconds := int32(max(m.config.CertValidity/time.Second, minCSRExpirationSeconds))
+
+	pending := &pendingCSR{
+		name:      fmt.Sprintf("%s-%s-%d", m.config.Namespace, m.config.CertSecretName, m.config.Clock.Now().Unix()),
+		key:       key,
+		subject:   subject,
+		hostnames: hostnames,
+		forced:    pendingForceRotate(secret),
+	}
+
+	updated := secret.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[pendingCSRAnnotation] = pending.name
+	if updated.Data == nil {
+		updated.Data = map[string][]byte{}
+	}
+	updated.Data[pendingKeyDataKey] = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	secret, err = m.updateSecret(ctx, updated)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The names and force-rotate value are recorded on the request to
+	// restore it after a leader change.
+	annotations := map[string]string{certrotation.CertificateHostnames: strings.Join(hostnames, ",")}
+	if pending.forced != "" {
+		annotations[ForceRotateAnnotation] = pending.forced
+	}
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pending.name,
+			Labels:      map[string]string{csrManagedByLabel: csrManagedByValue},
+			Annotations: annotations,
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:           pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+			SignerName:        m.config.SignerName,
+			ExpirationSeconds: &expirationSeconds,
+			Usages:            usages,
+		},
+	}
+	csr, err = m.k8sClient.CertificatesV1().CertificateSigningRequests().Create(ctx, csr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CertificateSigningRequest: %w", err)
+	}
+	klog.Infof("Submitted CertificateSigningRequest %s for signer %s", csr.Name, m.config.SignerName)
+
+	return pending, secret, nil
+}
+
+// restorePendingCSR returns the CertificateSigningRequest named in secret
+// with the key stored next to it, or nil if there is none to collect.
+func (m *Manager) restorePendingCSR(ctx context.Context, secret *corev1.Secret) (*pendingCSR, error) {
+	name := secret.Annotations[pendingCSRAnnotation]
+	if name == "" {
+		return nil, nil
+	}
+
+	csr, err := m.k8sClient.CertificatesV1().CertificateSigningRequests().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	key, err := keyutil.ParsePrivateKeyPEM(secret.Data[pendingKeyDataKey])
+	if err != nil {
+		klog.Warningf("Abandoning CertificateSigningRequest %s: invalid key in secret %s/%s: %v", name, secret.Namespace, secret.Name, err)
+		return nil, m.deleteCSR(ctx, name)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		klog.Warningf("Abandoning CertificateSigningRequest %s: unsupported key type %T in secret %s/%s", name, key, secret.Namespace, secret.Name)
+		return nil, m.deleteCSR(ctx, name)
+	}
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil {
+		klog.Warningf("Abandoning CertificateSigningRequest %s: no PEM certificate request", name)
+		return nil, m.deleteCSR(ctx, name)
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		klog.Warningf("Abandoning CertificateSigningRequest %s: %v", name, err)
+		return nil, m.deleteCSR(ctx, name)
+	}
+
+	var hostnames []string
+	if value := csr.Annotations[certrotation.CertificateHostnames]; value != "" {
+		hostnames = strings.Split(value, ",")
+	}
+	klog.Infof("Resuming CertificateSigningRequest %s", name)
+	return &pendingCSR{
+		name:      name,
+		key:       signer,
+		subject:   request.Subject,
+		hostnames: hostnames,
+		forced:    csr.Annotations[ForceRotateAnnotation],
+	}, nil
+}
+
+// deleteCSR deletes the named CertificateSigningRequest if it still exists.
+func (m *Manager) deleteCSR(ctx context.Context, name string) error {
+	err := m.k8sClient.CertificatesV1().CertificateSigningRequests().Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete CertificateSigningRequest %s: %w", name, err)
+	}
+	return nil
+}
+
+// withoutPendingCSR returns a copy of secret without the pending
+// CertificateSigningRequest and its key.
+func withoutPendingCSR(secret *corev1.Secret) *corev1.Secret {
+	updated := secret.DeepCopy()
+	delete(updated.Annotations, pendingCSRAnnotation)
+	delete(updated.Data, pendingKeyDataKey)
+	return updated
+}
+
+// collectCSR approves the pending CertificateSigningRequest if configured and
+// stores the certificate in secret once it is issued.
+func (m *Manager) collectCSR(ctx context.Context, secret *corev1.Secret, pending *pendingCSR) error {
+	csr, err := m.k8sClient.CertificatesV1().CertificateSigningRequests().Get(ctx, pending.name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			m.pendingCSR = nil
+			return fmt.Errorf("CertificateSigningRequest %s was deleted before it was issued", pending.name)
+		}
+		return err
+	}
+
+	approved := false
+	for _, condition := range csr.Status.Conditions {
+		switch condition.Type {
+		case certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+			pending.failedAt = m.config.Clock.Now()
+			m.eventRecorder.Warningf("CertificateSigningRequest"+string(condition.Type), "CertificateSigningRequest %s for %q in %q: %s %s",
+				csr.Name, secret.Name, secret.Namespace, condition.Reason, condition.Message)
+			return fmt.Errorf("CertificateSigningRequest %s %s: %s", csr.Name, strings.ToLower(string(condition.Type)), condition.Message)
+		case certificatesv1.CertificateApproved:
+			approved = true
+		}
+	}
+
+	if !approved && m.config.CSRAutoApprove {
+		csr = csr.DeepCopy()
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:           certificatesv1.CertificateApproved,
+			Status:         corev1.ConditionTrue,
+			Reason:         "AutoApproved",
+			Message:        "Approved by auto-cert-webhook",
+			LastUpdateTime: metav1.NewTime(m.config.Clock.Now()),
+		})
+		csr, err = m.k8sClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to approve CertificateSigningRequest %s: %w", pending.name, err)
+		}
+		klog.Infof("Approved CertificateSigningRequest %s", csr.Name)
+	}
+
+	if len(csr.Status.Certificate) == 0 {
+		klog.V(2).Infof("Waiting for CertificateSigningRequest %s to be issued", csr.Name)
+		return nil
+	}
+
+	certs, err := cert.ParseCertsPEM(csr.Status.Certificate)
+	if err != nil {
+		return fmt.Errorf("invalid certificate issued for CertificateSigningRequest %s: %w", csr.Name, err)
+	}
+	if !publicKeyEqual(certs[0].PublicKey, pending.key.Public()) {
+		m.pendingCSR = nil
+		if err := m.deleteCSR(ctx, csr.Name); err != nil {
+			return err
+		}
+		return fmt.Errorf("certificate issued for CertificateSigningRequest %s does not match its key", csr.Name)
+	}
+
+	annotations := map[string]string{
+		certrotation.CertificateHostnames: strings.Join(pending.hostnames, ","),
+	}
+	if pending.forced != "" {
+		annotations[ForceRotateHandledAnnotation] = pending.forced
+	}
+	secret, err = m.updateCertSecret(ctx, withoutPendingCSR(secret), &ocpcrypto.TLSCertificateConfig{Certs: certs, Key: pending.key}, m.config.CertRefresh, annotations)
+	if err != nil {
+		return err
+	}
+	m.pendingCSR = nil
+	if pending.forced != "" {
+		metrics.RecordForcedRotation("serving")
+	}
+	klog.Infof("Stored certificate issued for CertificateSigningRequest %s in secret %s/%s", csr.Name, secret.Namespace, secret.Name)
+	return m.deleteCSR(ctx, csr.Name)
+}
+
+// publicKeyEqual reports whether a and b are the same public key.
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	aDER, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false
+	}
+	bDER, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aDER, bDER)
+}
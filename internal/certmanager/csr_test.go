@@ -0,0 +1,356 @@
+package certmanager
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/crypto"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+const testSignerName = "example.com/webhook-serving"
+
+func newTestCSRConfig() Config {
+	config := newTestConfig()
+	config.SignerName = testSignerName
+	config.CSRAutoApprove = true
+	return config
+}
+
+func newTestCertSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cert", Namespace: "test-ns"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{"tls.crt": {}, "tls.key": {}},
+	}
+}
+
+func TestManager_sync_CSR(t *testing.T) {
+	client := fake.NewClientset(newTestCertSecret())
+	m := newTestManager(t, client, newTestCSRConfig())
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	csr := onlyCSR(t, client)
+	if csr.Spec.SignerName != testSignerName {
+		t.Errorf("signer name: got %q, want %q", csr.Spec.SignerName, testSignerName)
+	}
+	if csr.Labels[csrManagedByLabel] != csrManagedByValue {
+		t.Errorf("managed-by label: got %q, want %q", csr.Labels[csrManagedByLabel], csrManagedByValue)
+	}
+	if csr.Spec.ExpirationSeconds == nil || *csr.Spec.ExpirationSeconds != int32((24*time.Hour).Seconds()) {
+		t.Errorf("expiration seconds: got %v, want %d", csr.Spec.ExpirationSeconds, int32((24 * time.Hour).Seconds()))
+	}
+	if !csrHasCondition(csr, certificatesv1.CertificateApproved) {
+		t.Fatalf("expected CSR to be auto-approved, got conditions %v", csr.Status.Conditions)
+	}
+
+	request := parseTestCSR(t, csr)
+	wantNames := []string{"test-svc", "test-svc.test-ns", "test-svc.test-ns.svc"}
+	if !reflect.DeepEqual(request.DNSNames, wantNames) {
+		t.Errorf("CSR DNS names: got %v, want %v", request.DNSNames, wantNames)
+	}
+
+	// No certificate is stored until the signer issued it.
+	secret, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get cert secret: %v", err)
+	}
+	if len(secret.Data["tls.crt"]) != 0 {
+		t.Fatal("expected cert secret to be empty before the CSR is issued")
+	}
+	if secret.Annotations[pendingCSRAnnotation] != csr.Name {
+		t.Errorf("pending CSR annotation: got %q, want %q", secret.Annotations[pendingCSRAnnotation], csr.Name)
+	}
+	if len(secret.Data[pendingKeyDataKey]) == 0 {
+		t.Error("expected the pending key to be stored in the cert secret")
+	}
+
+	ca, err := newSelfSignedCA("test-signer", KeySpec{}, 48*time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("newSelfSignedCA failed: %v", err)
+	}
+	issueTestCSR(t, client, csr, ca)
+
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	leaf := servingLeaf(t, client)
+	if leaf.Issuer.CommonName != "test-signer" {
+		t.Errorf("issuer: got %q, want %q", leaf.Issuer.CommonName, "test-signer")
+	}
+	if !reflect.DeepEqual(leaf.DNSNames, wantNames) {
+		t.Errorf("DNS names: got %v, want %v", leaf.DNSNames, wantNames)
+	}
+	if m.pendingCSR != nil {
+		t.Errorf("expected pending CSR to be cleared, got %s", m.pendingCSR.name)
+	}
+
+	// The collected CSR and the pending key are removed.
+	if csrs := listCSRs(t, client); len(csrs) != 0 {
+		t.Errorf("CSRs after collection: got %d, want 0", len(csrs))
+	}
+	secret, err = client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get cert secret: %v", err)
+	}
+	if _, ok := secret.Annotations[pendingCSRAnnotation]; ok {
+		t.Error("expected the pending CSR annotation to be removed")
+	}
+	if _, ok := secret.Data[pendingKeyDataKey]; ok {
+		t.Error("expected the pending key to be removed")
+	}
+
+	// The stored certificate is valid, so no new CSR is submitted.
+	waitForCertSecretCache(t, m, client)
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if csrs := listCSRs(t, client); len(csrs) != 0 {
+		t.Errorf("CSRs after resync: got %d, want 0", len(csrs))
+	}
+}
+
+func TestManager_sync_CSRLeaderChange(t *testing.T) {
+	config := newTestCSRConfig()
+	config.CSRAutoApprove = false
+
+	client := fake.NewClientset(newTestCertSecret())
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	csr := onlyCSR(t, client)
+
+	ca, err := newSelfSignedCA("test-signer", KeySpec{}, 48*time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("newSelfSignedCA failed: %v", err)
+	}
+	issueTestCSR(t, client, csr, ca)
+
+	// A new leader collects the CSR with the key stored in the cert secret.
+	next := newTestManager(t, client, config)
+	if err := next.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	leaf := servingLeaf(t, client)
+	if leaf.Issuer.CommonName != "test-signer" {
+		t.Errorf("issuer: got %q, want %q", leaf.Issuer.CommonName, "test-signer")
+	}
+	if csrs := listCSRs(t, client); len(csrs) != 0 {
+		t.Errorf("CSRs after collection: got %d, want 0", len(csrs))
+	}
+}
+
+func TestManager_sync_CSRConfigChange(t *testing.T) {
+	config := newTestCSRConfig()
+	config.CSRAutoApprove = false
+
+	client := fake.NewClientset(newTestCertSecret())
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	previous := onlyCSR(t, client)
+
+	// The CSR for the previous names is replaced.
+	m.config.ExtraDNSNames = []string{"webhook.example.com"}
+	m.config.Clock = testingclock.NewFakeClock(time.Now().Add(time.Minute))
+	waitForCertSecretCache(t, m, client)
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	csr := onlyCSR(t, client)
+	if csr.Name == previous.Name {
+		t.Errorf("expected CSR %s to be replaced", previous.Name)
+	}
+	if got := parseTestCSR(t, csr).DNSNames; !slices.Contains(got, "webhook.example.com") {
+		t.Errorf("CSR DNS names: got %v, want webhook.example.com", got)
+	}
+}
+
+func TestManager_sync_CSRNotApproved(t *testing.T) {
+	config := newTestCSRConfig()
+	config.CSRAutoApprove = false
+
+	client := fake.NewClientset(newTestCertSecret())
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	// The pending CSR is waited on instead of being submitted again.
+	csr := onlyCSR(t, client)
+	if len(csr.Status.Conditions) != 0 {
+		t.Errorf("expected CSR to wait for approval, got conditions %v", csr.Status.Conditions)
+	}
+}
+
+func TestManager_sync_CSRDenied(t *testing.T) {
+	clk := testingclock.NewFakeClock(time.Now())
+	config := newTestCSRConfig()
+	config.CSRAutoApprove = false
+	config.Clock = clk
+
+	client := fake.NewClientset(newTestCertSecret())
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	csr := onlyCSR(t, client)
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateDenied,
+		Status:  corev1.ConditionTrue,
+		Reason:  "PolicyViolation",
+		Message: "not allowed",
+	})
+	if _, err := client.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to deny CSR: %v", err)
+	}
+
+	if err := m.sync(ctx); err == nil {
+		t.Fatal("expected sync to fail for a denied CSR")
+	}
+
+	// A new CSR is only submitted after the retry interval.
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	onlyCSR(t, client)
+
+	// The denied CSR is replaced after the retry interval.
+	clk.Step(csrRetryInterval)
+	waitForCertSecretCache(t, m, client)
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if retried := onlyCSR(t, client); retried.Name == csr.Name {
+		t.Errorf("expected denied CSR %s to be replaced", csr.Name)
+	}
+}
+
+// listCSRs returns the CertificateSigningRequests submitted to client.
+func listCSRs(t *testing.T, client *fake.Clientset) []certificatesv1.CertificateSigningRequest {
+	t.Helper()
+
+	list, err := client.CertificatesV1().CertificateSigningRequests().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list CSRs: %v", err)
+	}
+	return list.Items
+}
+
+// onlyCSR returns the single CertificateSigningRequest submitted to client.
+func onlyCSR(t *testing.T, client *fake.Clientset) *certificatesv1.CertificateSigningRequest {
+	t.Helper()
+
+	csrs := listCSRs(t, client)
+	if len(csrs) != 1 {
+		t.Fatalf("CSRs: got %d, want 1", len(csrs))
+	}
+	return &csrs[0]
+}
+
+func csrHasCondition(csr *certificatesv1.CertificateSigningRequest, conditionType certificatesv1.RequestConditionType) bool {
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTestCSR(t *testing.T, csr *certificatesv1.CertificateSigningRequest) *x509.CertificateRequest {
+	t.Helper()
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		t.Fatalf("CSR %s has no PEM certificate request", csr.Name)
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate request: %v", err)
+	}
+	return request
+}
+
+// issueTestCSR signs csr with ca and sets the certificate in its status, as a
+// signer controller would.
+func issueTestCSR(t *testing.T, client *fake.Clientset, csr *certificatesv1.CertificateSigningRequest, ca *crypto.TLSCertificateConfig) {
+	t.Helper()
+
+	request := parseTestCSR(t, csr)
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      request.Subject,
+		DNSNames:     request.DNSNames,
+		IPAddresses:  request.IPAddresses,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(time.Duration(*csr.Spec.ExpirationSeconds) * time.Second),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certs[0], request.PublicKey, ca.Key)
+	if err != nil {
+		t.Fatalf("Failed to sign CSR: %v", err)
+	}
+
+	current, err := client.CertificatesV1().CertificateSigningRequests().Get(context.Background(), csr.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get CSR: %v", err)
+	}
+	current.Status.Certificate = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if _, err := client.CertificatesV1().CertificateSigningRequests().UpdateStatus(context.Background(), current, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update CSR status: %v", err)
+	}
+}
+
+// waitForCertSecretCache waits until the informer cache holds the current cert secret.
+func waitForCertSecretCache(t *testing.T, m *Manager, client *fake.Clientset) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		secret, err := client.CoreV1().Secrets("test-ns").Get(context.Background(), "test-cert", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get cert secret: %v", err)
+		}
+		cached, err := m.informers.InformersFor("test-ns").Core().V1().Secrets().Lister().Secrets("test-ns").Get("test-cert")
+		if err == nil && reflect.DeepEqual(cached.Data, secret.Data) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for informer cache")
+}
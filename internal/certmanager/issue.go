@@ -0,0 +1,188 @@
+package certmanager
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	ocpcrypto "github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/certrotation"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// backdate is subtracted from the NotBefore time of issued certificates to
+// tolerate small clock skew.
+const backdate = time.Second
+
+// serialNumberLimit bounds the random serial numbers of issued certificates.
+var serialNumberLimit = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// newSelfSignedCA creates a self-signed CA certificate valid from now for validity.
+func newSelfSignedCA(commonName string, keySpec KeySpec, validity time.Duration, now time.Time) (*ocpcrypto.TLSCertificateConfig, error) {
+	key, err := keySpec.generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, serialNumberLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ocpcrypto.TLSCertificateConfig{
+		Certs: []*x509.Certificate{cert},
+		Key:   key,
+	}, nil
+}
+
+// newServingCert creates a serving certificate for subject and hostnames
+// signed by ca. Hostnames that parse as IP addresses become IP SANs.
+// The validity is capped at the remaining validity of the CA. The returned
+// chain contains the serving certificate followed by the CA certificates.
+func newServingCert(ca *ocpcrypto.CA, subject pkix.Name, hostnames []string, keySpec KeySpec, validity time.Duration, now time.Time) (*ocpcrypto.TLSCertificateConfig, error) {
+	if len(hostnames) == 0 {
+		return nil, fmt.Errorf("no hostnames set")
+	}
+
+	signer, ok := ca.Config.Key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("CA key of type %T cannot sign certificates", ca.Config.Key)
+	}
+	caCert := ca.Config.Certs[0]
+	if remaining := caCert.NotAfter.Sub(now); remaining < validity {
+		validity = remaining
+	}
+
+	key, err := keySpec.generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, serialNumberLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if keySpec.WithDefaults().Algorithm == KeyAlgorithmRSA {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, hostname := range hostnames {
+		if ip := net.ParseIP(hostname); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, hostname)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create serving certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ocpcrypto.TLSCertificateConfig{
+		Certs: append([]*x509.Certificate{cert}, ca.Config.Certs...),
+		Key:   key,
+	}, nil
+}
+
+// servingRotation is the certrotation.TargetCertCreator of the serving
+// certificate. On top of the rotation of certrotation.ServingRotation it
+// issues certificates with the configured key and subject and reissues them
+// when the configuration changes or a rotation is forced.
+type servingRotation struct {
+	certrotation.ServingRotation
+
+	manager *Manager
+	subject pkix.Name
+
+	// forced is the force-rotate annotation value of the current secret.
+	forced string
+	// issued reports whether a new certificate was issued.
+	issued bool
+}
+
+func (r *servingRotation) NewCertificate(signer *ocpcrypto.CA, validity time.Duration) (*ocpcrypto.TLSCertificateConfig, error) {
+	cert, err := newServingCert(signer, r.subject, r.Hostnames(), r.manager.config.CertKey, validity, r.manager.config.Clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	r.issued = true
+	return cert, nil
+}
+
+func (r *servingRotation) NeedNewTargetCertKeyPair(currentCertSecret *corev1.Secret, signer *ocpcrypto.CA, caBundleCerts []*x509.Certificate, refresh time.Duration, refreshOnlyWhenExpired, creationRequired bool) string {
+	r.forced = pendingForceRotate(currentCertSecret)
+	if r.forced != "" {
+		return fmt.Sprintf("rotation forced by annotation %s=%q", ForceRotateAnnotation, r.forced)
+	}
+
+	if reason := r.ServingRotation.NeedNewTargetCertKeyPair(currentCertSecret, signer, caBundleCerts, refresh, refreshOnlyWhenExpired, creationRequired); reason != "" {
+		return reason
+	}
+	return r.manager.needNewServingCert(currentCertSecret, signer, r.subject, r.Hostnames())
+}
+
+func (r *servingRotation) SetAnnotations(cert *ocpcrypto.TLSCertificateConfig, annotations map[string]string) map[string]string {
+	annotations = r.ServingRotation.SetAnnotations(cert, annotations)
+	if r.forced != "" {
+		annotations[ForceRotateHandledAnnotation] = r.forced
+	}
+	return annotations
+}
+
+// encodeCertKeyPair returns the PEM encoded certificate chain and PKCS #8 private key.
+func encodeCertKeyPair(config *ocpcrypto.TLSCertificateConfig) ([]byte, []byte, error) {
+	certPEM, err := ocpcrypto.EncodeCertificates(config.Certs...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(config.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,145 @@
+package certmanager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+)
+
+// KeyAlgorithm is the public key algorithm of a generated certificate.
+type KeyAlgorithm string
+
+const (
+	// KeyAlgorithmRSA generates RSA keys.
+	KeyAlgorithmRSA KeyAlgorithm = "RSA"
+	// KeyAlgorithmECDSA generates ECDSA keys on the NIST curve matching the key size.
+	KeyAlgorithmECDSA KeyAlgorithm = "ECDSA"
+	// KeyAlgorithmEd25519 generates Ed25519 keys.
+	KeyAlgorithmEd25519 KeyAlgorithm = "Ed25519"
+)
+
+const (
+	defaultRSAKeySize   = 2048
+	minRSAKeySize       = 2048
+	defaultECDSAKeySize = 256
+)
+
+// libraryGoKeySpec is the key of the certificates generated by library-go.
+var libraryGoKeySpec = KeySpec{Algorithm: KeyAlgorithmRSA, Size: 2048}
+
+// KeySpec describes the key of a generated certificate.
+type KeySpec struct {
+	// Algorithm is the key algorithm. Defaults to RSA.
+	Algorithm KeyAlgorithm
+
+	// Size is the key size: the modulus size in bits for RSA (default 2048,
+	// minimum 2048) or the curve size for ECDSA (256, 384 or 521, default 256).
+	// Must be zero for Ed25519.
+	Size int
+}
+
+// WithDefaults returns the spec with the default algorithm and size applied.
+func (s KeySpec) WithDefaults() KeySpec {
+	if s.Algorithm == "" {
+		s.Algorithm = KeyAlgorithmRSA
+	}
+	if s.Size == 0 {
+		switch s.Algorithm {
+		case KeyAlgorithmRSA:
+			s.Size = defaultRSAKeySize
+		case KeyAlgorithmECDSA:
+			s.Size = defaultECDSAKeySize
+		}
+	}
+	return s
+}
+
+// Validate returns an error if the algorithm is unknown or the size is not
+// supported by the algorithm.
+func (s KeySpec) Validate() error {
+	s = s.WithDefaults()
+	switch s.Algorithm {
+	case KeyAlgorithmRSA:
+		if s.Size < minRSAKeySize {
+			return fmt.Errorf("RSA key size must be at least %d, got %d", minRSAKeySize, s.Size)
+		}
+	case KeyAlgorithmECDSA:
+		if _, err := ecdsaCurve(s.Size); err != nil {
+			return err
+		}
+	case KeyAlgorithmEd25519:
+		if s.Size != 0 {
+			return fmt.Errorf("key size is not supported for Ed25519, got %d", s.Size)
+		}
+	default:
+		return fmt.Errorf("unknown key algorithm %q, must be %s, %s or %s", s.Algorithm, KeyAlgorithmRSA, KeyAlgorithmECDSA, KeyAlgorithmEd25519)
+	}
+	return nil
+}
+
+// String returns the spec in the form used by logs and events, e.g. "ECDSA-256".
+func (s KeySpec) String() string {
+	s = s.WithDefaults()
+	if s.Size == 0 {
+		return string(s.Algorithm)
+	}
+	return fmt.Sprintf("%s-%d", s.Algorithm, s.Size)
+}
+
+// encodableByLibraryGo reports whether library-go can store keys of the spec
+// in secrets, which excludes Ed25519 keys.
+func (s KeySpec) encodableByLibraryGo() bool {
+	return s.WithDefaults().Algorithm != KeyAlgorithmEd25519
+}
+
+// generateKey generates a new private key for the spec.
+func (s KeySpec) generateKey() (crypto.Signer, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	s = s.WithDefaults()
+	switch s.Algorithm {
+	case KeyAlgorithmECDSA:
+		curve, _ := ecdsaCurve(s.Size)
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case KeyAlgorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return rsa.GenerateKey(rand.Reader, s.Size)
+	}
+}
+
+// keySpecOf returns the spec of a public key, or false if the key type is
+// not supported.
+func keySpecOf(pub crypto.PublicKey) (KeySpec, bool) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return KeySpec{Algorithm: KeyAlgorithmRSA, Size: key.N.BitLen()}, true
+	case *ecdsa.PublicKey:
+		return KeySpec{Algorithm: KeyAlgorithmECDSA, Size: key.Curve.Params().BitSize}, true
+	case ed25519.PublicKey:
+		return KeySpec{Algorithm: KeyAlgorithmEd25519}, true
+	default:
+		return KeySpec{}, false
+	}
+}
+
+// ecdsaCurve returns the NIST curve for an ECDSA key size.
+func ecdsaCurve(size int) (elliptic.Curve, error) {
+	switch size {
+	case 256:
+		return elliptic.P256(), nil
+	case 384:
+		return elliptic.P384(), nil
+	case 521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("ECDSA key size must be 256, 384 or 521, got %d", size)
+	}
+}
@@ -0,0 +1,83 @@
+package certmanager
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"testing"
+)
+
+func TestKeySpec_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    KeySpec
+		wantErr bool
+	}{
+		{"default", KeySpec{}, false},
+		{"rsa 3072", KeySpec{Algorithm: KeyAlgorithmRSA, Size: 3072}, false},
+		{"rsa 1024", KeySpec{Algorithm: KeyAlgorithmRSA, Size: 1024}, true},
+		{"ecdsa default", KeySpec{Algorithm: KeyAlgorithmECDSA}, false},
+		{"ecdsa 521", KeySpec{Algorithm: KeyAlgorithmECDSA, Size: 521}, false},
+		{"ecdsa 2048", KeySpec{Algorithm: KeyAlgorithmECDSA, Size: 2048}, true},
+		{"ed25519", KeySpec{Algorithm: KeyAlgorithmEd25519}, false},
+		{"ed25519 with size", KeySpec{Algorithm: KeyAlgorithmEd25519, Size: 256}, true},
+		{"unknown", KeySpec{Algorithm: "DSA"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeySpec_String(t *testing.T) {
+	tests := []struct {
+		spec KeySpec
+		want string
+	}{
+		{KeySpec{}, "RSA-2048"},
+		{KeySpec{Algorithm: KeyAlgorithmECDSA}, "ECDSA-256"},
+		{KeySpec{Algorithm: KeyAlgorithmECDSA, Size: 384}, "ECDSA-384"},
+		{KeySpec{Algorithm: KeyAlgorithmEd25519}, "Ed25519"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.spec.String(); got != tt.want {
+			t.Errorf("String(): got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestKeySpec_generateKey(t *testing.T) {
+	key, err := KeySpec{Algorithm: KeyAlgorithmRSA}.generateKey()
+	if err != nil {
+		t.Fatalf("generateKey failed: %v", err)
+	}
+	if rsaKey, ok := key.(*rsa.PrivateKey); !ok || rsaKey.N.BitLen() != 2048 {
+		t.Errorf("Expected 2048-bit RSA key, got %T", key)
+	}
+
+	key, err = KeySpec{Algorithm: KeyAlgorithmECDSA, Size: 384}.generateKey()
+	if err != nil {
+		t.Fatalf("generateKey failed: %v", err)
+	}
+	if ecKey, ok := key.(*ecdsa.PrivateKey); !ok || ecKey.Curve.Params().BitSize != 384 {
+		t.Errorf("Expected P-384 ECDSA key, got %T", key)
+	}
+
+	key, err = KeySpec{Algorithm: KeyAlgorithmEd25519}.generateKey()
+	if err != nil {
+		t.Fatalf("generateKey failed: %v", err)
+	}
+	if _, ok := key.(ed25519.PrivateKey); !ok {
+		t.Errorf("Expected Ed25519 key, got %T", key)
+	}
+
+	if _, err := (KeySpec{Algorithm: "DSA"}).generateKey(); err == nil {
+		t.Error("Expected error for unknown algorithm")
+	}
+}
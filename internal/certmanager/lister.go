@@ -0,0 +1,89 @@
+package certmanager
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+)
+
+// The manager creates the CA and serving certificate secrets and the CA
+// bundle configmap itself, and the next sync may run before the informers
+// have seen them. The live listers read objects missing from the informer
+// cache from the API server, so an existing object is never created again
+// (failing with AlreadyExists) and a CA that was just created is not replaced.
+
+// liveSecretLister is a SecretLister falling back to the API server for
+// secrets missing from the cache.
+type liveSecretLister struct {
+	listerscorev1.SecretLister
+	client kubernetes.Interface
+}
+
+func newLiveSecretLister(lister listerscorev1.SecretLister, client kubernetes.Interface) listerscorev1.SecretLister {
+	return &liveSecretLister{SecretLister: lister, client: client}
+}
+
+// Secrets returns a lister for the secrets in namespace.
+func (l *liveSecretLister) Secrets(namespace string) listerscorev1.SecretNamespaceLister {
+	return &liveSecretNamespaceLister{
+		SecretNamespaceLister: l.SecretLister.Secrets(namespace),
+		client:                l.client,
+		namespace:             namespace,
+	}
+}
+
+type liveSecretNamespaceLister struct {
+	listerscorev1.SecretNamespaceLister
+	client    kubernetes.Interface
+	namespace string
+}
+
+// Get returns the cached secret, or the secret from the API server if it is
+// not cached yet.
+func (l *liveSecretNamespaceLister) Get(name string) (*corev1.Secret, error) {
+	secret, err := l.SecretNamespaceLister.Get(name)
+	if !errors.IsNotFound(err) {
+		return secret, err
+	}
+	return l.client.CoreV1().Secrets(l.namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// liveConfigMapLister is a ConfigMapLister falling back to the API server for
+// configmaps missing from the cache.
+type liveConfigMapLister struct {
+	listerscorev1.ConfigMapLister
+	client kubernetes.Interface
+}
+
+func newLiveConfigMapLister(lister listerscorev1.ConfigMapLister, client kubernetes.Interface) listerscorev1.ConfigMapLister {
+	return &liveConfigMapLister{ConfigMapLister: lister, client: client}
+}
+
+// ConfigMaps returns a lister for the configmaps in namespace.
+func (l *liveConfigMapLister) ConfigMaps(namespace string) listerscorev1.ConfigMapNamespaceLister {
+	return &liveConfigMapNamespaceLister{
+		ConfigMapNamespaceLister: l.ConfigMapLister.ConfigMaps(namespace),
+		client:                   l.client,
+		namespace:                namespace,
+	}
+}
+
+type liveConfigMapNamespaceLister struct {
+	listerscorev1.ConfigMapNamespaceLister
+	client    kubernetes.Interface
+	namespace string
+}
+
+// Get returns the cached configmap, or the configmap from the API server if
+// it is not cached yet.
+func (l *liveConfigMapNamespaceLister) Get(name string) (*corev1.ConfigMap, error) {
+	configMap, err := l.ConfigMapNamespaceLister.Get(name)
+	if !errors.IsNotFound(err) {
+		return configMap, err
+	}
+	return l.client.CoreV1().ConfigMaps(l.namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
@@ -3,7 +3,9 @@ package certmanager
 import (
 	"context"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/openshift/library-go/pkg/crypto"
@@ -14,11 +16,29 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
 	listerscorev1 "k8s.io/client-go/listers/core/v1"
 	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/cert"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
+
+	"github.com/jimyag/auto-cert-webhook/internal/cabundle"
+	"github.com/jimyag/auto-cert-webhook/internal/certcheck"
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
+)
+
+const (
+	// ForceRotateAnnotation forces the rotation of the certificate in the
+	// annotated CA or serving certificate secret whenever its value changes,
+	// e.g. when set to the current timestamp. It is reported and ignored on an
+	// external CA.
+	ForceRotateAnnotation = "auto-cert-webhook/force-rotate"
+
+	// ForceRotateHandledAnnotation records the last ForceRotateAnnotation
+	// value a rotation was performed for.
+	ForceRotateHandledAnnotation = "auto-cert-webhook/force-rotate-handled"
 )
 
 // Config holds the certificate manager configuration.
@@ -29,6 +49,25 @@ type Config struct {
 	// ServiceName is the name of the service for the webhook.
 	ServiceName string
 
+	// ClusterDomain is the cluster DNS domain, e.g. "cluster.local". When set,
+	// the fully qualified service name is added to the serving certificate.
+	ClusterDomain string
+
+	// ExtraDNSNames are additional DNS names of the serving certificate,
+	// e.g. for webhooks reached by URL.
+	ExtraDNSNames []string
+
+	// IPAddresses are the IP addresses of the serving certificate,
+	// e.g. for webhooks running on the host network.
+	IPAddresses []string
+
+	// CommonName is the subject common name of the serving certificate.
+	// If empty, defaults to ServiceName.
+	CommonName string
+
+	// Organizations are the subject organizations of the serving certificate.
+	Organizations []string
+
 	// CASecretName is the name of the CA secret.
 	CASecretName string
 
@@ -50,11 +89,43 @@ type Config struct {
 	// CertRefresh is the refresh interval for the server certificate.
 	CertRefresh time.Duration
 
+	// CAKey is the key algorithm and size of the CA certificate.
+	CAKey KeySpec
+
+	// CertKey is the key algorithm and size of the serving certificate.
+	CertKey KeySpec
+
+	// ExternalCA signs serving certificates with the CA provided in
+	// CASecretName instead of a generated one. The secret is read-only: its
+	// tls.crt holds the signing certificate followed by its chain, tls.key the
+	// signing key and the optional ca.crt the root certificate.
+	ExternalCA bool
+
+	// SignerName issues the serving certificate through the
+	// certificates.k8s.io/v1 CertificateSigningRequest API with this signer
+	// instead of a CA managed by the manager.
+	SignerName string
+
+	// CSRAutoApprove approves the CertificateSigningRequests submitted for
+	// SignerName, which requires permission to approve for the signer.
+	CSRAutoApprove bool
+
+	// CABundleFullChain adds the chain of the external CA, including the root
+	// certificate from ca.crt, to the CA bundle instead of only the signing
+	// certificate.
+	CABundleFullChain bool
+
 	// SyncInterval is the interval between certificate sync checks.
 	SyncInterval time.Duration
+
+	// Clock drives the sync loop and event timestamps.
+	// If nil, the real clock is used.
+	Clock clock.WithTicker
 }
 
-// Manager handles certificate rotation using openshift/library-go.
+// Manager generates and rotates the CA and serving certificates and maintains
+// the CA bundle configmap with openshift/library-go, adding support for
+// configurable keys, forced rotations and configuration changes.
 type Manager struct {
 	config Config
 
@@ -64,10 +135,31 @@ type Manager struct {
 
 	secretLister    listerscorev1.SecretLister
 	configMapLister listerscorev1.ConfigMapLister
+
+	// syncCh triggers a sync outside of the sync interval, e.g. for forced rotations.
+	syncCh chan struct{}
+
+	// pendingCSR is the CertificateSigningRequest waiting to be issued.
+	// Only accessed from the sync loop.
+	pendingCSR *pendingCSR
+
+	// externalCA is the external CA certificate loaded by the last sync, used
+	// to detect when it is replaced. Only accessed from the sync loop.
+	externalCA *x509.Certificate
+	// externalCAForced is the last force-rotate annotation value reported as
+	// ignored on the external CA. Only accessed from the sync loop.
+	externalCAForced string
+
+	// onCARotated is called with the new CA certificate after a rotation, or nil.
+	onCARotated func(ca *x509.Certificate)
 }
 
 // New creates a new certificate manager.
 func New(client kubernetes.Interface, config Config) *Manager {
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+
 	informers := v1helpers.NewKubeInformersForNamespaces(client, config.Namespace)
 
 	controllerRef, err := events.GetControllerReferenceForCurrentPod(context.TODO(), client, config.Namespace, nil)
@@ -75,40 +167,35 @@ func New(client kubernetes.Interface, config Config) *Manager {
 		klog.V(4).Infof("Unable to get controller reference: %v", err)
 	}
 
-	eventRecorder := events.NewRecorder(client.CoreV1().Events(config.Namespace), config.Namespace, controllerRef, clock.RealClock{})
+	eventRecorder := events.NewRecorder(client.CoreV1().Events(config.Namespace), config.Namespace, controllerRef, config.Clock)
 
 	return &Manager{
 		config:        config,
 		k8sClient:     client,
 		informers:     informers,
 		eventRecorder: eventRecorder,
+		syncCh:        make(chan struct{}, 1),
 	}
 }
 
+// OnCARotated registers fn to be called with the new CA certificate after a
+// CA was generated or the external CA was replaced. Must be called before Start.
+func (m *Manager) OnCARotated(fn func(ca *x509.Certificate)) {
+	m.onCARotated = fn
+}
+
 // Start starts the certificate manager and blocks until the context is cancelled.
 func (m *Manager) Start(ctx context.Context) error {
-	// Start informers
-	m.informers.Start(ctx.Done())
-
-	secretInformer := m.informers.InformersFor(m.config.Namespace).Core().V1().Secrets().Informer()
-	go secretInformer.Run(ctx.Done())
-
-	configMapInformer := m.informers.InformersFor(m.config.Namespace).Core().V1().ConfigMaps().Informer()
-	go configMapInformer.Run(ctx.Done())
-
-	if !toolscache.WaitForCacheSync(ctx.Done(), secretInformer.HasSynced, configMapInformer.HasSynced) {
-		return fmt.Errorf("could not sync informer cache")
+	if err := m.startInformers(ctx); err != nil {
+		return err
 	}
 
-	m.secretLister = m.informers.InformersFor(m.config.Namespace).Core().V1().Secrets().Lister()
-	m.configMapLister = m.informers.InformersFor(m.config.Namespace).Core().V1().ConfigMaps().Lister()
-
 	// Start the sync loop
 	syncInterval := m.config.SyncInterval
 	if syncInterval <= 0 {
 		syncInterval = time.Minute
 	}
-	ticker := time.NewTicker(syncInterval)
+	ticker := m.config.Clock.NewTicker(syncInterval)
 	defer ticker.Stop()
 
 	// Run immediately on start
@@ -121,7 +208,11 @@ func (m *Manager) Start(ctx context.Context) error {
 		case <-ctx.Done():
 			klog.Info("Certificate manager stopped")
 			return nil
-		case <-ticker.C:
+		case <-ticker.C():
+			if err := m.sync(ctx); err != nil {
+				klog.Errorf("Certificate sync failed: %v", err)
+			}
+		case <-m.syncCh:
 			if err := m.sync(ctx); err != nil {
 				klog.Errorf("Certificate sync failed: %v", err)
 			}
@@ -129,10 +220,69 @@ func (m *Manager) Start(ctx context.Context) error {
 	}
 }
 
+// startInformers starts the secret and configmap informers and waits for their caches to sync.
+func (m *Manager) startInformers(ctx context.Context) error {
+	m.informers.Start(ctx.Done())
+
+	secretInformer := m.informers.InformersFor(m.config.Namespace).Core().V1().Secrets().Informer()
+	if _, err := secretInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, newObj interface{}) {
+			m.onSecretUpdate(newObj)
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to add secret event handler: %w", err)
+	}
+	go secretInformer.Run(ctx.Done())
+
+	configMapInformer := m.informers.InformersFor(m.config.Namespace).Core().V1().ConfigMaps().Informer()
+	go configMapInformer.Run(ctx.Done())
+
+	if !toolscache.WaitForCacheSync(ctx.Done(), secretInformer.HasSynced, configMapInformer.HasSynced) {
+		return fmt.Errorf("could not sync informer cache")
+	}
+
+	if m.config.SignerName != "" {
+		if err := m.startCSRInformer(ctx); err != nil {
+			return err
+		}
+	}
+
+	m.secretLister = newLiveSecretLister(m.informers.InformersFor(m.config.Namespace).Core().V1().Secrets().Lister(), m.k8sClient)
+	m.configMapLister = newLiveConfigMapLister(m.informers.InformersFor(m.config.Namespace).Core().V1().ConfigMaps().Lister(), m.k8sClient)
+	return nil
+}
+
+// onSecretUpdate triggers a sync when a forced rotation is requested on the
+// CA or serving certificate secret.
+func (m *Manager) onSecretUpdate(obj interface{}) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok || (secret.Name != m.config.CASecretName && secret.Name != m.config.CertSecretName) {
+		return
+	}
+	if pendingForceRotate(secret) == "" {
+		return
+	}
+
+	select {
+	case m.syncCh <- struct{}{}:
+	default:
+	}
+}
+
 // sync performs a single synchronization cycle.
 func (m *Manager) sync(ctx context.Context) error {
 	klog.V(4).Info("Syncing certificates")
 
+	// Serving certificates issued through the CertificateSigningRequest API
+	// have no CA to manage.
+	if m.config.SignerName != "" {
+		if err := m.ensureServingCertFromCSR(ctx); err != nil {
+			return fmt.Errorf("failed to ensure serving certificate: %w", err)
+		}
+		klog.V(4).Info("Certificate sync completed")
+		return nil
+	}
+
 	// Ensure CA
 	ca, err := m.ensureCA(ctx)
 	if err != nil {
@@ -154,23 +304,30 @@ func (m *Manager) sync(ctx context.Context) error {
 	return nil
 }
 
-// ensureCA ensures the CA certificate exists and is valid.
+// ensureCA ensures the CA certificate exists and is valid. The CA is rotated
+// by library-go, which only generates RSA-2048 keys and knows nothing of
+// forced rotations or configuration changes: in those cases the manager
+// generates the CA itself, in the same secret format.
 func (m *Manager) ensureCA(ctx context.Context) (*crypto.CA, error) {
+	if m.config.ExternalCA {
+		return m.loadExternalCA()
+	}
+
 	secret, err := m.secretLister.Secrets(m.config.Namespace).Get(m.config.CASecretName)
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			return nil, err
 		}
+		secret = nil
+	}
 
-		secret, err = m.createSecret(ctx, m.config.Namespace, m.config.CASecretName)
-		if err != nil {
-			return nil, err
-		}
+	if reason := m.needNewCA(secret); reason != "" {
+		return m.rotateCA(ctx, secret, reason)
 	}
 
 	sr := certrotation.RotatedSigningCASecret{
-		Name:          secret.Name,
-		Namespace:     secret.Namespace,
+		Name:          m.config.CASecretName,
+		Namespace:     m.config.Namespace,
 		Validity:      m.config.CAValidity,
 		Refresh:       m.config.CARefresh,
 		Lister:        m.secretLister,
@@ -178,14 +335,152 @@ func (m *Manager) ensureCA(ctx context.Context) (*crypto.CA, error) {
 		EventRecorder: m.eventRecorder,
 	}
 
-	ca, _, err := sr.EnsureSigningCertKeyPair(ctx)
+	ca, rotated, err := sr.EnsureSigningCertKeyPair(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ca == nil {
+		// library-go skips updates of outdated secrets; retry with the current one.
+		return nil, fmt.Errorf("secret %s/%s was modified during the update", m.config.Namespace, m.config.CASecretName)
+	}
+	if rotated {
+		klog.Infof("Generated new %s CA certificate in secret %s/%s", m.config.CAKey, m.config.Namespace, m.config.CASecretName)
+		if m.onCARotated != nil {
+			m.onCARotated(ca.Config.Certs[0])
+		}
+	}
+	metrics.UpdateCertMetrics("ca", ca.Config.Certs[0])
+	return ca, nil
+}
+
+// rotateCA stores a new CA generated with the configured key in secret,
+// creating the secret when it is nil.
+func (m *Manager) rotateCA(ctx context.Context, secret *corev1.Secret, reason string) (*crypto.CA, error) {
+	m.eventRecorder.Eventf("SignerUpdateRequired", "%q in %q requires a new signing cert/key pair: %v", m.config.CASecretName, m.config.Namespace, reason)
+
+	now := m.config.Clock.Now()
+	commonName := fmt.Sprintf("%s_%s@%d", m.config.Namespace, m.config.CASecretName, now.Unix())
+	caConfig, err := newSelfSignedCA(commonName, m.config.CAKey, m.config.CAValidity, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA: %w", err)
+	}
+
+	if secret == nil {
+		secret, err = m.createSecret(ctx, m.config.Namespace, m.config.CASecretName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	secret = secret.DeepCopy()
+	certrotation.LabelAsManagedSecret(secret, certrotation.CertificateTypeSigner)
+
+	annotations := map[string]string{}
+	forced := pendingForceRotate(secret)
+	if forced != "" {
+		annotations[ForceRotateHandledAnnotation] = forced
+	}
+	secret, err = m.updateCertSecret(ctx, secret, caConfig, m.config.CARefresh, annotations)
+	if err != nil {
+		return nil, err
+	}
+	if forced != "" {
+		metrics.RecordForcedRotation("ca")
+	}
+	klog.Infof("Generated new %s CA certificate in secret %s/%s", m.config.CAKey, secret.Namespace, secret.Name)
+	if m.onCARotated != nil {
+		m.onCARotated(caConfig.Certs[0])
+	}
+
+	metrics.UpdateCertMetrics("ca", caConfig.Certs[0])
+	return &crypto.CA{Config: caConfig, SerialGenerator: &crypto.RandomSerialGenerator{}}, nil
+}
+
+// loadExternalCA returns the CA provided in the CA secret, emitting a warning
+// event when it is missing or invalid.
+func (m *Manager) loadExternalCA() (*crypto.CA, error) {
+	secret, err := m.secretLister.Secrets(m.config.Namespace).Get(m.config.CASecretName)
 	if err != nil {
+		if errors.IsNotFound(err) {
+			err = fmt.Errorf("secret %s/%s not found", m.config.Namespace, m.config.CASecretName)
+		}
+		m.eventRecorder.Warningf("ExternalCAInvalid", "External CA is not available: %v", err)
 		return nil, err
 	}
 
+	// An external CA is only replaced by its owner and its secret is never
+	// modified, so a forced rotation is reported once per annotation value
+	// instead of being silently ignored.
+	if forced := pendingForceRotate(secret); forced != "" && forced != m.externalCAForced {
+		m.eventRecorder.Warningf("ForceRotateIgnored", "External CA in %q in %q is not rotated by auto-cert-webhook, ignoring %s=%q",
+			secret.Name, secret.Namespace, ForceRotateAnnotation, forced)
+		m.externalCAForced = forced
+	}
+
+	now := m.config.Clock.Now()
+	ca, err := crypto.GetCAFromBytes(secret.Data["tls.crt"], secret.Data["tls.key"])
+	if err == nil {
+		err = certcheck.ValidateCA(ca.Config.Certs[0], now)
+	}
+	if err != nil {
+		m.eventRecorder.Warningf("ExternalCAInvalid", "External CA in %q in %q is invalid: %v", secret.Name, secret.Namespace, err)
+		return nil, fmt.Errorf("invalid CA in secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	caCert := ca.Config.Certs[0]
+	if caCert.NotAfter.Before(now.Add(m.config.CertValidity)) {
+		m.eventRecorder.Warningf("ExternalCAExpiring", "External CA in %q in %q expires at %v, serving certificates are limited to its validity",
+			secret.Name, secret.Namespace, caCert.NotAfter)
+	}
+	metrics.UpdateCertMetrics("ca", caCert)
+
+	if m.externalCA != nil && !m.externalCA.Equal(caCert) {
+		klog.Infof("External CA in secret %s/%s was replaced", secret.Namespace, secret.Name)
+		if m.onCARotated != nil {
+			m.onCARotated(caCert)
+		}
+	}
+	m.externalCA = caCert
 	return ca, nil
 }
 
+// needNewCA returns the reason the manager must generate the CA in secret
+// instead of library-go, or "" if library-go can maintain it. A nil secret
+// does not exist yet.
+func (m *Manager) needNewCA(secret *corev1.Secret) string {
+	// library-go generates and rotates CAs with its own key only.
+	libraryGoKey := m.config.CAKey.WithDefaults() == libraryGoKeySpec
+	if secret == nil {
+		if libraryGoKey {
+			return ""
+		}
+		return "secret doesn't exist"
+	}
+
+	if forced := pendingForceRotate(secret); forced != "" {
+		return fmt.Sprintf("rotation forced by annotation %s=%q", ForceRotateAnnotation, forced)
+	}
+
+	ca, err := crypto.GetCAFromBytes(secret.Data["tls.crt"], secret.Data["tls.key"])
+	if err != nil {
+		if libraryGoKey {
+			return ""
+		}
+		return fmt.Sprintf("invalid signing cert/key pair: %v", err)
+	}
+	caCert := ca.Config.Certs[0]
+	if !libraryGoKey {
+		if reason := needRefresh(caCert, m.config.CARefresh, m.config.Clock.Now()); reason != "" {
+			return reason
+		}
+	}
+
+	// Changes to the configuration take effect immediately.
+	if reason := keyMismatch(caCert, m.config.CAKey); reason != "" {
+		return reason
+	}
+	return validityMismatch(caCert, m.config.CAValidity)
+}
+
 // ensureCABundle ensures the CA bundle configmap exists and contains the current CA.
 func (m *Manager) ensureCABundle(ctx context.Context, ca *crypto.CA) ([]*x509.Certificate, error) {
 	br := certrotation.CABundleConfigMap{
@@ -202,47 +497,347 @@ func (m *Manager) ensureCABundle(ctx context.Context, ca *crypto.CA) ([]*x509.Ce
 		return nil, err
 	}
 
+	if m.config.ExternalCA && m.config.CABundleFullChain {
+		return m.ensureCABundleChain(ctx, certs, m.externalCAChain(ca))
+	}
 	return certs, nil
 }
 
-// ensureServingCert ensures the serving certificate exists and is valid.
+// externalCAChain returns the certificates above the signing certificate of
+// the external CA: the rest of its tls.crt and the root certificates in ca.crt.
+func (m *Manager) externalCAChain(ca *crypto.CA) []*x509.Certificate {
+	chain := append([]*x509.Certificate(nil), ca.Config.Certs[1:]...)
+
+	secret, err := m.secretLister.Secrets(m.config.Namespace).Get(m.config.CASecretName)
+	if err != nil || len(secret.Data[cabundle.SecretKey]) == 0 {
+		return chain
+	}
+	roots, err := cert.ParseCertsPEM(secret.Data[cabundle.SecretKey])
+	if err != nil {
+		m.eventRecorder.Warningf("ExternalCAInvalid", "Invalid %s in %q in %q: %v", cabundle.SecretKey, secret.Name, secret.Namespace, err)
+		return chain
+	}
+	return append(chain, roots...)
+}
+
+// ensureCABundleChain adds the unexpired certificates of chain missing from
+// bundle to the CA bundle configmap and returns the resulting bundle.
+func (m *Manager) ensureCABundleChain(ctx context.Context, bundle, chain []*x509.Certificate) ([]*x509.Certificate, error) {
+	var missing []*x509.Certificate
+	now := m.config.Clock.Now()
+	for _, chainCert := range chain {
+		if now.After(chainCert.NotAfter) || containsCert(bundle, chainCert) || containsCert(missing, chainCert) {
+			continue
+		}
+		missing = append(missing, chainCert)
+	}
+	if len(missing) == 0 {
+		return bundle, nil
+	}
+
+	cm, err := m.k8sClient.CoreV1().ConfigMaps(m.config.Namespace).Get(ctx, m.config.CABundleConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	current, err := cert.ParseCertsPEM([]byte(cm.Data[cabundle.ConfigMapKey]))
+	if err != nil {
+		return nil, err
+	}
+	for _, chainCert := range missing {
+		if !containsCert(current, chainCert) {
+			current = append(current, chainCert)
+		}
+	}
+	caBundle, err := crypto.EncodeCertificates(current...)
+	if err != nil {
+		return nil, err
+	}
+
+	cm = cm.DeepCopy()
+	cm.Data[cabundle.ConfigMapKey] = string(caBundle)
+	if _, err := m.k8sClient.CoreV1().ConfigMaps(cm.Namespace).Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to add the CA chain to configmap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+	klog.Infof("Added %d CA chain certificates to configmap %s/%s", len(missing), cm.Namespace, cm.Name)
+	return current, nil
+}
+
+// containsCert reports whether certs contains c.
+func containsCert(certs []*x509.Certificate, c *x509.Certificate) bool {
+	for _, existing := range certs {
+		if existing.Equal(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureServingCert ensures the serving certificate exists and is valid. The
+// certificate is rotated by library-go and issued by servingRotation with the
+// configured key and subject.
 func (m *Manager) ensureServingCert(ctx context.Context, ca *crypto.CA, bundle []*x509.Certificate) error {
+	hostnames := m.hostnames()
+	creator := &servingRotation{
+		ServingRotation: certrotation.ServingRotation{
+			Hostnames: func() []string { return hostnames },
+		},
+		manager: m,
+		subject: m.subject(),
+	}
+
+	var (
+		secret *corev1.Secret
+		err    error
+	)
+	if m.config.CertKey.encodableByLibraryGo() {
+		tr := certrotation.RotatedSelfSignedCertKeySecret{
+			Name:          m.config.CertSecretName,
+			Namespace:     m.config.Namespace,
+			Validity:      m.config.CertValidity,
+			Refresh:       m.config.CertRefresh,
+			CertCreator:   creator,
+			Lister:        m.secretLister,
+			Client:        m.k8sClient.CoreV1(),
+			EventRecorder: m.eventRecorder,
+		}
+		secret, err = tr.EnsureTargetCertKeyPair(ctx, ca, bundle)
+	} else {
+		secret, err = m.rotateServingCert(ctx, creator, ca, bundle)
+	}
+	if err != nil {
+		return err
+	}
+
+	if secret != nil && creator.issued {
+		if creator.forced != "" {
+			metrics.RecordForcedRotation("serving")
+		}
+		klog.Infof("Generated new %s serving certificate in secret %s/%s", m.config.CertKey, secret.Namespace, secret.Name)
+	}
+	return nil
+}
+
+// rotateServingCert maintains the serving certificate for keys library-go
+// cannot store, with the same rotation decisions and secret format.
+func (m *Manager) rotateServingCert(ctx context.Context, creator *servingRotation, ca *crypto.CA, bundle []*x509.Certificate) (*corev1.Secret, error) {
+	creationRequired := false
 	secret, err := m.secretLister.Secrets(m.config.Namespace).Get(m.config.CertSecretName)
 	if err != nil {
 		if !errors.IsNotFound(err) {
-			return err
+			return nil, err
 		}
+		secret = &corev1.Secret{}
+		creationRequired = true
+	}
 
+	reason := creator.NeedNewTargetCertKeyPair(secret, ca, bundle, m.config.CertRefresh, false, creationRequired)
+	if reason == "" {
+		return secret, nil
+	}
+	m.eventRecorder.Eventf("TargetUpdateRequired", "%q in %q requires a new target cert/key pair: %v", m.config.CertSecretName, m.config.Namespace, reason)
+
+	cert, err := creator.NewCertificate(ca, m.config.CertValidity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serving certificate: %w", err)
+	}
+
+	if creationRequired {
 		secret, err = m.createSecret(ctx, m.config.Namespace, m.config.CertSecretName)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
+	secret = secret.DeepCopy()
+	certrotation.LabelAsManagedSecret(secret, certrotation.CertificateTypeTarget)
 
-	tr := certrotation.RotatedSelfSignedCertKeySecret{
-		Name:      secret.Name,
-		Namespace: secret.Namespace,
-		Validity:  m.config.CertValidity,
-		Refresh:   m.config.CertRefresh,
-		CertCreator: &certrotation.ServingRotation{
-			Hostnames: func() []string {
-				return []string{
-					m.config.ServiceName,
-					fmt.Sprintf("%s.%s", m.config.ServiceName, m.config.Namespace),
-					fmt.Sprintf("%s.%s.svc", m.config.ServiceName, m.config.Namespace),
-				}
-			},
-		},
-		Lister:        m.secretLister,
-		Client:        m.k8sClient.CoreV1(),
-		EventRecorder: m.eventRecorder,
+	return m.updateCertSecret(ctx, secret, cert, m.config.CertRefresh, creator.SetAnnotations(cert, map[string]string{}))
+}
+
+// needNewServingCert returns the reason the serving certificate in secret
+// must be reissued although library-go would keep it, or "" if it matches
+// the configuration.
+func (m *Manager) needNewServingCert(secret *corev1.Secret, ca *crypto.CA, subject pkix.Name, hostnames []string) string {
+	cert, err := crypto.GetTLSCertificateConfigFromBytes(secret.Data["tls.crt"], secret.Data["tls.key"])
+	if err != nil {
+		return fmt.Sprintf("invalid target cert/key pair: %v", err)
+	}
+	leaf := cert.Certs[0]
+
+	// Changes to the configuration take effect immediately. A certificate
+	// signed by a previous CA is replaced once clients had time to trust the
+	// current one.
+	caCert := ca.Config.Certs[0]
+	if leaf.CheckSignatureFrom(caCert) != nil && m.config.Clock.Now().After(caCert.NotBefore.Add(m.config.CertRefresh/10)) {
+		return fmt.Sprintf("issuer %q is not the current signer %q", leaf.Issuer.CommonName, caCert.Subject.CommonName)
+	}
+	if reason := keyMismatch(leaf, m.config.CertKey); reason != "" {
+		return reason
+	}
+	// The validity of certificates capped at the expiry of the CA cannot match.
+	if leaf.NotAfter.Before(caCert.NotAfter.Add(-time.Second)) {
+		if reason := validityMismatch(leaf, m.config.CertValidity); reason != "" {
+			return reason
+		}
 	}
 
-	if _, err := tr.EnsureTargetCertKeyPair(ctx, ca, bundle); err != nil {
-		return err
+	return namesMismatch(leaf, subject, hostnames)
+}
+
+// namesMismatch returns the reason the subject or subject alternative names
+// of leaf do not match subject and hostnames, or "" if they do.
+func namesMismatch(leaf *x509.Certificate, subject pkix.Name, hostnames []string) string {
+	existing := sets.New(leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		existing.Insert(ip.String())
+	}
+	if required := sets.New(hostnames...); !existing.Equal(required) {
+		return fmt.Sprintf("hostnames %q do not match required %q",
+			strings.Join(sets.List(existing), ","), strings.Join(sets.List(required), ","))
 	}
 
-	return nil
+	if leaf.Subject.CommonName != subject.CommonName {
+		return fmt.Sprintf("common name %q does not match required %q", leaf.Subject.CommonName, subject.CommonName)
+	}
+	if existing, required := sets.New(leaf.Subject.Organization...), sets.New(subject.Organization...); !existing.Equal(required) {
+		return fmt.Sprintf("organizations %q do not match required %q",
+			strings.Join(sets.List(existing), ","), strings.Join(sets.List(required), ","))
+	}
+
+	return ""
+}
+
+// hostnames returns the subject alternative names of the serving
+// certificate: the DNS names of the webhook service, the extra DNS names
+// and the IP addresses, without duplicates.
+func (m *Manager) hostnames() []string {
+	hostnames := []string{
+		m.config.ServiceName,
+		fmt.Sprintf("%s.%s", m.config.ServiceName, m.config.Namespace),
+		fmt.Sprintf("%s.%s.svc", m.config.ServiceName, m.config.Namespace),
+	}
+	if m.config.ClusterDomain != "" {
+		hostnames = append(hostnames, fmt.Sprintf("%s.%s.svc.%s", m.config.ServiceName, m.config.Namespace, m.config.ClusterDomain))
+	}
+	hostnames = append(hostnames, m.config.ExtraDNSNames...)
+	hostnames = append(hostnames, m.config.IPAddresses...)
+
+	seen := sets.New[string]()
+	unique := hostnames[:0]
+	for _, hostname := range hostnames {
+		if !seen.Has(hostname) {
+			seen.Insert(hostname)
+			unique = append(unique, hostname)
+		}
+	}
+	return unique
+}
+
+// subject returns the subject of the serving certificate.
+func (m *Manager) subject() pkix.Name {
+	commonName := m.config.CommonName
+	if commonName == "" {
+		commonName = m.config.ServiceName
+	}
+	return pkix.Name{
+		CommonName:   commonName,
+		Organization: m.config.Organizations,
+	}
+}
+
+// updateCertSecret stores cert in secret along with the certificate
+// annotations and the given extra annotations.
+func (m *Manager) updateCertSecret(ctx context.Context, secret *corev1.Secret, cert *crypto.TLSCertificateConfig, refresh time.Duration, annotations map[string]string) (*corev1.Secret, error) {
+	certPEM, keyPEM, err := encodeCertKeyPair(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := secret.DeepCopy()
+	updated.Type = corev1.SecretTypeTLS
+	if updated.Data == nil {
+		updated.Data = map[string][]byte{}
+	}
+	updated.Data["tls.crt"] = certPEM
+	updated.Data["tls.key"] = keyPEM
+
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	leaf := cert.Certs[0]
+	updated.Annotations[certrotation.CertificateIssuer] = leaf.Issuer.CommonName
+	updated.Annotations[certrotation.CertificateNotBeforeAnnotation] = leaf.NotBefore.Format(time.RFC3339)
+	updated.Annotations[certrotation.CertificateNotAfterAnnotation] = leaf.NotAfter.Format(time.RFC3339)
+	updated.Annotations[certrotation.CertificateRefreshPeriodAnnotation] = refresh.String()
+	for key, value := range annotations {
+		updated.Annotations[key] = value
+	}
+
+	return m.updateSecret(ctx, updated)
+}
+
+// updateSecret writes secret to the API server.
+func (m *Manager) updateSecret(ctx context.Context, secret *corev1.Secret) (*corev1.Secret, error) {
+	result, err := m.k8sClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+	return result, nil
+}
+
+// needRefresh returns the reason cert must be refreshed, or "" if it is
+// still valid: it expired, is past 80% of its validity, or is older than
+// refresh when refresh is positive.
+func needRefresh(cert *x509.Certificate, refresh time.Duration, now time.Time) string {
+	if now.After(cert.NotAfter) {
+		return "already expired"
+	}
+
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	at80Percent := cert.NotAfter.Add(-validity / 5)
+	if now.After(at80Percent) {
+		return fmt.Sprintf("past refresh time (80%% of validity): %v", at80Percent)
+	}
+
+	if refresh > 0 {
+		if refreshTime := cert.NotBefore.Add(refresh); now.After(refreshTime) {
+			return fmt.Sprintf("past its refresh time %v", refreshTime)
+		}
+	}
+	return ""
+}
+
+// pendingForceRotate returns the value of the force-rotate annotation of
+// secret if no rotation was performed for it yet, or "".
+func pendingForceRotate(secret *corev1.Secret) string {
+	value := secret.Annotations[ForceRotateAnnotation]
+	if value == "" || value == secret.Annotations[ForceRotateHandledAnnotation] {
+		return ""
+	}
+	return value
+}
+
+// keyMismatch returns the reason the key of cert does not match spec, or "" if it does.
+func keyMismatch(cert *x509.Certificate, spec KeySpec) string {
+	spec = spec.WithDefaults()
+	current, ok := keySpecOf(cert.PublicKey)
+	if !ok {
+		return fmt.Sprintf("unsupported key type %T", cert.PublicKey)
+	}
+	if current != spec {
+		return fmt.Sprintf("key %s does not match required %s", current, spec)
+	}
+	return ""
+}
+
+// validityMismatch returns the reason the validity cert was issued with does
+// not match validity, or "" if it does. Certificate times have a precision
+// of one second.
+func validityMismatch(cert *x509.Certificate, validity time.Duration) string {
+	issued := cert.NotAfter.Sub(cert.NotBefore) - backdate
+	if diff := issued - validity; diff < -time.Second || diff > time.Second {
+		return fmt.Sprintf("validity %v does not match required %v", issued, validity)
+	}
+	return ""
 }
 
 // createSecret creates a new TLS secret.
@@ -0,0 +1,847 @@
+package certmanager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/certrotation"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/cert"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func newTestConfig() Config {
+	return Config{
+		Namespace:             "test-ns",
+		ServiceName:           "test-svc",
+		CASecretName:          "test-ca",
+		CertSecretName:        "test-cert",
+		CABundleConfigMapName: "test-ca-bundle",
+		CAValidity:            48 * time.Hour,
+		CARefresh:             24 * time.Hour,
+		CertValidity:          24 * time.Hour,
+		CertRefresh:           12 * time.Hour,
+	}
+}
+
+// newTestManager returns a manager with synced informers.
+func newTestManager(t *testing.T, client *fake.Clientset, config Config) *Manager {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	m := New(client, config)
+	if err := m.startInformers(ctx); err != nil {
+		t.Fatalf("startInformers failed: %v", err)
+	}
+	return m
+}
+
+func TestManager_sync_KeyAlgorithms(t *testing.T) {
+	config := newTestConfig()
+	config.CAKey = KeySpec{Algorithm: KeyAlgorithmECDSA, Size: 384}
+	config.CertKey = KeySpec{Algorithm: KeyAlgorithmEd25519}
+
+	client := fake.NewClientset()
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	caSecret, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get CA secret: %v", err)
+	}
+	caCerts, err := cert.ParseCertsPEM(caSecret.Data["tls.crt"])
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+	if key, ok := caCerts[0].PublicKey.(*ecdsa.PublicKey); !ok || key.Curve.Params().BitSize != 384 {
+		t.Errorf("Expected P-384 ECDSA CA key, got %T", caCerts[0].PublicKey)
+	}
+	if !caCerts[0].IsCA {
+		t.Error("Expected CA certificate")
+	}
+
+	servingSecret, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get serving secret: %v", err)
+	}
+	servingCert, err := tls.X509KeyPair(servingSecret.Data["tls.crt"], servingSecret.Data["tls.key"])
+	if err != nil {
+		t.Fatalf("Invalid serving key pair: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(servingCert.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse serving certificate: %v", err)
+	}
+	if _, ok := leaf.PublicKey.(ed25519.PublicKey); !ok {
+		t.Errorf("Expected Ed25519 serving key, got %T", leaf.PublicKey)
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("test-ns").Get(ctx, "test-ca-bundle", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get CA bundle configmap: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(cm.Data["ca-bundle.crt"])) {
+		t.Fatal("CA bundle has no certificates")
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:   "test-svc.test-ns.svc",
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		t.Errorf("Serving certificate does not verify against the CA bundle: %v", err)
+	}
+}
+
+func TestNeedRefresh(t *testing.T) {
+	now := time.Now()
+	cert := &x509.Certificate{
+		NotBefore: now,
+		NotAfter:  now.Add(10 * time.Hour),
+	}
+
+	tests := []struct {
+		name    string
+		refresh time.Duration
+		at      time.Time
+		want    bool
+	}{
+		{"fresh", 5 * time.Hour, now.Add(time.Hour), false},
+		{"past refresh", 5 * time.Hour, now.Add(6 * time.Hour), true},
+		{"no refresh", 0, now.Add(6 * time.Hour), false},
+		{"past 80 percent", 0, now.Add(9 * time.Hour), true},
+		{"expired", 0, now.Add(11 * time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := needRefresh(cert, tt.refresh, tt.at)
+			if (reason != "") != tt.want {
+				t.Errorf("needRefresh() = %q, want refresh %v", reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_needNewServingCert(t *testing.T) {
+	client := fake.NewClientset()
+	m := newTestManager(t, client, newTestConfig())
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	caSecret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+	servingSecret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+	ca, err := crypto.GetCAFromBytes(caSecret.Data["tls.crt"], caSecret.Data["tls.key"])
+	if err != nil {
+		t.Fatalf("Invalid CA: %v", err)
+	}
+
+	if reason := m.needNewServingCert(servingSecret, ca, m.subject(), m.hostnames()); reason != "" {
+		t.Errorf("Expected fresh certificate to be kept, got %q", reason)
+	}
+	if reason := m.needNewServingCert(servingSecret, ca, m.subject(), []string{"other-svc"}); reason == "" {
+		t.Error("Expected hostname change to require a new certificate")
+	}
+	if reason := m.needNewServingCert(servingSecret, ca, pkix.Name{CommonName: "other-svc"}, m.hostnames()); reason == "" {
+		t.Error("Expected subject change to require a new certificate")
+	}
+}
+
+func TestServingRotation_NeedNewTargetCertKeyPair(t *testing.T) {
+	client := fake.NewClientset()
+	m := newTestManager(t, client, newTestConfig())
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	caSecret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+	servingSecret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+	ca, err := crypto.GetCAFromBytes(caSecret.Data["tls.crt"], caSecret.Data["tls.key"])
+	if err != nil {
+		t.Fatalf("Invalid CA: %v", err)
+	}
+	hostnames := m.hostnames()
+	r := &servingRotation{
+		ServingRotation: certrotation.ServingRotation{Hostnames: func() []string { return hostnames }},
+		manager:         m,
+		subject:         m.subject(),
+	}
+	config := m.config
+
+	if reason := r.NeedNewTargetCertKeyPair(servingSecret, ca, ca.Config.Certs, config.CertRefresh, false, false); reason != "" {
+		t.Errorf("Expected fresh certificate to be kept, got %q", reason)
+	}
+	if reason := r.NeedNewTargetCertKeyPair(servingSecret, ca, nil, config.CertRefresh, false, false); reason == "" {
+		t.Error("Expected untrusted issuer to require a new certificate")
+	}
+	if reason := r.NeedNewTargetCertKeyPair(servingSecret, ca, ca.Config.Certs, time.Nanosecond, false, false); reason == "" {
+		t.Error("Expected certificate past its refresh time to require a new certificate")
+	}
+
+	forced := servingSecret.DeepCopy()
+	forced.Annotations[ForceRotateAnnotation] = "1"
+	if reason := r.NeedNewTargetCertKeyPair(forced, ca, ca.Config.Certs, config.CertRefresh, false, false); reason == "" {
+		t.Error("Expected forced rotation to require a new certificate")
+	}
+	if got := r.SetAnnotations(&crypto.TLSCertificateConfig{Certs: ca.Config.Certs}, map[string]string{})[ForceRotateHandledAnnotation]; got != "1" {
+		t.Errorf("%s: got %q, want %q", ForceRotateHandledAnnotation, got, "1")
+	}
+}
+
+func TestManager_needNewServingCert_ConfigChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(config *Config)
+	}{
+		{"key algorithm", func(config *Config) { config.CertKey = KeySpec{Algorithm: KeyAlgorithmECDSA} }},
+		{"key size", func(config *Config) { config.CertKey = KeySpec{Size: 4096} }},
+		{"validity", func(config *Config) { config.CertValidity = 36 * time.Hour }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientset()
+			m := newTestManager(t, client, newTestConfig())
+
+			ctx := context.Background()
+			if err := m.sync(ctx); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+			caSecret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+			servingSecret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+			ca, err := crypto.GetCAFromBytes(caSecret.Data["tls.crt"], caSecret.Data["tls.key"])
+			if err != nil {
+				t.Fatalf("Invalid CA: %v", err)
+			}
+
+			tt.change(&m.config)
+			if reason := m.needNewServingCert(servingSecret, ca, m.subject(), m.hostnames()); reason == "" {
+				t.Error("Expected configuration change to require a new certificate")
+			}
+		})
+	}
+}
+
+func TestManager_needNewServingCert_PreviousSigner(t *testing.T) {
+	clk := testingclock.NewFakeClock(time.Now())
+	config := newTestConfig()
+	config.Clock = clk
+
+	client := fake.NewClientset()
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	servingSecret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+
+	current, err := newSelfSignedCA("current", config.CAKey, config.CAValidity, clk.Now())
+	if err != nil {
+		t.Fatalf("newSelfSignedCA failed: %v", err)
+	}
+	ca := &crypto.CA{Config: current, SerialGenerator: &crypto.RandomSerialGenerator{}}
+
+	if reason := m.needNewServingCert(servingSecret, ca, m.subject(), m.hostnames()); reason != "" {
+		t.Errorf("Expected certificate to be kept until clients trust the new CA, got %q", reason)
+	}
+
+	clk.Step(config.CertRefresh/10 + time.Minute)
+	if reason := m.needNewServingCert(servingSecret, ca, m.subject(), m.hostnames()); reason == "" {
+		t.Error("Expected certificate signed by the previous CA to require a new certificate")
+	}
+}
+
+func TestManager_needNewCA(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(config *Config)
+		want   bool
+	}{
+		{"unchanged", func(config *Config) {}, false},
+		{"key algorithm", func(config *Config) { config.CAKey = KeySpec{Algorithm: KeyAlgorithmEd25519} }, true},
+		{"validity", func(config *Config) { config.CAValidity = 72 * time.Hour }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewClientset()
+			m := newTestManager(t, client, newTestConfig())
+
+			ctx := context.Background()
+			if err := m.sync(ctx); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+			caSecret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+
+			tt.change(&m.config)
+			if got := m.needNewCA(caSecret) != ""; got != tt.want {
+				t.Errorf("needNewCA: got %v, want %v (%q)", got, tt.want, m.needNewCA(caSecret))
+			}
+		})
+	}
+}
+
+func TestManager_hostnames(t *testing.T) {
+	config := newTestConfig()
+	config.ClusterDomain = "example.org"
+	config.ExtraDNSNames = []string{"webhook.example.com", "test-svc.test-ns.svc"}
+	config.IPAddresses = []string{"10.0.0.1"}
+	m := New(fake.NewClientset(), config)
+
+	want := []string{
+		"test-svc",
+		"test-svc.test-ns",
+		"test-svc.test-ns.svc",
+		"test-svc.test-ns.svc.example.org",
+		"webhook.example.com",
+		"10.0.0.1",
+	}
+	if got := m.hostnames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("hostnames: got %q, want %q", got, want)
+	}
+}
+
+func TestManager_sync_SANChange(t *testing.T) {
+	config := newTestConfig()
+	config.ClusterDomain = "cluster.local"
+	config.IPAddresses = []string{"10.0.0.1"}
+	config.Organizations = []string{"example"}
+
+	client := fake.NewClientset()
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	leaf := servingLeaf(t, client)
+
+	if want := []string{"test-svc", "test-svc.test-ns", "test-svc.test-ns.svc", "test-svc.test-ns.svc.cluster.local"}; !reflect.DeepEqual(leaf.DNSNames, want) {
+		t.Errorf("DNSNames: got %q, want %q", leaf.DNSNames, want)
+	}
+	if len(leaf.IPAddresses) != 1 || !leaf.IPAddresses[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("IPAddresses: got %v, want [10.0.0.1]", leaf.IPAddresses)
+	}
+	if leaf.Subject.CommonName != "test-svc" {
+		t.Errorf("CommonName: got %q, want %q", leaf.Subject.CommonName, "test-svc")
+	}
+	if !reflect.DeepEqual(leaf.Subject.Organization, []string{"example"}) {
+		t.Errorf("Organization: got %q, want %q", leaf.Subject.Organization, []string{"example"})
+	}
+
+	// Unchanged configuration keeps the certificate
+	waitForInformerCache(t, m, client)
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if servingLeaf(t, client).SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+		t.Error("Expected certificate to be kept when the SANs are unchanged")
+	}
+
+	// An added DNS name reissues the certificate
+	m.config.ExtraDNSNames = []string{"webhook.example.com"}
+	waitForInformerCache(t, m, client)
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	reissued := servingLeaf(t, client)
+	if reissued.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+		t.Fatal("Expected certificate to be reissued after the SANs changed")
+	}
+	if err := reissued.VerifyHostname("webhook.example.com"); err != nil {
+		t.Errorf("Reissued certificate does not cover the extra DNS name: %v", err)
+	}
+}
+
+// servingLeaf returns the serving certificate stored in the cert secret.
+func servingLeaf(t *testing.T, client *fake.Clientset) *x509.Certificate {
+	t.Helper()
+
+	secret, err := client.CoreV1().Secrets("test-ns").Get(context.Background(), "test-cert", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get cert secret: %v", err)
+	}
+	certs, err := cert.ParseCertsPEM(secret.Data["tls.crt"])
+	if err != nil {
+		t.Fatalf("Invalid serving certificate: %v", err)
+	}
+	return certs[0]
+}
+
+// waitForInformerCache waits until the informer caches hold the current
+// content of the certificate secrets and the CA bundle configmap.
+func waitForInformerCache(t *testing.T, m *Manager, client *fake.Clientset) {
+	t.Helper()
+
+	ctx := context.Background()
+	synced := func() bool {
+		for _, name := range []string{"test-ca", "test-cert"} {
+			secret, err := client.CoreV1().Secrets("test-ns").Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false
+			}
+			cached, err := m.informers.InformersFor("test-ns").Core().V1().Secrets().Lister().Secrets("test-ns").Get(name)
+			if err != nil || !reflect.DeepEqual(cached.Data, secret.Data) || !reflect.DeepEqual(cached.Annotations, secret.Annotations) {
+				return false
+			}
+		}
+		cm, err := client.CoreV1().ConfigMaps("test-ns").Get(ctx, "test-ca-bundle", metav1.GetOptions{})
+		if err != nil {
+			return false
+		}
+		cached, err := m.informers.InformersFor("test-ns").Core().V1().ConfigMaps().Lister().ConfigMaps("test-ns").Get("test-ca-bundle")
+		return err == nil && reflect.DeepEqual(cached.Data, cm.Data)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if synced() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for the informer cache")
+}
+
+func TestManager_sync_ForceRotate(t *testing.T) {
+	client := fake.NewClientset()
+	m := newTestManager(t, client, newTestConfig())
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	leaf := servingLeaf(t, client)
+
+	secret, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get cert secret: %v", err)
+	}
+	secret.Annotations[ForceRotateAnnotation] = "2026-01-01T00:00:00Z"
+	if _, err := client.CoreV1().Secrets("test-ns").Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to annotate cert secret: %v", err)
+	}
+	waitForInformerCache(t, m, client)
+
+	select {
+	case <-m.syncCh:
+	default:
+		t.Error("Expected the annotation to trigger a sync")
+	}
+
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	rotated := servingLeaf(t, client)
+	if rotated.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+		t.Fatal("Expected certificate to be reissued after the forced rotation")
+	}
+	secret, _ = client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+	if got := secret.Annotations[ForceRotateHandledAnnotation]; got != "2026-01-01T00:00:00Z" {
+		t.Errorf("%s: got %q, want %q", ForceRotateHandledAnnotation, got, "2026-01-01T00:00:00Z")
+	}
+
+	// The handled value does not rotate again
+	waitForInformerCache(t, m, client)
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if servingLeaf(t, client).SerialNumber.Cmp(rotated.SerialNumber) != 0 {
+		t.Error("Expected certificate to be kept once the forced rotation was handled")
+	}
+}
+
+func TestPendingForceRotate(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{"none", nil, ""},
+		{"requested", map[string]string{ForceRotateAnnotation: "1"}, "1"},
+		{"handled", map[string]string{ForceRotateAnnotation: "1", ForceRotateHandledAnnotation: "1"}, ""},
+		{"requested again", map[string]string{ForceRotateAnnotation: "2", ForceRotateHandledAnnotation: "1"}, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			if got := pendingForceRotate(secret); got != tt.want {
+				t.Errorf("pendingForceRotate: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_sync_ExternalCA(t *testing.T) {
+	now := time.Now()
+	root, err := newSelfSignedCA("root", KeySpec{}, 10*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("newSelfSignedCA failed: %v", err)
+	}
+	intermediate := newTestIntermediateCA(t, root, now.Add(5*24*time.Hour))
+	caSecret := newTestExternalCASecret(t, intermediate, root)
+
+	config := newTestConfig()
+	config.ExternalCA = true
+	config.CABundleFullChain = true
+
+	client := fake.NewClientset(caSecret)
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	current, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get CA secret: %v", err)
+	}
+	if !reflect.DeepEqual(current.Data, caSecret.Data) || current.ResourceVersion != caSecret.ResourceVersion {
+		t.Error("Expected the external CA secret to be left untouched")
+	}
+
+	secret, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get cert secret: %v", err)
+	}
+	chain, err := cert.ParseCertsPEM(secret.Data["tls.crt"])
+	if err != nil {
+		t.Fatalf("Invalid serving certificate: %v", err)
+	}
+	if len(chain) != 2 || !chain[1].Equal(intermediate.Certs[0]) {
+		t.Fatalf("Expected serving certificate followed by the intermediate CA, got %d certificates", len(chain))
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("test-ns").Get(ctx, "test-ca-bundle", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get CA bundle configmap: %v", err)
+	}
+	bundle, err := cert.ParseCertsPEM([]byte(cm.Data["ca-bundle.crt"]))
+	if err != nil {
+		t.Fatalf("Invalid CA bundle: %v", err)
+	}
+	if !containsCert(bundle, intermediate.Certs[0]) || !containsCert(bundle, root.Certs[0]) {
+		t.Errorf("Expected CA bundle with the full chain, got %d certificates", len(bundle))
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.Certs[0])
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(chain[1])
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       "test-svc.test-ns.svc",
+		Roots:         roots,
+		Intermediates: intermediates,
+	}); err != nil {
+		t.Errorf("Serving certificate does not chain to the root CA: %v", err)
+	}
+}
+
+func TestManager_OnCARotated(t *testing.T) {
+	t.Run("generated", func(t *testing.T) {
+		client := fake.NewClientset()
+		m := newTestManager(t, client, newTestConfig())
+		var rotated []*x509.Certificate
+		m.OnCARotated(func(ca *x509.Certificate) { rotated = append(rotated, ca) })
+
+		ctx := context.Background()
+		if err := m.sync(ctx); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		if len(rotated) != 1 {
+			t.Fatalf("rotations: got %d, want 1", len(rotated))
+		}
+		secret, _ := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+		ca, err := crypto.GetCAFromBytes(secret.Data["tls.crt"], secret.Data["tls.key"])
+		if err != nil {
+			t.Fatalf("Invalid CA: %v", err)
+		}
+		if !rotated[0].Equal(ca.Config.Certs[0]) {
+			t.Error("Expected the generated CA to be reported")
+		}
+
+		waitForInformerCache(t, m, client)
+		if err := m.sync(ctx); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		if len(rotated) != 1 {
+			t.Errorf("rotations after unchanged sync: got %d, want 1", len(rotated))
+		}
+	})
+
+	t.Run("external", func(t *testing.T) {
+		now := time.Now()
+		root, err := newSelfSignedCA("root", KeySpec{}, 10*24*time.Hour, now)
+		if err != nil {
+			t.Fatalf("newSelfSignedCA failed: %v", err)
+		}
+		config := newTestConfig()
+		config.ExternalCA = true
+
+		client := fake.NewClientset(newTestExternalCASecret(t, newTestIntermediateCA(t, root, now.Add(5*24*time.Hour)), root))
+		m := newTestManager(t, client, config)
+		var rotated []*x509.Certificate
+		m.OnCARotated(func(ca *x509.Certificate) { rotated = append(rotated, ca) })
+
+		ctx := context.Background()
+		if err := m.sync(ctx); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		if len(rotated) != 0 {
+			t.Fatalf("rotations after loading the external CA: got %d, want 0", len(rotated))
+		}
+
+		replacement := newTestIntermediateCA(t, root, now.Add(6*24*time.Hour))
+		if _, err := client.CoreV1().Secrets("test-ns").Update(ctx, newTestExternalCASecret(t, replacement, root), metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("Failed to replace external CA: %v", err)
+		}
+		waitForInformerCache(t, m, client)
+		if err := m.sync(ctx); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		if len(rotated) != 1 || !rotated[0].Equal(replacement.Certs[0]) {
+			t.Errorf("Expected the replaced external CA to be reported, got %d rotations", len(rotated))
+		}
+	})
+}
+
+func TestManager_sync_ExternalCAInvalid(t *testing.T) {
+	now := time.Now()
+	root, err := newSelfSignedCA("root", KeySpec{}, 10*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("newSelfSignedCA failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+	}{
+		{"missing", nil},
+		{"expired", newTestExternalCASecret(t, newTestIntermediateCA(t, root, now.Add(-time.Hour)), root)},
+		{"not a CA", newTestExternalCASecret(t, mustServingCert(t, root), root)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig()
+			config.ExternalCA = true
+
+			client := fake.NewClientset()
+			if tt.secret != nil {
+				client = fake.NewClientset(tt.secret)
+			}
+			m := newTestManager(t, client, config)
+
+			ctx := context.Background()
+			if err := m.sync(ctx); err == nil {
+				t.Fatal("Expected sync to fail")
+			}
+
+			if _, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-cert", metav1.GetOptions{}); err == nil {
+				t.Error("Expected no serving certificate to be issued")
+			}
+			events, err := client.CoreV1().Events("test-ns").List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("Failed to list events: %v", err)
+			}
+			found := false
+			for _, event := range events.Items {
+				if event.Reason == "ExternalCAInvalid" && event.Type == corev1.EventTypeWarning {
+					found = true
+				}
+			}
+			if !found {
+				t.Error("Expected an ExternalCAInvalid warning event")
+			}
+		})
+	}
+}
+
+func TestManager_sync_ExternalCAForceRotate(t *testing.T) {
+	now := time.Now()
+	root, err := newSelfSignedCA("root", KeySpec{}, 10*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("newSelfSignedCA failed: %v", err)
+	}
+	caSecret := newTestExternalCASecret(t, newTestIntermediateCA(t, root, now.Add(5*24*time.Hour)), root)
+	caSecret.Annotations = map[string]string{ForceRotateAnnotation: "2024-01-01T00:00:00Z"}
+
+	config := newTestConfig()
+	config.ExternalCA = true
+
+	client := fake.NewClientset(caSecret)
+	m := newTestManager(t, client, config)
+
+	ctx := context.Background()
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	// The external CA secret is left untouched and the annotation is
+	// reported once.
+	waitForCertSecretCache(t, m, client)
+	if err := m.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	current, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get CA secret: %v", err)
+	}
+	if !reflect.DeepEqual(current, caSecret) {
+		t.Error("Expected the external CA secret to be left untouched")
+	}
+
+	events, err := client.CoreV1().Events("test-ns").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	ignored := 0
+	for _, event := range events.Items {
+		if event.Reason == "ForceRotateIgnored" && event.Type == corev1.EventTypeWarning {
+			ignored++
+		}
+	}
+	if ignored != 1 {
+		t.Errorf("ForceRotateIgnored warning events: got %d, want 1", ignored)
+	}
+}
+
+// newTestIntermediateCA returns an intermediate CA signed by root and valid until notAfter.
+func newTestIntermediateCA(t *testing.T, root *crypto.TLSCertificateConfig, notAfter time.Time) *crypto.TLSCertificateConfig {
+	t.Helper()
+
+	key, err := KeySpec{Algorithm: KeyAlgorithmECDSA}.generateKey()
+	if err != nil {
+		t.Fatalf("generateKey failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		NotBefore:             root.Certs[0].NotBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, root.Certs[0], key.Public(), root.Key)
+	if err != nil {
+		t.Fatalf("Failed to create intermediate CA: %v", err)
+	}
+	intermediate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse intermediate CA: %v", err)
+	}
+	return &crypto.TLSCertificateConfig{Certs: []*x509.Certificate{intermediate}, Key: key}
+}
+
+// newTestExternalCASecret returns a CA secret for the external CA signing with
+// ca and the root certificate of root in ca.crt.
+func newTestExternalCASecret(t *testing.T, ca, root *crypto.TLSCertificateConfig) *corev1.Secret {
+	t.Helper()
+
+	certPEM, keyPEM, err := encodeCertKeyPair(ca)
+	if err != nil {
+		t.Fatalf("encodeCertKeyPair failed: %v", err)
+	}
+	rootPEM, err := crypto.EncodeCertificates(root.Certs...)
+	if err != nil {
+		t.Fatalf("EncodeCertificates failed: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ca", Namespace: "test-ns"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			"tls.crt": certPEM,
+			"tls.key": keyPEM,
+			"ca.crt":  rootPEM,
+		},
+	}
+}
+
+// mustServingCert returns a serving certificate signed by ca.
+func mustServingCert(t *testing.T, ca *crypto.TLSCertificateConfig) *crypto.TLSCertificateConfig {
+	t.Helper()
+
+	serving, err := newServingCert(&crypto.CA{Config: ca}, pkix.Name{CommonName: "test"}, []string{"test"}, KeySpec{}, time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("newServingCert failed: %v", err)
+	}
+	serving.Certs = serving.Certs[:1]
+	return serving
+}
+
+func TestManager_sync_StaleCache(t *testing.T) {
+	tests := []struct {
+		name string
+		key  KeySpec
+	}{
+		{name: "rotated by library-go", key: KeySpec{Algorithm: KeyAlgorithmRSA, Size: 2048}},
+		{name: "rotated by the manager", key: KeySpec{Algorithm: KeyAlgorithmECDSA, Size: 256}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig()
+			config.CAKey = tt.key
+			config.CertKey = tt.key
+
+			// The informer caches never see the objects created by the
+			// manager, like right after they were created.
+			client := fake.NewClientset()
+			m := New(client, config)
+			indexer := toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc, toolscache.Indexers{toolscache.NamespaceIndex: toolscache.MetaNamespaceIndexFunc})
+			m.secretLister = newLiveSecretLister(listerscorev1.NewSecretLister(indexer), client)
+			m.configMapLister = newLiveConfigMapLister(listerscorev1.NewConfigMapLister(indexer), client)
+
+			ctx := context.Background()
+			if err := m.sync(ctx); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+			caSecret, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Failed to get CA secret: %v", err)
+			}
+
+			if err := m.sync(ctx); err != nil {
+				t.Fatalf("sync with a stale cache failed: %v", err)
+			}
+			current, err := client.CoreV1().Secrets("test-ns").Get(ctx, "test-ca", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Failed to get CA secret: %v", err)
+			}
+			if !reflect.DeepEqual(current.Data, caSecret.Data) {
+				t.Error("Expected the CA to be kept on a sync with a stale cache")
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+// Package certmanagerio manages the cert-manager.io resources that issue the
+// webhook serving certificate when certificates are delegated to cert-manager.
+package certmanagerio
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/jimyag/auto-cert-webhook/internal/certmanager"
+)
+
+const (
+	// Group is the API group of cert-manager resources.
+	Group = "cert-manager.io"
+
+	// KindIssuer is the kind of namespaced cert-manager issuers.
+	KindIssuer = "Issuer"
+
+	// ManagedByLabel is set on resources created by the reconciler.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedByValue is the value of ManagedByLabel.
+	ManagedByValue = "auto-cert-webhook"
+)
+
+// optionalSpecKeys are the Certificate spec fields set by the reconciler only
+// when configured. They are removed when no longer configured.
+var optionalSpecKeys = []string{"ipAddresses", "commonName", "subject"}
+
+var (
+	// CertificateResource is the cert-manager.io/v1 Certificate resource.
+	CertificateResource = schema.GroupVersionResource{Group: Group, Version: "v1", Resource: "certificates"}
+
+	// IssuerResource is the cert-manager.io/v1 Issuer resource.
+	IssuerResource = schema.GroupVersionResource{Group: Group, Version: "v1", Resource: "issuers"}
+)
+
+// Config holds the cert-manager Certificate reconciler configuration.
+type Config struct {
+	// Namespace is the namespace of the webhook service and the Certificate.
+	Namespace string
+
+	// ServiceName is the name of the webhook service, used for the DNS names.
+	ServiceName string
+
+	// ClusterDomain is the cluster DNS domain. When set, the fully qualified
+	// service name is added to the DNS names.
+	ClusterDomain string
+
+	// ExtraDNSNames are additional DNS names of the certificate.
+	ExtraDNSNames []string
+
+	// IPAddresses are the IP addresses of the certificate.
+	IPAddresses []string
+
+	// CommonName is the subject common name of the certificate. Optional.
+	CommonName string
+
+	// Organizations are the subject organizations of the certificate. Optional.
+	Organizations []string
+
+	// CertificateName is the name of the Certificate.
+	CertificateName string
+
+	// SecretName is the name of the secret cert-manager writes the certificate to.
+	SecretName string
+
+	// IssuerName is the name of the issuer signing the certificate.
+	// If empty, a self-signed Issuer named "<CertificateName>-selfsigned" is created.
+	IssuerName string
+
+	// IssuerKind is the kind of the issuer. Defaults to Issuer.
+	IssuerKind string
+
+	// IssuerGroup is the API group of the issuer. Defaults to cert-manager.io.
+	IssuerGroup string
+
+	// Duration is the requested validity of the certificate.
+	Duration time.Duration
+
+	// RenewBefore is how long before expiry cert-manager renews the certificate.
+	RenewBefore time.Duration
+
+	// Key is the private key algorithm and size of the certificate.
+	// Defaults to 2048-bit RSA.
+	Key certmanager.KeySpec
+
+	// SyncInterval is the interval between reconciles.
+	SyncInterval time.Duration
+
+	// Clock drives the sync loop.
+	// If nil, the real clock is used.
+	Clock clock.WithTicker
+}
+
+// Reconciler creates and reconciles the cert-manager Certificate, and the
+// self-signed Issuer when no issuer is configured.
+type Reconciler struct {
+	client dynamic.Interface
+	config Config
+}
+
+// New creates a new cert-manager Certificate reconciler.
+func New(client dynamic.Interface, config Config) *Reconciler {
+	if config.IssuerKind == "" {
+		config.IssuerKind = KindIssuer
+	}
+	if config.IssuerGroup == "" {
+		config.IssuerGroup = Group
+	}
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	return &Reconciler{
+		client: client,
+		config: config,
+	}
+}
+
+// Start reconciles the cert-manager resources periodically until the context is cancelled.
+func (r *Reconciler) Start(ctx context.Context) error {
+	syncInterval := r.config.SyncInterval
+	if syncInterval <= 0 {
+		syncInterval = time.Minute
+	}
+	ticker := r.config.Clock.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	if err := r.sync(ctx); err != nil {
+		klog.Errorf("Initial cert-manager Certificate sync failed: %v", err)
+	}
+
+	klog.Infof("cert-manager Certificate reconciler started for %s/%s", r.config.Namespace, r.config.CertificateName)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			if err := r.sync(ctx); err != nil {
+				klog.Errorf("cert-manager Certificate sync failed: %v", err)
+			}
+		}
+	}
+}
+
+// sync performs a single reconcile.
+func (r *Reconciler) sync(ctx context.Context) error {
+	if r.config.IssuerName == "" {
+		if err := r.ensureSelfSignedIssuer(ctx); err != nil {
+			return fmt.Errorf("failed to ensure self-signed Issuer: %w", err)
+		}
+	}
+	if err := r.ensureCertificate(ctx); err != nil {
+		return fmt.Errorf("failed to ensure Certificate %s: %w", r.config.CertificateName, err)
+	}
+	return nil
+}
+
+// ensureSelfSignedIssuer creates the self-signed Issuer if it does not exist.
+// An existing Issuer is left untouched.
+func (r *Reconciler) ensureSelfSignedIssuer(ctx context.Context) error {
+	client := r.client.Resource(IssuerResource).Namespace(r.config.Namespace)
+
+	name := r.selfSignedIssuerName()
+	_, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	issuer := newObject(KindIssuer, r.config.Namespace, name)
+	issuer.Object["spec"] = map[string]interface{}{
+		"selfSigned": map[string]interface{}{},
+	}
+	if _, err := client.Create(ctx, issuer, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	klog.Infof("Created self-signed Issuer %s/%s", r.config.Namespace, name)
+	return nil
+}
+
+// ensureCertificate creates or updates the Certificate. Only the fields set by
+// the reconciler are compared and replaced, so fields set by users or
+// defaulted by cert-manager are preserved.
+func (r *Reconciler) ensureCertificate(ctx context.Context) error {
+	desired := r.desiredCertificate()
+	client := r.client.Resource(CertificateResource).Namespace(r.config.Namespace)
+
+	current, err := client.Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		if _, err := client.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		klog.Infof("Created Certificate %s/%s", desired.GetNamespace(), desired.GetName())
+		return nil
+	}
+
+	desiredSpec := desired.Object["spec"].(map[string]interface{})
+	currentSpec, _, err := unstructured.NestedMap(current.Object, "spec")
+	if err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	if specContains(currentSpec, desiredSpec) && !hasStaleOptionalKeys(currentSpec, desiredSpec) && current.GetLabels()[ManagedByLabel] == ManagedByValue {
+		return nil
+	}
+
+	updated := current.DeepCopy()
+	if currentSpec == nil {
+		currentSpec = map[string]interface{}{}
+	}
+	for _, key := range optionalSpecKeys {
+		delete(currentSpec, key)
+	}
+	mergeSpec(currentSpec, desiredSpec)
+	updated.Object["spec"] = currentSpec
+
+	labels := updated.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ManagedByLabel] = ManagedByValue
+	updated.SetLabels(labels)
+
+	if _, err := client.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.Infof("Updated Certificate %s/%s", desired.GetNamespace(), desired.GetName())
+	return nil
+}
+
+// desiredCertificate builds the desired Certificate for the service DNS names
+// and the configured extra names and subject.
+func (r *Reconciler) desiredCertificate() *unstructured.Unstructured {
+	issuerName := r.config.IssuerName
+	issuerKind := r.config.IssuerKind
+	issuerGroup := r.config.IssuerGroup
+	if issuerName == "" {
+		issuerName = r.selfSignedIssuerName()
+		issuerKind = KindIssuer
+		issuerGroup = Group
+	}
+
+	spec := map[string]interface{}{
+		"secretName": r.config.SecretName,
+		"dnsNames":   toInterfaces(r.dnsNames()),
+		"privateKey": r.privateKey(),
+		"usages":     r.usages(),
+		"issuerRef": map[string]interface{}{
+			"name":  issuerName,
+			"kind":  issuerKind,
+			"group": issuerGroup,
+		},
+	}
+	if len(r.config.IPAddresses) > 0 {
+		spec["ipAddresses"] = toInterfaces(r.config.IPAddresses)
+	}
+	if r.config.CommonName != "" {
+		spec["commonName"] = r.config.CommonName
+	}
+	if len(r.config.Organizations) > 0 {
+		spec["subject"] = map[string]interface{}{
+			"organizations": toInterfaces(r.config.Organizations),
+		}
+	}
+	if r.config.Duration > 0 {
+		spec["duration"] = r.config.Duration.String()
+	}
+	if r.config.RenewBefore > 0 {
+		spec["renewBefore"] = r.config.RenewBefore.String()
+	}
+
+	certificate := newObject("Certificate", r.config.Namespace, r.config.CertificateName)
+	certificate.Object["spec"] = spec
+	return certificate
+}
+
+// privateKey returns the privateKey spec for the configured key. cert-manager
+// uses the same algorithm names; Ed25519 keys have no size.
+func (r *Reconciler) privateKey() map[string]interface{} {
+	key := r.config.Key.WithDefaults()
+	privateKey := map[string]interface{}{
+		"algorithm": string(key.Algorithm),
+	}
+	if key.Size > 0 {
+		privateKey["size"] = int64(key.Size)
+	}
+	return privateKey
+}
+
+// usages returns the key usages of the certificate. Key encipherment only
+// applies to RSA keys, which encrypt the key exchange in RSA cipher suites.
+func (r *Reconciler) usages() []interface{} {
+	usages := []interface{}{"server auth", "digital signature"}
+	if r.config.Key.WithDefaults().Algorithm == certmanager.KeyAlgorithmRSA {
+		usages = append(usages, "key encipherment")
+	}
+	return usages
+}
+
+// dnsNames returns the DNS names of the webhook service and the extra DNS
+// names, without duplicates.
+func (r *Reconciler) dnsNames() []string {
+	names := []string{
+		r.config.ServiceName,
+		fmt.Sprintf("%s.%s", r.config.ServiceName, r.config.Namespace),
+		fmt.Sprintf("%s.%s.svc", r.config.ServiceName, r.config.Namespace),
+	}
+	if r.config.ClusterDomain != "" {
+		names = append(names, fmt.Sprintf("%s.%s.svc.%s", r.config.ServiceName, r.config.Namespace, r.config.ClusterDomain))
+	}
+	names = append(names, r.config.ExtraDNSNames...)
+
+	seen := sets.New[string]()
+	unique := names[:0]
+	for _, name := range names {
+		if !seen.Has(name) {
+			seen.Insert(name)
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// selfSignedIssuerName returns the name of the Issuer created when no issuer is configured.
+func (r *Reconciler) selfSignedIssuerName() string {
+	return r.config.CertificateName + "-selfsigned"
+}
+
+// newObject returns a cert-manager.io/v1 object labeled as managed by the reconciler.
+func newObject(kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(Group + "/v1")
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(map[string]string{ManagedByLabel: ManagedByValue})
+	return obj
+}
+
+// hasStaleOptionalKeys reports whether current has optional fields that are
+// no longer desired.
+func hasStaleOptionalKeys(current, desired map[string]interface{}) bool {
+	for _, key := range optionalSpecKeys {
+		_, isCurrent := current[key]
+		_, isDesired := desired[key]
+		if isCurrent && !isDesired {
+			return true
+		}
+	}
+	return false
+}
+
+// toInterfaces converts values to the []interface{} form of unstructured objects.
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+// specContains reports whether current has every field of desired with an
+// equal value. Nested maps may have additional fields.
+func specContains(current, desired map[string]interface{}) bool {
+	for key, value := range desired {
+		if desiredMap, ok := value.(map[string]interface{}); ok {
+			currentMap, ok := current[key].(map[string]interface{})
+			if !ok || !specContains(currentMap, desiredMap) {
+				return false
+			}
+			continue
+		}
+		if !equality.Semantic.DeepEqual(current[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeSpec sets the desired fields in current. Nested fields not set by the
+// reconciler, e.g. privateKey.rotationPolicy, are preserved.
+func mergeSpec(current, desired map[string]interface{}) {
+	for key, value := range desired {
+		desiredMap, isMap := value.(map[string]interface{})
+		currentMap, isCurrentMap := current[key].(map[string]interface{})
+		if isMap && isCurrentMap {
+			mergeSpec(currentMap, desiredMap)
+			continue
+		}
+		current[key] = value
+	}
+}
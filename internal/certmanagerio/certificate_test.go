@@ -0,0 +1,258 @@
+package certmanagerio
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/jimyag/auto-cert-webhook/internal/certmanager"
+)
+
+func newFakeClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		CertificateResource: "CertificateList",
+		IssuerResource:      "IssuerList",
+	}, objects...)
+}
+
+func newTestConfig() Config {
+	return Config{
+		Namespace:       "test-ns",
+		ServiceName:     "test-svc",
+		CertificateName: "test-webhook",
+		SecretName:      "test-webhook-cert",
+		Duration:        24 * time.Hour,
+		RenewBefore:     12 * time.Hour,
+	}
+}
+
+func getSpec(t *testing.T, client *dynamicfake.FakeDynamicClient, gvr schema.GroupVersionResource, name string) map[string]interface{} {
+	t.Helper()
+
+	obj, err := client.Resource(gvr).Namespace("test-ns").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get %s %s: %v", gvr.Resource, name, err)
+	}
+	spec, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		t.Fatalf("Invalid spec: %v", err)
+	}
+	return spec
+}
+
+func TestReconciler_sync_SelfSignedIssuer(t *testing.T) {
+	client := newFakeClient()
+	reconciler := New(client, newTestConfig())
+
+	if err := reconciler.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	issuerSpec := getSpec(t, client, IssuerResource, "test-webhook-selfsigned")
+	if _, ok := issuerSpec["selfSigned"]; !ok {
+		t.Errorf("Expected self-signed issuer, got spec %v", issuerSpec)
+	}
+
+	spec := getSpec(t, client, CertificateResource, "test-webhook")
+	if spec["secretName"] != "test-webhook-cert" {
+		t.Errorf("secretName: got %v, want %q", spec["secretName"], "test-webhook-cert")
+	}
+	if spec["duration"] != "24h0m0s" {
+		t.Errorf("duration: got %v, want %q", spec["duration"], "24h0m0s")
+	}
+	if spec["renewBefore"] != "12h0m0s" {
+		t.Errorf("renewBefore: got %v, want %q", spec["renewBefore"], "12h0m0s")
+	}
+
+	dnsNames, _, _ := unstructured.NestedStringSlice(spec, "dnsNames")
+	wantDNSNames := []string{"test-svc", "test-svc.test-ns", "test-svc.test-ns.svc"}
+	if len(dnsNames) != len(wantDNSNames) {
+		t.Fatalf("dnsNames: got %v, want %v", dnsNames, wantDNSNames)
+	}
+	for i := range wantDNSNames {
+		if dnsNames[i] != wantDNSNames[i] {
+			t.Errorf("dnsNames[%d]: got %q, want %q", i, dnsNames[i], wantDNSNames[i])
+		}
+	}
+
+	issuerRef, _, _ := unstructured.NestedStringMap(spec, "issuerRef")
+	if issuerRef["name"] != "test-webhook-selfsigned" || issuerRef["kind"] != KindIssuer || issuerRef["group"] != Group {
+		t.Errorf("issuerRef: got %v", issuerRef)
+	}
+}
+
+func TestReconciler_sync_ConfiguredIssuer(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.IssuerName = "cluster-ca"
+	cfg.IssuerKind = "ClusterIssuer"
+	client := newFakeClient()
+	reconciler := New(client, cfg)
+
+	if err := reconciler.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if _, err := client.Resource(IssuerResource).Namespace("test-ns").Get(context.Background(), "test-webhook-selfsigned", metav1.GetOptions{}); err == nil {
+		t.Error("Expected no self-signed issuer when an issuer is configured")
+	}
+
+	spec := getSpec(t, client, CertificateResource, "test-webhook")
+	issuerRef, _, _ := unstructured.NestedStringMap(spec, "issuerRef")
+	if issuerRef["name"] != "cluster-ca" || issuerRef["kind"] != "ClusterIssuer" || issuerRef["group"] != Group {
+		t.Errorf("issuerRef: got %v", issuerRef)
+	}
+}
+
+func TestReconciler_sync_UpdatesDrift(t *testing.T) {
+	existing := newObject("Certificate", "test-ns", "test-webhook")
+	existing.SetLabels(nil)
+	existing.Object["spec"] = map[string]interface{}{
+		"secretName": "stale-secret",
+		"privateKey": map[string]interface{}{"algorithm": "ECDSA", "rotationPolicy": "Always"},
+	}
+
+	cfg := newTestConfig()
+	cfg.IssuerName = "cluster-ca"
+	client := newFakeClient(existing)
+	reconciler := New(client, cfg)
+
+	if err := reconciler.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	obj, err := client.Resource(CertificateResource).Namespace("test-ns").Get(context.Background(), "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get Certificate: %v", err)
+	}
+	if obj.GetLabels()[ManagedByLabel] != ManagedByValue {
+		t.Errorf("Expected managed-by label, got %v", obj.GetLabels())
+	}
+
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if spec["secretName"] != "test-webhook-cert" {
+		t.Errorf("secretName: got %v, want %q", spec["secretName"], "test-webhook-cert")
+	}
+	if algorithm, _, _ := unstructured.NestedString(spec, "privateKey", "algorithm"); algorithm != "RSA" {
+		t.Errorf("privateKey.algorithm: got %q, want %q", algorithm, "RSA")
+	}
+	if policy, _, _ := unstructured.NestedString(spec, "privateKey", "rotationPolicy"); policy != "Always" {
+		t.Errorf("Expected unmanaged spec fields to be preserved, got privateKey.rotationPolicy %q", policy)
+	}
+
+	// A second sync is a no-op
+	client.ClearActions()
+	if err := reconciler.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("Expected no writes on unchanged Certificate, got %s", action.GetVerb())
+		}
+	}
+}
+
+func TestReconciler_sync_PrivateKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        certmanager.KeySpec
+		wantKey    map[string]interface{}
+		wantUsages []string
+	}{
+		{
+			name:       "default",
+			wantKey:    map[string]interface{}{"algorithm": "RSA", "size": int64(2048)},
+			wantUsages: []string{"server auth", "digital signature", "key encipherment"},
+		},
+		{
+			name:       "rsa 4096",
+			key:        certmanager.KeySpec{Algorithm: certmanager.KeyAlgorithmRSA, Size: 4096},
+			wantKey:    map[string]interface{}{"algorithm": "RSA", "size": int64(4096)},
+			wantUsages: []string{"server auth", "digital signature", "key encipherment"},
+		},
+		{
+			name:       "ecdsa",
+			key:        certmanager.KeySpec{Algorithm: certmanager.KeyAlgorithmECDSA},
+			wantKey:    map[string]interface{}{"algorithm": "ECDSA", "size": int64(256)},
+			wantUsages: []string{"server auth", "digital signature"},
+		},
+		{
+			name:       "ecdsa 384",
+			key:        certmanager.KeySpec{Algorithm: certmanager.KeyAlgorithmECDSA, Size: 384},
+			wantKey:    map[string]interface{}{"algorithm": "ECDSA", "size": int64(384)},
+			wantUsages: []string{"server auth", "digital signature"},
+		},
+		{
+			name:       "ed25519",
+			key:        certmanager.KeySpec{Algorithm: certmanager.KeyAlgorithmEd25519},
+			wantKey:    map[string]interface{}{"algorithm": "Ed25519"},
+			wantUsages: []string{"server auth", "digital signature"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.IssuerName = "cluster-ca"
+			cfg.Key = tt.key
+			client := newFakeClient()
+			reconciler := New(client, cfg)
+
+			if err := reconciler.sync(context.Background()); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			spec := getSpec(t, client, CertificateResource, "test-webhook")
+			if privateKey, _, _ := unstructured.NestedMap(spec, "privateKey"); !reflect.DeepEqual(privateKey, tt.wantKey) {
+				t.Errorf("privateKey: got %v, want %v", privateKey, tt.wantKey)
+			}
+			if usages, _, _ := unstructured.NestedStringSlice(spec, "usages"); !reflect.DeepEqual(usages, tt.wantUsages) {
+				t.Errorf("usages: got %q, want %q", usages, tt.wantUsages)
+			}
+		})
+	}
+}
+
+func TestReconciler_sync_ExtraNames(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.IssuerName = "cluster-ca"
+	cfg.ClusterDomain = "cluster.local"
+	cfg.ExtraDNSNames = []string{"webhook.example.com"}
+	cfg.IPAddresses = []string{"10.0.0.1"}
+	cfg.Organizations = []string{"example"}
+	client := newFakeClient()
+	reconciler := New(client, cfg)
+
+	if err := reconciler.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	spec := getSpec(t, client, CertificateResource, "test-webhook")
+	dnsNames, _, _ := unstructured.NestedStringSlice(spec, "dnsNames")
+	wantDNSNames := []string{"test-svc", "test-svc.test-ns", "test-svc.test-ns.svc", "test-svc.test-ns.svc.cluster.local", "webhook.example.com"}
+	if !reflect.DeepEqual(dnsNames, wantDNSNames) {
+		t.Errorf("dnsNames: got %q, want %q", dnsNames, wantDNSNames)
+	}
+	if ipAddresses, _, _ := unstructured.NestedStringSlice(spec, "ipAddresses"); !reflect.DeepEqual(ipAddresses, []string{"10.0.0.1"}) {
+		t.Errorf("ipAddresses: got %q, want %q", ipAddresses, []string{"10.0.0.1"})
+	}
+	if organizations, _, _ := unstructured.NestedStringSlice(spec, "subject", "organizations"); !reflect.DeepEqual(organizations, []string{"example"}) {
+		t.Errorf("subject.organizations: got %q, want %q", organizations, []string{"example"})
+	}
+
+	// Removing the IP addresses removes them from the Certificate
+	reconciler.config.IPAddresses = nil
+	if err := reconciler.sync(context.Background()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	spec = getSpec(t, client, CertificateResource, "test-webhook")
+	if _, ok := spec["ipAddresses"]; ok {
+		t.Errorf("Expected ipAddresses to be removed, got %v", spec["ipAddresses"])
+	}
+}
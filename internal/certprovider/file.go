@@ -0,0 +1,110 @@
+package certprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// FileProvider provides TLS certificates loaded from PEM files, e.g. mounted
+// by the cert-manager CSI driver or a Vault Agent sidecar.
+//
+// The files are polled and reloaded whenever their content changes. Paths are
+// resolved on every poll, so atomic updates that swap a symlink (as done for
+// Kubernetes volume mounts) are picked up.
+type FileProvider struct {
+	certStore
+
+	certFile string
+	keyFile  string
+	interval time.Duration
+	clock    clock.WithTicker
+
+	// certPEM and keyPEM are the last contents read, used to detect changes.
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// NewFile creates a certificate provider that reads certFile and keyFile
+// every interval, which must be positive.
+func NewFile(certFile, keyFile string, interval time.Duration) *FileProvider {
+	return &FileProvider{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		clock:    clock.RealClock{},
+	}
+}
+
+// UseClock sets the clock that drives polling. Defaults to the real clock.
+// Must be called before Start.
+func (p *FileProvider) UseClock(clk clock.WithTicker) {
+	p.clock = clk
+}
+
+// Start loads the certificate and polls the files for changes until ctx is cancelled.
+func (p *FileProvider) Start(ctx context.Context) error {
+	if err := p.reload(); err != nil {
+		klog.Warningf("Initial certificate load failed (will retry): %v", err)
+	}
+
+	klog.Infof("Certificate provider started watching files %s and %s", p.certFile, p.keyFile)
+
+	ticker := p.clock.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			if err := p.reload(); err != nil {
+				klog.Warningf("Certificate reload failed (will retry): %v", err)
+			}
+		}
+	}
+}
+
+// reload reads the certificate files and stores the key pair if it changed.
+// The current certificate is kept if the files are missing or do not form a
+// valid key pair, e.g. while only one of them has been replaced.
+func (p *FileProvider) reload() error {
+	certPEM, err := os.ReadFile(p.certFile)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	keyPEM, err := os.ReadFile(p.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	if bytes.Equal(certPEM, p.certPEM) && bytes.Equal(keyPEM, p.keyPEM) {
+		return nil
+	}
+
+	cert, err := parseCertificate(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate from %s and %s: %w", p.certFile, p.keyFile, err)
+	}
+
+	p.certPEM = certPEM
+	p.keyPEM = keyPEM
+	p.store(cert)
+	klog.Infof("Certificate reloaded from %s", p.certFile)
+	return nil
+}
+
+// GetCertificate returns the current certificate for TLS configuration.
+func (p *FileProvider) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := p.current.Load()
+	if cert == nil {
+		return nil, fmt.Errorf("certificate not yet loaded from %s", p.certFile)
+	}
+	return cert, nil
+}
@@ -0,0 +1,206 @@
+package certprovider
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestNewFile(t *testing.T) {
+	provider := NewFile("/tls/tls.crt", "/tls/tls.key", time.Second)
+
+	if provider.certFile != "/tls/tls.crt" {
+		t.Errorf("certFile: got %q, want %q", provider.certFile, "/tls/tls.crt")
+	}
+	if provider.keyFile != "/tls/tls.key" {
+		t.Errorf("keyFile: got %q, want %q", provider.keyFile, "/tls/tls.key")
+	}
+	if provider.interval != time.Second {
+		t.Errorf("interval: got %v, want %v", provider.interval, time.Second)
+	}
+}
+
+func TestFileProvider_reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	provider := NewFile(certFile, keyFile, time.Second)
+
+	// Missing files
+	if err := provider.reload(); err == nil {
+		t.Error("Expected error for missing files")
+	}
+	if provider.Ready() {
+		t.Error("Provider should not be ready without files")
+	}
+	if _, err := provider.GetCertificate(nil); err == nil {
+		t.Error("Expected error when certificate not loaded")
+	}
+
+	certPEM1, keyPEM1 := generateTestCert(t)
+	writeFile(t, certFile, certPEM1)
+	writeFile(t, keyFile, keyPEM1)
+
+	if err := provider.reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !provider.Ready() {
+		t.Fatal("Provider should be ready after loading the files")
+	}
+	cert1, err := provider.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate failed: %v", err)
+	}
+
+	// Only the certificate is replaced: the pair is invalid, keep the old one
+	certPEM2, keyPEM2 := generateTestCert(t)
+	writeFile(t, certFile, certPEM2)
+	if err := provider.reload(); err == nil {
+		t.Error("Expected error for mismatched key pair")
+	}
+	if cert, _ := provider.GetCertificate(nil); cert != cert1 {
+		t.Error("Expected previous certificate to be kept")
+	}
+
+	writeFile(t, keyFile, keyPEM2)
+	if err := provider.reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	cert2, err := provider.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate failed: %v", err)
+	}
+	if string(cert1.Certificate[0]) == string(cert2.Certificate[0]) {
+		t.Error("Certificates should be different after reload")
+	}
+}
+
+func TestFileProvider_SymlinkSwap(t *testing.T) {
+	// Mimic the layout of Kubernetes volume mounts:
+	// tls.crt -> ..data/tls.crt, ..data -> ..<timestamp>
+	dir := t.TempDir()
+	certPEM1, keyPEM1 := generateTestCert(t)
+	certPEM2, keyPEM2 := generateTestCert(t)
+
+	writeVersion(t, dir, "..v1", certPEM1, keyPEM1)
+	writeVersion(t, dir, "..v2", certPEM2, keyPEM2)
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+
+	provider := NewFile(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- provider.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		if err := <-errCh; err != nil {
+			t.Errorf("Start returned error: %v", err)
+		}
+	}()
+
+	cert1 := waitForCertificate(t, provider, nil)
+
+	// Atomically swap the ..data symlink to the new version
+	tmpLink := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink("..v2", tmpLink); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Rename(tmpLink, filepath.Join(dir, "..data")); err != nil {
+		t.Fatalf("Failed to swap symlink: %v", err)
+	}
+
+	cert2 := waitForCertificate(t, provider, cert1.Certificate[0])
+	if string(cert1.Certificate[0]) == string(cert2.Certificate[0]) {
+		t.Error("Certificates should be different after symlink swap")
+	}
+}
+
+func TestFileProvider_Start_Clock(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	certPEM1, keyPEM1 := generateTestCert(t)
+	writeFile(t, certFile, certPEM1)
+	writeFile(t, keyFile, keyPEM1)
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	provider := NewFile(certFile, keyFile, time.Hour)
+	provider.UseClock(fakeClock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- provider.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		if err := <-errCh; err != nil {
+			t.Errorf("Start returned error: %v", err)
+		}
+	}()
+
+	cert1 := waitForCertificate(t, provider, nil)
+
+	certPEM2, keyPEM2 := generateTestCert(t)
+	writeFile(t, certFile, certPEM2)
+	writeFile(t, keyFile, keyPEM2)
+
+	// The files are only polled when the injected clock ticks
+	deadline := time.Now().Add(5 * time.Second)
+	for !fakeClock.HasWaiters() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cert, _ := provider.GetCertificate(nil); cert != cert1 {
+		t.Error("Certificate reloaded before the clock ticked")
+	}
+	fakeClock.Step(time.Hour)
+
+	waitForCertificate(t, provider, cert1.Certificate[0])
+}
+
+// waitForCertificate waits until the provider serves a certificate other than previous.
+func waitForCertificate(t *testing.T, provider *FileProvider, previous []byte) *tls.Certificate {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cert, err := provider.GetCertificate(nil); err == nil && string(cert.Certificate[0]) != string(previous) {
+			return cert
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for certificate")
+	return nil
+}
+
+func writeVersion(t *testing.T, dir, version string, certPEM, keyPEM []byte) {
+	t.Helper()
+
+	if err := os.Mkdir(filepath.Join(dir, version), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, version, "tls.crt"), certPEM)
+	writeFile(t, filepath.Join(dir, version, "tls.key"), keyPEM)
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
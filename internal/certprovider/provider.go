@@ -7,25 +7,52 @@ import (
 	"fmt"
 	"sync/atomic"
 
-	"github.com/jimyag/auto-cert-webhook/internal/metrics"
-
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/cert"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/jimyag/auto-cert-webhook/internal/certcheck"
 )
 
+// Source provides the serving certificate to the webhook server.
+type Source interface {
+	// Start loads the certificate and watches it for changes until ctx is cancelled.
+	Start(ctx context.Context) error
+
+	// GetCertificate returns the current certificate for TLS configuration.
+	GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	// Ready returns true if the certificate is loaded and ready.
+	Ready() bool
+
+	// OnReload registers fn to be called with the leaf certificate after
+	// every load or reload. Must be called before Start.
+	OnReload(fn func(leaf *x509.Certificate))
+}
+
 // Provider provides dynamic TLS certificates loaded from Kubernetes secrets.
 type Provider struct {
+	certStore
+
 	client    kubernetes.Interface
 	namespace string
 	name      string
 
-	current atomic.Pointer[tls.Certificate]
-	ready   atomic.Bool
+	// caName is the name of the secret holding an external CA, or "".
+	caName string
+	// ca is the external CA certificate, nil until it is loaded.
+	ca atomic.Pointer[x509.Certificate]
+	// caStatus is the CA state last reported by Ready, "" when valid, used to
+	// log changes only.
+	caStatus atomic.Pointer[string]
+
+	clock clock.PassiveClock
 }
 
 // New creates a new certificate provider.
@@ -34,7 +61,44 @@ func New(client kubernetes.Interface, namespace, secretName string) *Provider {
 		client:    client,
 		namespace: namespace,
 		name:      secretName,
+		clock:     clock.RealClock{},
+	}
+}
+
+// WatchCA makes readiness depend on the external CA in the named secret:
+// the provider is not ready while the CA is missing, invalid or expired.
+// Must be called before Start.
+func (p *Provider) WatchCA(secretName string) {
+	p.caName = secretName
+}
+
+// Ready returns true if the certificate is loaded and, when watching an
+// external CA, the CA is valid.
+func (p *Provider) Ready() bool {
+	if !p.certStore.Ready() {
+		return false
 	}
+	if p.caName == "" {
+		return true
+	}
+
+	// The CA is validated against the clock on every check, so a CA that
+	// becomes valid or expires without a secret update is noticed.
+	status := "not loaded"
+	if ca := p.ca.Load(); ca != nil {
+		status = ""
+		if err := certcheck.ValidateCA(ca, p.clock.Now()); err != nil {
+			status = err.Error()
+		}
+	}
+	if previous := p.caStatus.Swap(&status); previous == nil || *previous != status {
+		if status == "" {
+			klog.Infof("External CA in secret %s/%s is valid", p.namespace, p.caName)
+		} else {
+			klog.Errorf("External CA in secret %s/%s is not usable: %s", p.namespace, p.caName, status)
+		}
+	}
+	return status == ""
 }
 
 // Start starts watching the secret and loading certificates.
@@ -60,9 +124,7 @@ func (p *Provider) Start(ctx context.Context) error {
 				klog.Warningf("unexpected object type in AddFunc: %T", obj)
 				return
 			}
-			if secret.Name == p.name {
-				p.onSecretUpdate(secret)
-			}
+			p.onSecret(secret)
 		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
 			secret, ok := newObj.(*corev1.Secret)
@@ -70,9 +132,7 @@ func (p *Provider) Start(ctx context.Context) error {
 				klog.Warningf("unexpected object type in UpdateFunc: %T", newObj)
 				return
 			}
-			if secret.Name == p.name {
-				p.onSecretUpdate(secret)
-			}
+			p.onSecret(secret)
 		},
 		DeleteFunc: func(obj interface{}) {
 			secret, ok := obj.(*corev1.Secret)
@@ -88,8 +148,11 @@ func (p *Provider) Start(ctx context.Context) error {
 			}
 			if secret.Name == p.name {
 				klog.Warningf("Certificate secret %s/%s deleted", p.namespace, p.name)
-				p.current.Store(nil)
-				p.ready.Store(false)
+				p.clear()
+			}
+			if p.caName != "" && secret.Name == p.caName {
+				klog.Warningf("CA secret %s/%s deleted", p.namespace, p.caName)
+				p.ca.Store(nil)
 			}
 		},
 	})
@@ -109,21 +172,51 @@ func (p *Provider) Start(ctx context.Context) error {
 	return nil
 }
 
-// loadCertificate loads the certificate from the secret.
+// loadCertificate loads the certificate, and the external CA if watched, from the secrets.
 func (p *Provider) loadCertificate(ctx context.Context) error {
-	secret, err := p.client.CoreV1().Secrets(p.namespace).Get(ctx, p.name, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			klog.V(4).Infof("Secret %s/%s not found yet", p.namespace, p.name)
-			return nil
-		}
-		return err
+	names := []string{p.name}
+	if p.caName != "" {
+		names = append(names, p.caName)
 	}
 
-	p.onSecretUpdate(secret)
+	for _, name := range names {
+		secret, err := p.client.CoreV1().Secrets(p.namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				klog.V(4).Infof("Secret %s/%s not found yet", p.namespace, name)
+				continue
+			}
+			return err
+		}
+		p.onSecret(secret)
+	}
 	return nil
 }
 
+// onSecret dispatches added or updated secrets to their handler.
+func (p *Provider) onSecret(secret *corev1.Secret) {
+	switch {
+	case secret.Name == p.name:
+		p.onSecretUpdate(secret)
+	case p.caName != "" && secret.Name == p.caName:
+		p.onCASecretUpdate(secret)
+	}
+}
+
+// onCASecretUpdate handles updates of the external CA secret. The CA is
+// validated by Ready.
+func (p *Provider) onCASecretUpdate(secret *corev1.Secret) {
+	certs, err := cert.ParseCertsPEM(secret.Data["tls.crt"])
+	if err != nil {
+		klog.Errorf("Failed to parse CA certificate from secret %s/%s: %v", p.namespace, p.caName, err)
+		p.ca.Store(nil)
+		return
+	}
+
+	p.ca.Store(certs[0])
+	klog.Infof("CA certificate loaded from secret %s/%s", p.namespace, p.caName)
+}
+
 // onSecretUpdate handles secret updates.
 func (p *Provider) onSecretUpdate(secret *corev1.Secret) {
 	certPEM, ok := secret.Data["tls.crt"]
@@ -138,27 +231,13 @@ func (p *Provider) onSecretUpdate(secret *corev1.Secret) {
 		return
 	}
 
-	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	cert, err := parseCertificate(certPEM, keyPEM)
 	if err != nil {
 		klog.Errorf("Failed to parse certificate from secret %s/%s: %v", p.namespace, p.name, err)
 		return
 	}
 
-	// Update metrics
-	if cert.Leaf == nil {
-		parsed, err := x509.ParseCertificate(cert.Certificate[0])
-		if err != nil {
-			klog.Warningf("Failed to parse certificate leaf from secret %s/%s: %v", p.namespace, p.name, err)
-		} else {
-			cert.Leaf = parsed
-		}
-	}
-	if cert.Leaf != nil {
-		metrics.UpdateCertMetrics("serving", cert.Leaf)
-	}
-
-	p.current.Store(&cert)
-	p.ready.Store(true)
+	p.store(cert)
 	klog.Infof("Certificate reloaded from secret %s/%s", p.namespace, p.name)
 }
 
@@ -170,8 +249,3 @@ func (p *Provider) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, err
 	}
 	return cert, nil
 }
-
-// Ready returns true if the certificate is loaded and ready.
-func (p *Provider) Ready() bool {
-	return p.ready.Load()
-}
@@ -14,6 +14,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestNew(t *testing.T) {
@@ -47,6 +48,94 @@ func TestProvider_Ready(t *testing.T) {
 	}
 }
 
+func TestProvider_Ready_ExternalCA(t *testing.T) {
+	client := fake.NewClientset()
+	provider := New(client, "test-ns", "test-secret")
+	provider.WatchCA("test-ca")
+
+	certPEM, keyPEM := generateTestCert(t)
+	provider.onSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-ns"},
+		Data:       map[string][]byte{"tls.crt": certPEM, "tls.key": keyPEM},
+	})
+	if provider.Ready() {
+		t.Error("Provider should not be ready before the CA is loaded")
+	}
+
+	tests := []struct {
+		name      string
+		caPEM     []byte
+		wantReady bool
+	}{
+		{"valid CA", generateTestCACert(t, time.Now().Add(24*time.Hour)), true},
+		{"expired CA", generateTestCACert(t, time.Now().Add(-time.Hour)), false},
+		{"not a CA", certPEM, false},
+		{"invalid PEM", []byte("invalid"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.onSecret(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-ca", Namespace: "test-ns"},
+				Data:       map[string][]byte{"tls.crt": tt.caPEM},
+			})
+			if got := provider.Ready(); got != tt.wantReady {
+				t.Errorf("Ready: got %v, want %v", got, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestProvider_Ready_ExternalCAExpires(t *testing.T) {
+	clk := testingclock.NewFakeClock(time.Now())
+	provider := New(fake.NewClientset(), "test-ns", "test-secret")
+	provider.clock = clk
+	provider.WatchCA("test-ca")
+
+	certPEM, keyPEM := generateTestCert(t)
+	provider.onSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-ns"},
+		Data:       map[string][]byte{"tls.crt": certPEM, "tls.key": keyPEM},
+	})
+	provider.onSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ca", Namespace: "test-ns"},
+		Data:       map[string][]byte{"tls.crt": generateTestCACert(t, clk.Now().Add(time.Hour))},
+	})
+	if !provider.Ready() {
+		t.Fatal("Provider should be ready with a valid CA")
+	}
+
+	clk.Step(2 * time.Hour)
+	if provider.Ready() {
+		t.Error("Provider should not be ready once the CA expired")
+	}
+}
+
+func TestProvider_Ready_ExternalCANotYetValid(t *testing.T) {
+	clk := testingclock.NewFakeClock(time.Now())
+	provider := New(fake.NewClientset(), "test-ns", "test-secret")
+	provider.clock = clk
+	provider.WatchCA("test-ca")
+
+	certPEM, keyPEM := generateTestCert(t)
+	provider.onSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-ns"},
+		Data:       map[string][]byte{"tls.crt": certPEM, "tls.key": keyPEM},
+	})
+	// The CA is valid from 24 hours on.
+	provider.onSecret(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ca", Namespace: "test-ns"},
+		Data:       map[string][]byte{"tls.crt": generateTestCACert(t, clk.Now().Add(72*time.Hour))},
+	})
+	if provider.Ready() {
+		t.Fatal("Provider should not be ready before the CA is valid")
+	}
+
+	clk.Step(25 * time.Hour)
+	if !provider.Ready() {
+		t.Error("Provider should be ready once the CA is valid")
+	}
+}
+
 func TestProvider_GetCertificate_NotLoaded(t *testing.T) {
 	client := fake.NewClientset()
 	provider := New(client, "test-ns", "test-secret")
@@ -200,6 +289,42 @@ func TestProvider_loadCertificate_Exists(t *testing.T) {
 	}
 }
 
+func TestProvider_OnReload(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-secret",
+			Namespace: "test-ns",
+		},
+		Data: map[string][]byte{
+			"tls.crt": certPEM,
+			"tls.key": keyPEM,
+		},
+	}
+
+	provider := New(fake.NewClientset(), "test-ns", "test-secret")
+	var reloaded []*x509.Certificate
+	provider.OnReload(func(leaf *x509.Certificate) {
+		reloaded = append(reloaded, leaf)
+	})
+
+	provider.onSecretUpdate(secret)
+	if len(reloaded) != 1 {
+		t.Fatalf("reloads: got %d, want 1", len(reloaded))
+	}
+	if cert, _ := provider.GetCertificate(nil); !reloaded[0].Equal(cert.Leaf) {
+		t.Error("expected the reloaded leaf to be the current certificate")
+	}
+
+	// Secrets without a valid key pair are not reported.
+	secret.Data["tls.key"] = nil
+	provider.onSecretUpdate(secret)
+	if len(reloaded) != 1 {
+		t.Errorf("reloads after invalid secret: got %d, want 1", len(reloaded))
+	}
+}
+
 func TestProvider_CertificateReload(t *testing.T) {
 	certPEM1, keyPEM1 := generateTestCert(t)
 	certPEM2, keyPEM2 := generateTestCert(t)
@@ -295,3 +420,30 @@ func generateTestCert(t *testing.T) (certPEM, keyPEM []byte) {
 
 	return certPEM, keyPEM
 }
+
+func generateTestCACert(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "test-ca",
+		},
+		NotBefore:             notAfter.Add(-48 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+}
@@ -0,0 +1,68 @@
+package certprovider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
+)
+
+// certStore holds the current serving certificate of a certificate source.
+type certStore struct {
+	current atomic.Pointer[tls.Certificate]
+	ready   atomic.Bool
+
+	// onReload is called with the leaf of every stored certificate, or nil.
+	onReload func(leaf *x509.Certificate)
+}
+
+// OnReload registers fn to be called with the leaf certificate after every
+// load or reload. Must be called before Start.
+func (s *certStore) OnReload(fn func(leaf *x509.Certificate)) {
+	s.onReload = fn
+}
+
+// store makes cert the current certificate, updates the serving certificate
+// metrics and notifies the reload handler.
+func (s *certStore) store(cert *tls.Certificate) {
+	if cert.Leaf != nil {
+		metrics.UpdateCertMetrics("serving", cert.Leaf)
+	}
+
+	s.current.Store(cert)
+	s.ready.Store(true)
+
+	if s.onReload != nil && cert.Leaf != nil {
+		s.onReload(cert.Leaf)
+	}
+}
+
+// clear drops the current certificate.
+func (s *certStore) clear() {
+	s.current.Store(nil)
+	s.ready.Store(false)
+}
+
+// Ready returns true if the certificate is loaded and ready.
+func (s *certStore) Ready() bool {
+	return s.ready.Load()
+}
+
+// parseCertificate parses a PEM encoded key pair and populates its leaf.
+func parseCertificate(certPEM, keyPEM []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert.Leaf == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate leaf: %w", err)
+		}
+		cert.Leaf = leaf
+	}
+	return &cert, nil
+}
@@ -0,0 +1,47 @@
+// Package dynamictest provides a fake dynamic client that supports
+// server-side apply, shared by the cabundle tests and the webhooktest harness.
+package dynamictest
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// NewClient returns a fake dynamic client serving the given resources, mapped
+// to their kind, e.g. "CustomResourceDefinition". Unlike the default fake
+// dynamic client, applies are merged by a field manager, so conflicts and
+// managed fields behave like on the API server.
+func NewClient(kinds map[schema.GroupVersionResource]string, objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	scheme := runtime.NewScheme()
+	listKinds := make(map[schema.GroupVersionResource]string, len(kinds))
+	for gvr, kind := range kinds {
+		scheme.AddKnownTypeWithName(gvr.GroupVersion().WithKind(kind), &unstructured.Unstructured{})
+		listKinds[gvr] = kind + "List"
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	tracker := clienttesting.NewFieldManagedObjectTracker(scheme, unstructured.UnstructuredJSONScheme, managedfields.NewDeducedTypeConverter())
+	for _, obj := range objects {
+		if err := tracker.Add(obj); err != nil {
+			panic(err)
+		}
+	}
+
+	// Replace the reactors of the default object tracker
+	client.ReactionChain = nil
+	client.AddReactor("*", "*", clienttesting.ObjectReaction(tracker))
+	client.WatchReactionChain = nil
+	client.AddWatchReactor("*", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		w, err := tracker.Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return false, nil, err
+		}
+		return true, w, nil
+	})
+	return client
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Admission request results used as the "result" label.
+const (
+	AdmissionResultAllowed = "allowed"
+	AdmissionResultDenied  = "denied"
+	AdmissionResultErrored = "errored"
+)
+
+var (
+	admissionRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Total number of admission requests handled.",
+		},
+		[]string{"path", "type", "operation", "kind", "result", "patched"},
+	)
+
+	admissionRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of admission requests in seconds.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"path", "type", "operation", "kind", "result"},
+	)
+
+	admissionResponseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "response_size_bytes",
+			Help:      "Size of admission responses in bytes.",
+			Buckets:   prometheus.ExponentialBuckets(128, 4, 8),
+		},
+		[]string{"path", "type"},
+	)
+
+	admissionRequestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Number of admission requests currently being handled.",
+		},
+		[]string{"path", "type"},
+	)
+)
+
+// AdmissionObservation describes a handled admission request.
+type AdmissionObservation struct {
+	// Path is the hook path.
+	Path string
+	// HookType is the hook type, e.g. "Mutating" or "Validating".
+	HookType string
+	// Operation is the admission operation, e.g. "CREATE". Empty if the request could not be decoded.
+	Operation string
+	// Kind is the kind of the object under admission. Empty if the request could not be decoded.
+	Kind string
+	// Result is one of AdmissionResultAllowed, AdmissionResultDenied or AdmissionResultErrored.
+	Result string
+	// Patched reports whether the response carries a patch.
+	Patched bool
+	// Duration is the time spent handling the request.
+	Duration time.Duration
+	// ResponseSize is the size of the response body in bytes.
+	ResponseSize int
+}
+
+// ObserveAdmission records request metrics for a handled admission request.
+func ObserveAdmission(o AdmissionObservation) {
+	admissionRequestsTotal.WithLabelValues(o.Path, o.HookType, o.Operation, o.Kind, o.Result, strconv.FormatBool(o.Patched)).Inc()
+	admissionRequestDuration.WithLabelValues(o.Path, o.HookType, o.Operation, o.Kind, o.Result).Observe(o.Duration.Seconds())
+	admissionResponseSize.WithLabelValues(o.Path, o.HookType).Observe(float64(o.ResponseSize))
+}
+
+// TrackAdmissionInFlight marks an admission request as in flight and returns
+// a function that must be called when the request completes.
+func TrackAdmissionInFlight(path, hookType string) func() {
+	gauge := admissionRequestsInFlight.WithLabelValues(path, hookType)
+	gauge.Inc()
+	return gauge.Dec
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestObserveAdmission(t *testing.T) {
+	admissionRequestsTotal.Reset()
+	admissionRequestDuration.Reset()
+	admissionResponseSize.Reset()
+
+	ObserveAdmission(AdmissionObservation{
+		Path:         "/mutate",
+		HookType:     "Mutating",
+		Operation:    "CREATE",
+		Kind:         "Pod",
+		Result:       AdmissionResultAllowed,
+		Patched:      true,
+		Duration:     50 * time.Millisecond,
+		ResponseSize: 512,
+	})
+	ObserveAdmission(AdmissionObservation{
+		Path:      "/mutate",
+		HookType:  "Mutating",
+		Operation: "CREATE",
+		Kind:      "Pod",
+		Result:    AdmissionResultDenied,
+		Duration:  10 * time.Millisecond,
+	})
+
+	allowed := getCounterValue(t, admissionRequestsTotal, prometheus.Labels{
+		"path": "/mutate", "type": "Mutating", "operation": "CREATE", "kind": "Pod", "result": "allowed", "patched": "true",
+	})
+	if allowed != 1 {
+		t.Errorf("requests_total{result=allowed,patched=true}: got %v, want 1", allowed)
+	}
+	denied := getCounterValue(t, admissionRequestsTotal, prometheus.Labels{
+		"path": "/mutate", "type": "Mutating", "operation": "CREATE", "kind": "Pod", "result": "denied", "patched": "false",
+	})
+	if denied != 1 {
+		t.Errorf("requests_total{result=denied,patched=false}: got %v, want 1", denied)
+	}
+
+	duration := getHistogram(t, admissionRequestDuration, prometheus.Labels{
+		"path": "/mutate", "type": "Mutating", "operation": "CREATE", "kind": "Pod", "result": "allowed",
+	})
+	if duration.GetSampleCount() != 1 || duration.GetSampleSum() != 0.05 {
+		t.Errorf("request_duration_seconds: got count=%d sum=%v", duration.GetSampleCount(), duration.GetSampleSum())
+	}
+
+	size := getHistogram(t, admissionResponseSize, prometheus.Labels{"path": "/mutate", "type": "Mutating"})
+	if size.GetSampleCount() != 2 || size.GetSampleSum() != 512 {
+		t.Errorf("response_size_bytes: got count=%d sum=%v", size.GetSampleCount(), size.GetSampleSum())
+	}
+}
+
+func TestTrackAdmissionInFlight(t *testing.T) {
+	admissionRequestsInFlight.Reset()
+
+	done1 := TrackAdmissionInFlight("/validate", "Validating")
+	done2 := TrackAdmissionInFlight("/validate", "Validating")
+
+	labels := prometheus.Labels{"path": "/validate", "type": "Validating"}
+	if got := getGaugeValueWithLabels(t, admissionRequestsInFlight, labels); got != 2 {
+		t.Errorf("requests_in_flight: got %v, want 2", got)
+	}
+
+	done1()
+	done2()
+
+	if got := getGaugeValueWithLabels(t, admissionRequestsInFlight, labels); got != 0 {
+		t.Errorf("requests_in_flight: got %v, want 0", got)
+	}
+}
+
+func getHistogram(t *testing.T, histogram *prometheus.HistogramVec, labels prometheus.Labels) *dto.Histogram {
+	t.Helper()
+
+	observer, err := histogram.GetMetricWith(labels)
+	if err != nil {
+		t.Fatalf("Failed to get metric: %v", err)
+	}
+
+	metric, ok := observer.(prometheus.Metric)
+	if !ok {
+		t.Fatalf("Observer %T is not a metric", observer)
+	}
+
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatalf("Failed to write metric: %v", err)
+	}
+
+	return m.GetHistogram()
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Conversion request results used as the "result" label.
+const (
+	ConversionResultSucceeded = "succeeded"
+	ConversionResultFailed    = "failed"
+	ConversionResultErrored   = "errored"
+)
+
+var (
+	conversionRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "conversion_requests_total",
+			Help:      "Total number of CustomResourceDefinition conversion requests handled.",
+		},
+		[]string{"path", "kind", "result"},
+	)
+
+	conversionRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "conversion_request_duration_seconds",
+			Help:      "Latency of CustomResourceDefinition conversion requests in seconds.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"path", "kind", "result"},
+	)
+
+	conversionRequestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "conversion_requests_in_flight",
+			Help:      "Number of CustomResourceDefinition conversion requests currently being handled.",
+		},
+		[]string{"path"},
+	)
+)
+
+// ConversionObservation describes a handled conversion request.
+type ConversionObservation struct {
+	// Path is the hook path.
+	Path string
+	// Kind is the kind of the converted objects. Empty if no object could be decoded.
+	Kind string
+	// Result is one of ConversionResultSucceeded, ConversionResultFailed or ConversionResultErrored.
+	Result string
+	// Duration is the time spent handling the request.
+	Duration time.Duration
+}
+
+// ObserveConversion records request metrics for a handled conversion request.
+func ObserveConversion(o ConversionObservation) {
+	conversionRequestsTotal.WithLabelValues(o.Path, o.Kind, o.Result).Inc()
+	conversionRequestDuration.WithLabelValues(o.Path, o.Kind, o.Result).Observe(o.Duration.Seconds())
+}
+
+// TrackConversionInFlight marks a conversion request as in flight and returns
+// a function that must be called when the request completes.
+func TrackConversionInFlight(path string) func() {
+	gauge := conversionRequestsInFlight.WithLabelValues(path)
+	gauge.Inc()
+	return gauge.Dec
+}
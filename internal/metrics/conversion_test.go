@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestObserveConversion(t *testing.T) {
+	conversionRequestsTotal.Reset()
+	conversionRequestDuration.Reset()
+
+	ObserveConversion(ConversionObservation{
+		Path:     "/convert",
+		Kind:     "Widget",
+		Result:   ConversionResultSucceeded,
+		Duration: 50 * time.Millisecond,
+	})
+	ObserveConversion(ConversionObservation{
+		Path:     "/convert",
+		Kind:     "Widget",
+		Result:   ConversionResultFailed,
+		Duration: 10 * time.Millisecond,
+	})
+
+	succeeded := getCounterValue(t, conversionRequestsTotal, prometheus.Labels{"path": "/convert", "kind": "Widget", "result": "succeeded"})
+	if succeeded != 1 {
+		t.Errorf("conversion_requests_total{result=succeeded}: got %v, want 1", succeeded)
+	}
+	failed := getCounterValue(t, conversionRequestsTotal, prometheus.Labels{"path": "/convert", "kind": "Widget", "result": "failed"})
+	if failed != 1 {
+		t.Errorf("conversion_requests_total{result=failed}: got %v, want 1", failed)
+	}
+
+	duration := getHistogram(t, conversionRequestDuration, prometheus.Labels{"path": "/convert", "kind": "Widget", "result": "succeeded"})
+	if duration.GetSampleCount() != 1 || duration.GetSampleSum() != 0.05 {
+		t.Errorf("conversion_request_duration_seconds: got count=%d sum=%v", duration.GetSampleCount(), duration.GetSampleSum())
+	}
+}
+
+func TestTrackConversionInFlight(t *testing.T) {
+	conversionRequestsInFlight.Reset()
+
+	done := TrackConversionInFlight("/convert")
+
+	labels := prometheus.Labels{"path": "/convert"}
+	if got := getGaugeValueWithLabels(t, conversionRequestsInFlight, labels); got != 1 {
+		t.Errorf("conversion_requests_in_flight: got %v, want 1", got)
+	}
+
+	done()
+
+	if got := getGaugeValueWithLabels(t, conversionRequestsInFlight, labels); got != 0 {
+		t.Errorf("conversion_requests_in_flight: got %v, want 0", got)
+	}
+}
@@ -1,8 +1,12 @@
 package metrics
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"crypto/x509"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -48,6 +52,76 @@ var (
 		[]string{"type"},
 	)
 
+	// certKeyInfo exposes the key algorithm and size of certificates.
+	certKeyInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "key_info",
+			Help:      "The key algorithm and size of the certificate in use. Always 1.",
+		},
+		[]string{"type", "algorithm", "size"},
+	)
+
+	// certForcedRotationsTotal counts rotations requested with the force-rotate annotation.
+	certForcedRotationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "forced_rotations_total",
+			Help:      "Number of certificate rotations forced with the force-rotate annotation.",
+		},
+		[]string{"type"},
+	)
+
+	// caBundleDriftRepairsTotal counts caBundle fields restored after they were
+	// changed on a webhook configuration outside the framework.
+	caBundleDriftRepairsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cabundle",
+			Name:      "drift_repairs_total",
+			Help:      "Number of times the caBundle of a webhook configuration was repaired after it drifted.",
+		},
+		[]string{"name", "type"},
+	)
+
+	// caBundleSyncAttemptsTotal counts attempts to patch the caBundle of a
+	// webhook configuration.
+	caBundleSyncAttemptsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cabundle",
+			Name:      "sync_attempts_total",
+			Help:      "Number of attempts to sync the caBundle of a webhook configuration.",
+		},
+		[]string{"name", "type"},
+	)
+
+	// caBundleSyncFailuresTotal counts failed attempts to patch the caBundle of
+	// a webhook configuration.
+	caBundleSyncFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cabundle",
+			Name:      "sync_failures_total",
+			Help:      "Number of failed attempts to sync the caBundle of a webhook configuration.",
+		},
+		[]string{"name", "type"},
+	)
+
+	// caBundleLastSyncSuccessTimestamp tracks the last successful caBundle
+	// sync of a webhook configuration.
+	caBundleLastSyncSuccessTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cabundle",
+			Name:      "last_sync_success_timestamp_seconds",
+			Help:      "The timestamp of the last successful caBundle sync of a webhook configuration in seconds since epoch.",
+		},
+		[]string{"name", "type"},
+	)
+
 	leaderInfo = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
@@ -66,19 +140,42 @@ var (
 		[]string{"namespace", "lease"},
 	)
 
+	admissionPanicsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "panics_total",
+			Help:      "Total number of panics recovered in admission handlers.",
+		},
+		[]string{"path"},
+	)
+
 	registerOnce  sync.Once
 	leaderStateMu sync.Mutex
 	leaderStates  = map[string]string{}
 )
 
-// Register registers all certificate metrics with the default registry.
+// Register registers all metrics with the default registry.
 func Register() {
 	registerOnce.Do(func() {
 		prometheus.MustRegister(certExpiryTimestamp)
 		prometheus.MustRegister(certNotBeforeTimestamp)
 		prometheus.MustRegister(certValidDurationSeconds)
+		prometheus.MustRegister(certKeyInfo)
+		prometheus.MustRegister(certForcedRotationsTotal)
+		prometheus.MustRegister(caBundleDriftRepairsTotal)
+		prometheus.MustRegister(caBundleSyncAttemptsTotal)
+		prometheus.MustRegister(caBundleSyncFailuresTotal)
+		prometheus.MustRegister(caBundleLastSyncSuccessTimestamp)
 		prometheus.MustRegister(leaderInfo)
 		prometheus.MustRegister(hasLeader)
+		prometheus.MustRegister(admissionPanicsTotal)
+		prometheus.MustRegister(admissionRequestsTotal)
+		prometheus.MustRegister(admissionRequestDuration)
+		prometheus.MustRegister(admissionResponseSize)
+		prometheus.MustRegister(admissionRequestsInFlight)
+		prometheus.MustRegister(conversionRequestsTotal)
+		prometheus.MustRegister(conversionRequestDuration)
+		prometheus.MustRegister(conversionRequestsInFlight)
 	})
 }
 
@@ -91,6 +188,54 @@ func UpdateCertMetrics(certType string, cert *x509.Certificate) {
 	certExpiryTimestamp.WithLabelValues(certType).Set(float64(cert.NotAfter.Unix()))
 	certNotBeforeTimestamp.WithLabelValues(certType).Set(float64(cert.NotBefore.Unix()))
 	certValidDurationSeconds.WithLabelValues(certType).Set(cert.NotAfter.Sub(cert.NotBefore).Seconds())
+
+	algorithm, size := keyInfo(cert)
+	certKeyInfo.DeletePartialMatch(prometheus.Labels{"type": certType})
+	certKeyInfo.WithLabelValues(certType, algorithm, size).Set(1)
+}
+
+// RecordAdmissionPanic records a panic recovered in the admission handler for path.
+func RecordAdmissionPanic(path string) {
+	admissionPanicsTotal.WithLabelValues(path).Inc()
+}
+
+// RecordForcedRotation records a rotation of the certType certificate forced
+// with the force-rotate annotation.
+func RecordForcedRotation(certType string) {
+	certForcedRotationsTotal.WithLabelValues(certType).Inc()
+}
+
+// RecordCABundleDriftRepair records a repair of the caBundle of the named
+// webhook configuration of webhookType.
+func RecordCABundleDriftRepair(name, webhookType string) {
+	caBundleDriftRepairsTotal.WithLabelValues(name, webhookType).Inc()
+}
+
+// ObserveCABundleSync records an attempt to sync the caBundle of the named
+// webhook configuration of webhookType, which failed if err is not nil.
+func ObserveCABundleSync(name, webhookType string, err error) {
+	caBundleSyncAttemptsTotal.WithLabelValues(name, webhookType).Inc()
+	if err != nil {
+		caBundleSyncFailuresTotal.WithLabelValues(name, webhookType).Inc()
+		return
+	}
+	caBundleLastSyncSuccessTimestamp.WithLabelValues(name, webhookType).SetToCurrentTime()
+}
+
+// keyInfo returns the key algorithm and size of cert's public key. The size
+// is the modulus size in bits for RSA, the curve size for ECDSA and empty for
+// Ed25519.
+func keyInfo(cert *x509.Certificate) (string, string) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", strconv.Itoa(key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA", strconv.Itoa(key.Curve.Params().BitSize)
+	case ed25519.PublicKey:
+		return "Ed25519", ""
+	default:
+		return cert.PublicKeyAlgorithm.String(), ""
+	}
 }
 
 // UpdateLeaderMetrics updates leader metrics from the current lease holder state.
@@ -1,10 +1,15 @@
 package metrics
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"errors"
 	"math/big"
 	"testing"
 	"time"
@@ -69,6 +74,125 @@ func TestUpdateCertMetrics(t *testing.T) {
 		// Should not panic
 		UpdateCertMetrics("test", nil)
 	})
+
+	t.Run("exposes key algorithm", func(t *testing.T) {
+		certKeyInfo.Reset()
+
+		UpdateCertMetrics("serving", cert)
+		UpdateCertMetrics("serving", cert)
+
+		keyMetrics := collectGaugeMetrics(t, certKeyInfo)
+		if len(keyMetrics) != 1 {
+			t.Fatalf("certKeyInfo metric count: got %d, want 1", len(keyMetrics))
+		}
+		if keyMetrics[0].labels["algorithm"] != "RSA" {
+			t.Errorf("algorithm: got %q, want %q", keyMetrics[0].labels["algorithm"], "RSA")
+		}
+		if keyMetrics[0].labels["size"] != "2048" {
+			t.Errorf("size: got %q, want %q", keyMetrics[0].labels["size"], "2048")
+		}
+	})
+}
+
+func TestKeyInfo(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		key           crypto.Signer
+		wantAlgorithm string
+		wantSize      string
+	}{
+		{"ecdsa", ecdsaKey, "ECDSA", "384"},
+		{"ed25519", ed25519Key, "Ed25519", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: "test"},
+				NotBefore:    time.Now(),
+				NotAfter:     time.Now().Add(time.Hour),
+			}
+			certDER, err := x509.CreateCertificate(rand.Reader, template, template, tt.key.Public(), tt.key)
+			if err != nil {
+				t.Fatalf("Failed to create certificate: %v", err)
+			}
+			cert, err := x509.ParseCertificate(certDER)
+			if err != nil {
+				t.Fatalf("Failed to parse certificate: %v", err)
+			}
+
+			algorithm, size := keyInfo(cert)
+			if algorithm != tt.wantAlgorithm {
+				t.Errorf("algorithm: got %q, want %q", algorithm, tt.wantAlgorithm)
+			}
+			if size != tt.wantSize {
+				t.Errorf("size: got %q, want %q", size, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestRecordForcedRotation(t *testing.T) {
+	certForcedRotationsTotal.Reset()
+
+	RecordForcedRotation("ca")
+	RecordForcedRotation("serving")
+	RecordForcedRotation("serving")
+
+	if got := getCounterValue(t, certForcedRotationsTotal, prometheus.Labels{"type": "ca"}); got != 1 {
+		t.Errorf("forced_rotations_total{type=ca}: got %v, want 1", got)
+	}
+	if got := getCounterValue(t, certForcedRotationsTotal, prometheus.Labels{"type": "serving"}); got != 2 {
+		t.Errorf("forced_rotations_total{type=serving}: got %v, want 2", got)
+	}
+}
+
+func TestRecordCABundleDriftRepair(t *testing.T) {
+	caBundleDriftRepairsTotal.Reset()
+
+	RecordCABundleDriftRepair("test", "validating")
+	RecordCABundleDriftRepair("test", "validating")
+
+	if got := getCounterValue(t, caBundleDriftRepairsTotal, prometheus.Labels{"name": "test", "type": "validating"}); got != 2 {
+		t.Errorf("drift_repairs_total{name=test,type=validating}: got %v, want 2", got)
+	}
+	if got := getCounterValue(t, caBundleDriftRepairsTotal, prometheus.Labels{"name": "test", "type": "mutating"}); got != 0 {
+		t.Errorf("drift_repairs_total{name=test,type=mutating}: got %v, want 0", got)
+	}
+}
+
+func TestObserveCABundleSync(t *testing.T) {
+	caBundleSyncAttemptsTotal.Reset()
+	caBundleSyncFailuresTotal.Reset()
+	caBundleLastSyncSuccessTimestamp.Reset()
+
+	labels := prometheus.Labels{"name": "test", "type": "validating"}
+	before := time.Now().Unix()
+	ObserveCABundleSync("test", "validating", errors.New("conflict"))
+	if got := getGaugeValueWithLabels(t, caBundleLastSyncSuccessTimestamp, labels); got != 0 {
+		t.Errorf("last_sync_success_timestamp_seconds after a failure: got %v, want 0", got)
+	}
+	ObserveCABundleSync("test", "validating", nil)
+
+	if got := getCounterValue(t, caBundleSyncAttemptsTotal, labels); got != 2 {
+		t.Errorf("sync_attempts_total: got %v, want 2", got)
+	}
+	if got := getCounterValue(t, caBundleSyncFailuresTotal, labels); got != 1 {
+		t.Errorf("sync_failures_total: got %v, want 1", got)
+	}
+	if got := getGaugeValueWithLabels(t, caBundleLastSyncSuccessTimestamp, labels); got < float64(before) {
+		t.Errorf("last_sync_success_timestamp_seconds: got %v, want at least %d", got, before)
+	}
 }
 
 func TestRegister(t *testing.T) {
@@ -130,6 +254,21 @@ func TestUpdateLeaderMetrics(t *testing.T) {
 	})
 }
 
+func TestRecordAdmissionPanic(t *testing.T) {
+	admissionPanicsTotal.Reset()
+
+	RecordAdmissionPanic("/mutate")
+	RecordAdmissionPanic("/mutate")
+	RecordAdmissionPanic("/validate")
+
+	if got := getCounterValue(t, admissionPanicsTotal, prometheus.Labels{"path": "/mutate"}); got != 2 {
+		t.Errorf("panics_total{path=/mutate}: got %v, want 2", got)
+	}
+	if got := getCounterValue(t, admissionPanicsTotal, prometheus.Labels{"path": "/validate"}); got != 1 {
+		t.Errorf("panics_total{path=/validate}: got %v, want 1", got)
+	}
+}
+
 func TestHandler(t *testing.T) {
 	handler := Handler()
 	if handler == nil {
@@ -170,6 +309,22 @@ func getGaugeValueWithLabels(t *testing.T, gauge *prometheus.GaugeVec, labels pr
 	return m.GetGauge().GetValue()
 }
 
+func getCounterValue(t *testing.T, counter *prometheus.CounterVec, labels prometheus.Labels) float64 {
+	t.Helper()
+
+	metric, err := counter.GetMetricWith(labels)
+	if err != nil {
+		t.Fatalf("Failed to get metric: %v", err)
+	}
+
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatalf("Failed to write metric: %v", err)
+	}
+
+	return m.GetCounter().GetValue()
+}
+
 type collectedGaugeMetric struct {
 	labels map[string]string
 	value  float64
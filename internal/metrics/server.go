@@ -3,6 +3,7 @@ package metrics
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -16,6 +17,9 @@ type ServerConfig struct {
 
 	// Path is the path to serve metrics on.
 	Path string
+
+	// Listener, if set, is used instead of listening on Port.
+	Listener net.Listener
 }
 
 // Server is a dedicated HTTP server for serving Prometheus metrics.
@@ -51,11 +55,17 @@ func (s *Server) Start(ctx context.Context) error {
 		IdleTimeout:       60 * time.Second,
 	}
 
-	klog.Infof("Starting metrics server on port %d", s.config.Port)
-
 	errCh := make(chan error, 1)
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.config.Listener != nil {
+			klog.Infof("Starting metrics server on %s", s.config.Listener.Addr())
+			err = s.server.Serve(s.config.Listener)
+		} else {
+			klog.Infof("Starting metrics server on port %d", s.config.Port)
+			err = s.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
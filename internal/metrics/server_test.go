@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"testing"
 	"time"
@@ -75,4 +76,44 @@ func TestServer_Start(t *testing.T) {
 			t.Error("Server did not shutdown in time")
 		}
 	})
+	t.Run("serves on listener", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+
+		server := NewServer(ServerConfig{
+			Path:     "/metrics",
+			Listener: listener,
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- server.Start(ctx)
+		}()
+
+		// The listener is already bound, so the server is reachable immediately
+		resp, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+		if err != nil {
+			t.Fatalf("Failed to connect to metrics server: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		cancel()
+
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Errorf("Server returned error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("Server did not shutdown in time")
+		}
+	})
 }
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
+)
+
+// ConversionHookType is the hook type of conversion webhooks.
+const ConversionHookType = "Conversion"
+
+// defaultConversionTimeout is used when the API server does not send a
+// timeout query parameter. It matches the fixed timeout of the API server's
+// conversion webhook calls.
+const defaultConversionTimeout = 30 * time.Second
+
+// ConvertFunc is the function signature for converting a custom resource.
+// This is defined here to match the public API type signature.
+type ConvertFunc = func(ctx context.Context, obj *unstructured.Unstructured, desiredAPIVersion string) (*unstructured.Unstructured, error)
+
+// conversionHandler handles CustomResourceDefinition conversion requests.
+type conversionHandler struct {
+	convert ConvertFunc
+	// path is the registered hook path used as metrics label.
+	path string
+}
+
+// RegisterConversion registers a CustomResourceDefinition conversion webhook
+// handler at the given path. Middlewares are not applied to conversions.
+func (s *Server) RegisterConversion(path string, convert ConvertFunc) {
+	s.mux.Handle(path, &conversionHandler{convert: convert, path: path})
+	klog.V(2).Infof("Registered %s webhook at %s", ConversionHookType, path)
+}
+
+func (h *conversionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	klog.V(2).Infof("Handling conversion request: %s %s", r.Method, r.URL.Path)
+
+	start := time.Now()
+	done := metrics.TrackConversionInFlight(h.path)
+	defer done()
+
+	// Requests that fail before a response is built are recorded as errored.
+	observation := metrics.ConversionObservation{
+		Path:   h.path,
+		Result: metrics.ConversionResultErrored,
+	}
+	defer func() {
+		observation.Duration = time.Since(start)
+		metrics.ObserveConversion(observation)
+	}()
+
+	body, ok := readReviewBody(w, r)
+	if !ok {
+		return
+	}
+
+	var requestedReview apiextensionsv1.ConversionReview
+	if err := json.Unmarshal(body, &requestedReview); err != nil {
+		klog.Errorf("Failed to decode conversion review: %v", err)
+		http.Error(w, fmt.Sprintf("failed to decode conversion review: %v", err), http.StatusBadRequest)
+		return
+	}
+	if requestedReview.Request == nil {
+		klog.Error("ConversionReview.Request is nil")
+		http.Error(w, "ConversionReview.Request is nil", http.StatusBadRequest)
+		return
+	}
+
+	// Answer in the version of the request, apiextensions.k8s.io/v1 or
+	// v1beta1, which has the same shape.
+	responseReview := apiextensionsv1.ConversionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: requestedReview.APIVersion,
+			Kind:       "ConversionReview",
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout(r, defaultConversionTimeout))
+	responseReview.Response, observation.Kind = h.convertAll(ctx, requestedReview.Request)
+	cancel()
+
+	observation.Result = metrics.ConversionResultFailed
+	if responseReview.Response.Result.Status == metav1.StatusSuccess {
+		observation.Result = metrics.ConversionResultSucceeded
+	}
+
+	klog.V(4).Infof("Sending conversion response: %+v", responseReview.Response.Result)
+
+	respBytes, err := json.Marshal(responseReview)
+	if err != nil {
+		klog.Errorf("Failed to marshal conversion response: %v", err)
+		http.Error(w, fmt.Sprintf("failed to marshal conversion response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(respBytes); err != nil {
+		klog.Errorf("Failed to write conversion response: %v", err)
+	}
+}
+
+// convertAll converts every object of req to the desired API version and
+// returns the response along with the kind of the objects. The conversion
+// fails as a whole if any object cannot be converted.
+func (h *conversionHandler) convertAll(ctx context.Context, req *apiextensionsv1.ConversionRequest) (*apiextensionsv1.ConversionResponse, string) {
+	resp := &apiextensionsv1.ConversionResponse{UID: req.UID}
+
+	var kind string
+	converted := make([]runtime.RawExtension, 0, len(req.Objects))
+	for i, raw := range req.Objects {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+			resp.Result = failedConversion(fmt.Sprintf("failed to decode object %d: %v", i, err))
+			return resp, kind
+		}
+		kind = obj.GetKind()
+
+		out, err := h.safeConvert(ctx, obj, req.DesiredAPIVersion)
+		if err != nil {
+			resp.Result = failedConversion(fmt.Sprintf("failed to convert %s %s to %s: %v", obj.GetKind(), objectName(obj), req.DesiredAPIVersion, err))
+			return resp, kind
+		}
+
+		data, err := out.MarshalJSON()
+		if err != nil {
+			resp.Result = failedConversion(fmt.Sprintf("failed to encode converted %s %s: %v", obj.GetKind(), objectName(obj), err))
+			return resp, kind
+		}
+		converted = append(converted, runtime.RawExtension{Raw: data})
+	}
+
+	resp.ConvertedObjects = converted
+	resp.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return resp, kind
+}
+
+// safeConvert converts obj, passing objects already in the desired version
+// through unchanged. A panic in the convert function is returned as an error.
+func (h *conversionHandler) safeConvert(ctx context.Context, obj *unstructured.Unstructured, desiredAPIVersion string) (out *unstructured.Unstructured, err error) {
+	if obj.GetAPIVersion() == desiredAPIVersion {
+		return obj, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			klog.Errorf("Recovered panic in conversion handler %s: %v\n%s", h.path, r, debug.Stack())
+			metrics.RecordAdmissionPanic(h.path)
+			err = fmt.Errorf("conversion handler panicked: %v", r)
+		}
+	}()
+
+	out, err = h.convert(ctx, obj, desiredAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("conversion function returned no object: %w", err)
+		}
+		return nil, fmt.Errorf("conversion function returned no object")
+	}
+	if out.GetAPIVersion() != desiredAPIVersion {
+		return nil, fmt.Errorf("conversion function returned apiVersion %q", out.GetAPIVersion())
+	}
+	return out, nil
+}
+
+// objectName returns the namespace/name of obj for messages.
+func objectName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// failedConversion returns the result of a failed conversion.
+func failedConversion(message string) metav1.Status {
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: message,
+	}
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
+)
+
+// convertWidget converts example.com Widgets between v1 and v2, renaming
+// spec.size to spec.replicas.
+func convertWidget(_ context.Context, obj *unstructured.Unstructured, desiredAPIVersion string) (*unstructured.Unstructured, error) {
+	out := obj.DeepCopy()
+	out.SetAPIVersion(desiredAPIVersion)
+	switch desiredAPIVersion {
+	case "example.com/v2":
+		size, _, _ := unstructured.NestedInt64(obj.Object, "spec", "size")
+		unstructured.RemoveNestedField(out.Object, "spec", "size")
+		if err := unstructured.SetNestedField(out.Object, size, "spec", "replicas"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unsupported version")
+	}
+	return out, nil
+}
+
+func newTestWidget(apiVersion, name string, size int64) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"spec":       map[string]interface{}{"size": size},
+	})
+	return data
+}
+
+func serveConversion(t *testing.T, handler http.Handler, apiVersion, desiredAPIVersion string, objects ...[]byte) apiextensionsv1.ConversionReview {
+	t.Helper()
+
+	review := apiextensionsv1.ConversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiVersion, Kind: "ConversionReview"},
+		Request:  &apiextensionsv1.ConversionRequest{UID: "test-uid", DesiredAPIVersion: desiredAPIVersion},
+	}
+	for _, object := range objects {
+		review.Request.Objects = append(review.Request.Objects, runtime.RawExtension{Raw: object})
+	}
+	body, _ := json.Marshal(review)
+
+	req := httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp apiextensionsv1.ConversionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if resp.Response == nil {
+		t.Fatal("Expected a response")
+	}
+	if resp.Response.UID != "test-uid" {
+		t.Errorf("UID: got %q, want %q", resp.Response.UID, "test-uid")
+	}
+	return resp
+}
+
+func TestConversionHandler_ServeHTTP(t *testing.T) {
+	handler := &conversionHandler{convert: convertWidget, path: "/convert"}
+
+	t.Run("successful conversion", func(t *testing.T) {
+		resp := serveConversion(t, handler, "apiextensions.k8s.io/v1", "example.com/v2",
+			newTestWidget("example.com/v1", "a", 3),
+			newTestWidget("example.com/v2", "b", 5),
+		)
+
+		if resp.APIVersion != "apiextensions.k8s.io/v1" || resp.Kind != "ConversionReview" {
+			t.Errorf("TypeMeta: got %+v", resp.TypeMeta)
+		}
+		if resp.Response.Result.Status != metav1.StatusSuccess {
+			t.Fatalf("Result: got %+v, want success", resp.Response.Result)
+		}
+		if len(resp.Response.ConvertedObjects) != 2 {
+			t.Fatalf("Converted objects: got %d, want 2", len(resp.Response.ConvertedObjects))
+		}
+
+		converted := &unstructured.Unstructured{}
+		if err := converted.UnmarshalJSON(resp.Response.ConvertedObjects[0].Raw); err != nil {
+			t.Fatalf("Failed to decode converted object: %v", err)
+		}
+		if converted.GetAPIVersion() != "example.com/v2" {
+			t.Errorf("apiVersion: got %q, want %q", converted.GetAPIVersion(), "example.com/v2")
+		}
+		if replicas, _, _ := unstructured.NestedInt64(converted.Object, "spec", "replicas"); replicas != 3 {
+			t.Errorf("spec.replicas: got %d, want 3", replicas)
+		}
+
+		// Objects already in the desired version are passed through.
+		passed := &unstructured.Unstructured{}
+		if err := passed.UnmarshalJSON(resp.Response.ConvertedObjects[1].Raw); err != nil {
+			t.Fatalf("Failed to decode converted object: %v", err)
+		}
+		if size, _, _ := unstructured.NestedInt64(passed.Object, "spec", "size"); size != 5 {
+			t.Errorf("spec.size: got %d, want 5", size)
+		}
+	})
+
+	t.Run("v1beta1 review", func(t *testing.T) {
+		resp := serveConversion(t, handler, "apiextensions.k8s.io/v1beta1", "example.com/v2", newTestWidget("example.com/v1", "a", 3))
+
+		if resp.APIVersion != "apiextensions.k8s.io/v1beta1" {
+			t.Errorf("APIVersion: got %q, want %q", resp.APIVersion, "apiextensions.k8s.io/v1beta1")
+		}
+		if resp.Response.Result.Status != metav1.StatusSuccess {
+			t.Errorf("Result: got %+v, want success", resp.Response.Result)
+		}
+	})
+
+	t.Run("failed conversion", func(t *testing.T) {
+		resp := serveConversion(t, handler, "apiextensions.k8s.io/v1", "example.com/v3", newTestWidget("example.com/v1", "a", 3))
+
+		if resp.Response.Result.Status != metav1.StatusFailure {
+			t.Errorf("Result: got %+v, want failure", resp.Response.Result)
+		}
+		if !strings.Contains(resp.Response.Result.Message, "default/a") || !strings.Contains(resp.Response.Result.Message, "unsupported version") {
+			t.Errorf("Message: got %q", resp.Response.Result.Message)
+		}
+		if len(resp.Response.ConvertedObjects) != 0 {
+			t.Errorf("Expected no converted objects, got %d", len(resp.Response.ConvertedObjects))
+		}
+	})
+
+	t.Run("wrong version returned", func(t *testing.T) {
+		handler := &conversionHandler{convert: func(_ context.Context, obj *unstructured.Unstructured, _ string) (*unstructured.Unstructured, error) {
+			return obj, nil
+		}}
+		resp := serveConversion(t, handler, "apiextensions.k8s.io/v1", "example.com/v2", newTestWidget("example.com/v1", "a", 3))
+
+		if resp.Response.Result.Status != metav1.StatusFailure {
+			t.Errorf("Result: got %+v, want failure", resp.Response.Result)
+		}
+	})
+
+	t.Run("missing request", func(t *testing.T) {
+		body := []byte(`{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview"}`)
+		req := httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("wrong content type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader([]byte("test")))
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected status %d, got %d", http.StatusUnsupportedMediaType, rec.Code)
+		}
+	})
+}
+
+func TestConversionHandler_Panic(t *testing.T) {
+	metrics.Register()
+
+	handler := &conversionHandler{
+		convert: func(context.Context, *unstructured.Unstructured, string) (*unstructured.Unstructured, error) {
+			panic("boom")
+		},
+		path: "/convert-panic-test",
+	}
+	resp := serveConversion(t, handler, "apiextensions.k8s.io/v1", "example.com/v2", newTestWidget("example.com/v1", "a", 3))
+
+	if resp.Response.Result.Status != metav1.StatusFailure || !strings.Contains(resp.Response.Result.Message, "boom") {
+		t.Errorf("Expected panic message in result, got %+v", resp.Response.Result)
+	}
+
+	metricsRec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(metricsRec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(metricsRec.Body.String(), `admission_webhook_panics_total{path="/convert-panic-test"} 1`) {
+		t.Errorf("panics_total metric not found in output:\n%s", metricsRec.Body.String())
+	}
+}
+
+func TestConversionHandler_Metrics(t *testing.T) {
+	metrics.Register()
+
+	handler := &conversionHandler{convert: convertWidget, path: "/convert-metrics-test"}
+	serveConversion(t, handler, "apiextensions.k8s.io/v1", "example.com/v2", newTestWidget("example.com/v1", "a", 3))
+	serveConversion(t, handler, "apiextensions.k8s.io/v1", "example.com/v3", newTestWidget("example.com/v1", "a", 3))
+
+	metricsRec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(metricsRec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	output := metricsRec.Body.String()
+
+	for _, want := range []string{
+		`admission_webhook_conversion_requests_total{kind="Widget",path="/convert-metrics-test",result="succeeded"} 1`,
+		`admission_webhook_conversion_requests_total{kind="Widget",path="/convert-metrics-test",result="failed"} 1`,
+		`admission_webhook_conversion_requests_in_flight{path="/convert-metrics-test"} 0`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("metric %q not found in output", want)
+		}
+	}
+	if strings.Contains(output, `path="/convert-metrics-test",result="allowed"`) {
+		t.Error("conversion recorded in admission requests_total")
+	}
+}
+
+func TestServer_RegisterConversion(t *testing.T) {
+	srv := newTestServer(&mockCertProvider{}, Config{HealthzPath: "/healthz", ReadyzPath: "/readyz"})
+	srv.RegisterConversion("/convert", convertWidget)
+
+	resp := serveConversion(t, srv.mux, "apiextensions.k8s.io/v1", "example.com/v2", newTestWidget("example.com/v1", "a", 3))
+	if resp.Response.Result.Status != metav1.StatusSuccess {
+		t.Errorf("Result: got %+v, want success", resp.Response.Result)
+	}
+}
@@ -1,22 +1,37 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"runtime/debug"
 	"sync"
+	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/klog/v2"
+
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
 )
 
 const (
 	// maxRequestBodySize is the maximum allowed request body size (10MB).
 	maxRequestBodySize = 10 * 1024 * 1024
+
+	// defaultAdmissionTimeout is used when the API server does not send a
+	// timeout query parameter. It matches the API server's default
+	// webhook timeoutSeconds.
+	defaultAdmissionTimeout = 10 * time.Second
+
+	// admissionTimeoutMargin is subtracted from the API server timeout so the
+	// handler gives up and responds before the API server applies failurePolicy.
+	admissionTimeoutMargin = 500 * time.Millisecond
 )
 
 var (
@@ -34,6 +49,10 @@ func initScheme() error {
 			schemeErr = fmt.Errorf("failed to add admissionv1 scheme: %w", err)
 			return
 		}
+		if err := admissionv1beta1.AddToScheme(scheme); err != nil {
+			schemeErr = fmt.Errorf("failed to add admissionv1beta1 scheme: %w", err)
+			return
+		}
 		codecs = serializer.NewCodecFactory(scheme)
 	})
 	return schemeErr
@@ -41,23 +60,48 @@ func initScheme() error {
 
 // admissionHandler handles admission requests.
 type admissionHandler struct {
-	admit AdmitFunc
+	admit AdmitContextFunc
+	// path is the registered hook path used as metrics label.
+	// Falls back to the request path when empty.
+	path string
+	// hookType is the registered hook type used as metrics label.
+	hookType string
 }
 
 func newAdmissionHandler(admit AdmitFunc) *admissionHandler {
+	return newAdmissionHandlerContext(func(_ context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		return admit(ar)
+	})
+}
+
+func newAdmissionHandlerContext(admit AdmitContextFunc) *admissionHandler {
 	return &admissionHandler{admit: admit}
 }
 
-func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	klog.V(2).Infof("Handling admission request: %s %s", r.Method, r.URL.Path)
+// requestTimeout returns the time budget for handling the request, derived
+// from the "timeout" query parameter set by the API server (e.g. "10s"), or
+// from defaultTimeout when it is not set.
+func requestTimeout(r *http.Request, defaultTimeout time.Duration) time.Duration {
+	timeout := defaultTimeout
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			klog.V(2).Infof("Ignoring invalid request timeout %q", raw)
+		} else {
+			timeout = parsed
+		}
+	}
 
-	// Initialize scheme lazily
-	if err := initScheme(); err != nil {
-		klog.Errorf("Failed to initialize scheme: %v", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
-		return
+	if timeout > 2*admissionTimeoutMargin {
+		timeout -= admissionTimeoutMargin
 	}
+	return timeout
+}
 
+// readReviewBody reads the JSON encoded review sent by the API server. It
+// writes the error response and returns false if the body is missing, too
+// large or not JSON.
+func readReviewBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
 	var body []byte
 	if r.Body != nil {
 		defer r.Body.Close()
@@ -66,7 +110,7 @@ func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			klog.Errorf("Failed to read request body: %v", err)
 			http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
-			return
+			return nil, false
 		}
 		body = data
 	}
@@ -74,22 +118,54 @@ func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if len(body) == 0 {
 		klog.Error("Empty request body")
 		http.Error(w, "empty request body", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	contentType := r.Header.Get("Content-Type")
 	if contentType != "application/json" {
 		klog.Errorf("Unsupported content type: %s", contentType)
 		http.Error(w, fmt.Sprintf("unsupported content type: %s", contentType), http.StatusUnsupportedMediaType)
-		return
+		return nil, false
 	}
 
 	klog.V(4).Infof("Request body: %s", string(body))
+	return body, true
+}
 
-	// Decode the request
-	requestedAdmissionReview := admissionv1.AdmissionReview{}
-	deserializer := codecs.UniversalDeserializer()
-	if _, _, err := deserializer.Decode(body, nil, &requestedAdmissionReview); err != nil {
+func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	klog.V(2).Infof("Handling admission request: %s %s", r.Method, r.URL.Path)
+
+	path := h.hookPath(r)
+	start := time.Now()
+	done := metrics.TrackAdmissionInFlight(path, h.hookType)
+	defer done()
+
+	// Requests that fail before a response is built are recorded as errored.
+	observation := metrics.AdmissionObservation{
+		Path:     path,
+		HookType: h.hookType,
+		Result:   metrics.AdmissionResultErrored,
+	}
+	defer func() {
+		observation.Duration = time.Since(start)
+		metrics.ObserveAdmission(observation)
+	}()
+
+	// Initialize scheme lazily
+	if err := initScheme(); err != nil {
+		klog.Errorf("Failed to initialize scheme: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	body, ok := readReviewBody(w, r)
+	if !ok {
+		return
+	}
+
+	// Decode the request, converting v1beta1 reviews to v1
+	requestedAdmissionReview, err := decodeAdmissionReview(body)
+	if err != nil {
 		klog.Errorf("Failed to decode admission review: %v", err)
 		http.Error(w, fmt.Sprintf("failed to decode admission review: %v", err), http.StatusBadRequest)
 		return
@@ -103,6 +179,11 @@ func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
+	if req := requestedAdmissionReview.Request; req != nil {
+		observation.Operation = string(req.Operation)
+		observation.Kind = req.Kind.Kind
+	}
+
 	// Handle the request
 	if requestedAdmissionReview.Request == nil {
 		responseAdmissionReview.Response = &admissionv1.AdmissionResponse{
@@ -113,7 +194,16 @@ func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			},
 		}
 	} else {
-		responseAdmissionReview.Response = h.admit(requestedAdmissionReview)
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout(r, defaultAdmissionTimeout))
+		responseAdmissionReview.Response = h.safeAdmit(ctx, path, requestedAdmissionReview)
+		if responseAdmissionReview.Response == nil {
+			message := "admission function returned no response"
+			if err := ctx.Err(); err != nil {
+				message = fmt.Sprintf("admission function returned no response: %v", err)
+			}
+			responseAdmissionReview.Response = erroredResponse(message)
+		}
+		cancel()
 	}
 
 	// Set the UID
@@ -128,8 +218,11 @@ func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	klog.V(4).Infof("Sending admission response: %+v", responseAdmissionReview.Response)
 
+	observation.Result = admissionResult(responseAdmissionReview.Response)
+	observation.Patched = len(responseAdmissionReview.Response.Patch) > 0
+
 	// Write the response
-	respBytes, err := json.Marshal(responseAdmissionReview)
+	respBytes, err := encodeAdmissionReview(responseAdmissionReview)
 	if err != nil {
 		klog.Errorf("Failed to marshal admission response: %v", err)
 		http.Error(w, fmt.Sprintf("failed to marshal admission response: %v", err), http.StatusInternalServerError)
@@ -137,7 +230,97 @@ func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if _, err := w.Write(respBytes); err != nil {
+	n, err := w.Write(respBytes)
+	if err != nil {
 		klog.Errorf("Failed to write admission response: %v", err)
 	}
+	observation.ResponseSize = n
+}
+
+// decodeAdmissionReview decodes an admission.k8s.io/v1 or v1beta1 AdmissionReview.
+// v1beta1 reviews are converted to v1; the original APIVersion is preserved
+// so the response can be sent back in the same version.
+func decodeAdmissionReview(body []byte) (admissionv1.AdmissionReview, error) {
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(body, &typeMeta); err != nil {
+		return admissionv1.AdmissionReview{}, err
+	}
+
+	deserializer := codecs.UniversalDeserializer()
+	if typeMeta.APIVersion == admissionv1beta1.SchemeGroupVersion.String() {
+		review := admissionv1beta1.AdmissionReview{}
+		if _, _, err := deserializer.Decode(body, nil, &review); err != nil {
+			return admissionv1.AdmissionReview{}, err
+		}
+		return admissionv1.AdmissionReview{
+			TypeMeta: review.TypeMeta,
+			Request:  convertRequestFromV1beta1(review.Request),
+		}, nil
+	}
+
+	review := admissionv1.AdmissionReview{}
+	if _, _, err := deserializer.Decode(body, nil, &review); err != nil {
+		return admissionv1.AdmissionReview{}, err
+	}
+	return review, nil
+}
+
+// encodeAdmissionReview encodes the response review in the version set in its APIVersion.
+func encodeAdmissionReview(review admissionv1.AdmissionReview) ([]byte, error) {
+	if review.APIVersion == admissionv1beta1.SchemeGroupVersion.String() {
+		return json.Marshal(admissionv1beta1.AdmissionReview{
+			TypeMeta: review.TypeMeta,
+			Response: convertResponseToV1beta1(review.Response),
+		})
+	}
+	return json.Marshal(review)
+}
+
+// admissionResult classifies a response for metrics.
+func admissionResult(resp *admissionv1.AdmissionResponse) string {
+	if resp.Allowed {
+		return metrics.AdmissionResultAllowed
+	}
+	if resp.Result != nil && (resp.Result.Code >= http.StatusInternalServerError || resp.Result.Reason == metav1.StatusReasonInternalError) {
+		return metrics.AdmissionResultErrored
+	}
+	return metrics.AdmissionResultDenied
+}
+
+// hookPath returns the path used to identify the hook in logs and metrics.
+func (h *admissionHandler) hookPath(r *http.Request) string {
+	if h.path != "" {
+		return h.path
+	}
+	return r.URL.Path
+}
+
+// safeAdmit calls the admit function and converts a panic into an error response
+// so the API server receives a well-formed AdmissionReview instead of an EOF.
+func (h *admissionHandler) safeAdmit(ctx context.Context, path string, ar admissionv1.AdmissionReview) (resp *admissionv1.AdmissionResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			klog.Errorf("Recovered panic in admission handler %s: %v\n%s", path, r, debug.Stack())
+			metrics.RecordAdmissionPanic(path)
+			resp = erroredResponse(fmt.Sprintf("admission handler panicked: %v", r))
+		}
+	}()
+
+	return h.admit(ctx, ar)
+}
+
+// erroredResponse returns a response that rejects the request with an internal error.
+func erroredResponse(message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: message,
+			Reason:  metav1.StatusReasonInternalError,
+			Code:    http.StatusInternalServerError,
+		},
+	}
 }
@@ -2,16 +2,22 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/jimyag/auto-cert-webhook/internal/metrics"
 )
 
 func TestAdmissionHandler_ServeHTTP(t *testing.T) {
@@ -224,6 +230,352 @@ func TestAdmissionHandler_WithPatch(t *testing.T) {
 	}
 }
 
+func TestAdmissionHandler_Versions(t *testing.T) {
+	var received admissionv1.AdmissionReview
+	patchType := admissionv1.PatchTypeJSONPatch
+	handler := newAdmissionHandler(func(ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		received = ar
+		return &admissionv1.AdmissionResponse{
+			Allowed:   true,
+			Patch:     []byte(`[{"op":"add","path":"/metadata/labels/test","value":"true"}]`),
+			PatchType: &patchType,
+			Warnings:  []string{"deprecated"},
+		}
+	})
+
+	t.Run("v1", func(t *testing.T) {
+		review := createAdmissionReview("v1-uid", []byte(`{"metadata":{"name":"test"}}`))
+		body, _ := json.Marshal(review)
+
+		req := httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		var resp admissionv1.AdmissionReview
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if resp.APIVersion != admissionv1.SchemeGroupVersion.String() {
+			t.Errorf("Expected APIVersion %q, got %q", admissionv1.SchemeGroupVersion.String(), resp.APIVersion)
+		}
+		if resp.Response.UID != "v1-uid" {
+			t.Errorf("Expected UID %q, got %q", "v1-uid", resp.Response.UID)
+		}
+		if resp.Response.PatchType == nil || *resp.Response.PatchType != admissionv1.PatchTypeJSONPatch {
+			t.Error("Expected PatchType=JSONPatch")
+		}
+	})
+
+	t.Run("v1beta1", func(t *testing.T) {
+		review := admissionv1beta1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "admission.k8s.io/v1beta1",
+				Kind:       "AdmissionReview",
+			},
+			Request: &admissionv1beta1.AdmissionRequest{
+				UID:       "v1beta1-uid",
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+				Name:      "test-pod",
+				Namespace: "default",
+				Operation: admissionv1beta1.Update,
+				Object:    runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"test"}}`)},
+				OldObject: runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"old"}}`)},
+			},
+		}
+		body, _ := json.Marshal(review)
+
+		req := httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		// The admit function sees a v1 request
+		if received.Request == nil {
+			t.Fatal("Expected admit function to receive a request")
+		}
+		if received.Request.Operation != admissionv1.Update {
+			t.Errorf("Expected operation %q, got %q", admissionv1.Update, received.Request.Operation)
+		}
+		if received.Request.Namespace != "default" || received.Request.Name != "test-pod" {
+			t.Errorf("Unexpected request %s/%s", received.Request.Namespace, received.Request.Name)
+		}
+		if string(received.Request.OldObject.Raw) != `{"metadata":{"name":"old"}}` {
+			t.Errorf("Unexpected old object %s", received.Request.OldObject.Raw)
+		}
+
+		var resp admissionv1beta1.AdmissionReview
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if resp.APIVersion != "admission.k8s.io/v1beta1" {
+			t.Errorf("Expected APIVersion %q, got %q", "admission.k8s.io/v1beta1", resp.APIVersion)
+		}
+		if resp.Kind != "AdmissionReview" {
+			t.Errorf("Expected Kind %q, got %q", "AdmissionReview", resp.Kind)
+		}
+		if resp.Response == nil {
+			t.Fatal("Expected non-nil Response")
+		}
+		if resp.Response.UID != "v1beta1-uid" {
+			t.Errorf("Expected UID %q, got %q", "v1beta1-uid", resp.Response.UID)
+		}
+		if !resp.Response.Allowed {
+			t.Error("Expected Allowed=true")
+		}
+		if resp.Response.PatchType == nil || *resp.Response.PatchType != admissionv1beta1.PatchTypeJSONPatch {
+			t.Error("Expected PatchType=JSONPatch")
+		}
+		if len(resp.Response.Warnings) != 1 || resp.Response.Warnings[0] != "deprecated" {
+			t.Errorf("Expected warnings to be preserved, got %v", resp.Response.Warnings)
+		}
+	})
+}
+
+func TestAdmissionHandler_Context(t *testing.T) {
+	t.Run("deadline from timeout query parameter", func(t *testing.T) {
+		var deadline time.Time
+		var hasDeadline bool
+		handler := newAdmissionHandlerContext(func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+			deadline, hasDeadline = ctx.Deadline()
+			return &admissionv1.AdmissionResponse{Allowed: true}
+		})
+
+		review := createAdmissionReview("test-uid", nil)
+		body, _ := json.Marshal(review)
+
+		start := time.Now()
+		req := httptest.NewRequest(http.MethodPost, "/validate?timeout=5s", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if !hasDeadline {
+			t.Fatal("Expected context to have a deadline")
+		}
+		if remaining := deadline.Sub(start); remaining > 5*time.Second || remaining < 4*time.Second {
+			t.Errorf("Expected deadline within the 5s timeout minus margin, got %v", remaining)
+		}
+	})
+
+	t.Run("default deadline without timeout query parameter", func(t *testing.T) {
+		var deadline time.Time
+		handler := newAdmissionHandlerContext(func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+			deadline, _ = ctx.Deadline()
+			return &admissionv1.AdmissionResponse{Allowed: true}
+		})
+
+		review := createAdmissionReview("test-uid", nil)
+		body, _ := json.Marshal(review)
+
+		start := time.Now()
+		req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if remaining := deadline.Sub(start); remaining > defaultAdmissionTimeout || remaining < defaultAdmissionTimeout-time.Second {
+			t.Errorf("Expected default deadline, got %v", remaining)
+		}
+	})
+
+	t.Run("cancelled with request context", func(t *testing.T) {
+		handler := newAdmissionHandlerContext(func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+			<-ctx.Done()
+			return nil
+		})
+
+		review := createAdmissionReview("test-uid", nil)
+		body, _ := json.Marshal(review)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		var resp admissionv1.AdmissionReview
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if resp.Response.Allowed {
+			t.Error("Expected Allowed=false")
+		}
+		if resp.Response.UID != "test-uid" {
+			t.Errorf("Expected UID %q, got %q", "test-uid", resp.Response.UID)
+		}
+		if resp.Response.Result == nil || resp.Response.Result.Code != http.StatusInternalServerError {
+			t.Errorf("Expected internal error result, got %+v", resp.Response.Result)
+		}
+	})
+}
+
+func TestAdmissionHandler_Panic(t *testing.T) {
+	metrics.Register()
+
+	handler := newAdmissionHandler(func(ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		panic("boom")
+	})
+	handler.path = "/panic-test"
+
+	review := createAdmissionReview("test-uid", nil)
+	body, _ := json.Marshal(review)
+
+	req := httptest.NewRequest(http.MethodPost, "/panic-test", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var resp admissionv1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if resp.Response.Allowed {
+		t.Error("Expected Allowed=false")
+	}
+	if resp.Response.UID != "test-uid" {
+		t.Errorf("Expected UID %q, got %q", "test-uid", resp.Response.UID)
+	}
+	if resp.Response.Result == nil || !strings.Contains(resp.Response.Result.Message, "boom") {
+		t.Errorf("Expected panic message in result, got %+v", resp.Response.Result)
+	}
+	if resp.Response.Result.Code != http.StatusInternalServerError {
+		t.Errorf("Expected code %d, got %d", http.StatusInternalServerError, resp.Response.Result.Code)
+	}
+
+	metricsRec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(metricsRec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(metricsRec.Body.String(), `admission_webhook_panics_total{path="/panic-test"} 1`) {
+		t.Errorf("panics_total metric not found in output:\n%s", metricsRec.Body.String())
+	}
+}
+
+func TestAdmissionHandler_Metrics(t *testing.T) {
+	metrics.Register()
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	handler := newAdmissionHandler(func(ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		return &admissionv1.AdmissionResponse{
+			Allowed:   true,
+			Patch:     []byte(`[{"op":"add","path":"/metadata/labels/test","value":"true"}]`),
+			PatchType: &patchType,
+		}
+	})
+	handler.path = "/metrics-test"
+	handler.hookType = "Mutating"
+
+	review := createAdmissionReview("test-uid", []byte(`{"metadata":{"name":"test"}}`))
+	body, _ := json.Marshal(review)
+
+	req := httptest.NewRequest(http.MethodPost, "/metrics-test", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// A request that fails before decoding is recorded as errored
+	badReq := httptest.NewRequest(http.MethodPost, "/metrics-test", bytes.NewReader([]byte("{invalid json}")))
+	badReq.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), badReq)
+
+	metricsRec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(metricsRec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	output := metricsRec.Body.String()
+
+	for _, want := range []string{
+		`admission_webhook_requests_total{kind="Pod",operation="CREATE",patched="true",path="/metrics-test",result="allowed",type="Mutating"} 1`,
+		`admission_webhook_requests_total{kind="",operation="",patched="false",path="/metrics-test",result="errored",type="Mutating"} 1`,
+		`admission_webhook_request_duration_seconds_count{kind="Pod",operation="CREATE",path="/metrics-test",result="allowed",type="Mutating"} 1`,
+		`admission_webhook_response_size_bytes_count{path="/metrics-test",type="Mutating"} 2`,
+		`admission_webhook_requests_in_flight{path="/metrics-test",type="Mutating"} 0`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("metric %q not found in output", want)
+		}
+	}
+}
+
+func TestAdmissionResult(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *admissionv1.AdmissionResponse
+		want string
+	}{
+		{"allowed", &admissionv1.AdmissionResponse{Allowed: true}, metrics.AdmissionResultAllowed},
+		{"denied", &admissionv1.AdmissionResponse{Result: &metav1.Status{Code: http.StatusForbidden}}, metrics.AdmissionResultDenied},
+		{"denied without result", &admissionv1.AdmissionResponse{}, metrics.AdmissionResultDenied},
+		{"errored by code", &admissionv1.AdmissionResponse{Result: &metav1.Status{Code: http.StatusInternalServerError}}, metrics.AdmissionResultErrored},
+		{"errored by reason", erroredResponse("boom"), metrics.AdmissionResultErrored},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := admissionResult(tt.resp); got != tt.want {
+				t.Errorf("admissionResult() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdmissionHandler_AbortHandlerPanic(t *testing.T) {
+	handler := newAdmissionHandler(func(ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		panic(http.ErrAbortHandler)
+	})
+
+	review := createAdmissionReview("test-uid", nil)
+	body, _ := json.Marshal(review)
+
+	req := httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("Expected http.ErrAbortHandler to be re-panicked, got %v", r)
+		}
+	}()
+	handler.ServeHTTP(rec, req)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		query string
+		want  time.Duration
+	}{
+		{"", defaultAdmissionTimeout - admissionTimeoutMargin},
+		{"?timeout=30s", 30*time.Second - admissionTimeoutMargin},
+		{"?timeout=invalid", defaultAdmissionTimeout - admissionTimeoutMargin},
+		{"?timeout=-1s", defaultAdmissionTimeout - admissionTimeoutMargin},
+		{"?timeout=500ms", 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/validate"+tt.query, nil)
+		if got := requestTimeout(req, defaultAdmissionTimeout); got != tt.want {
+			t.Errorf("requestTimeout(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/convert", nil)
+	if got, want := requestTimeout(req, defaultConversionTimeout), defaultConversionTimeout-admissionTimeoutMargin; got != want {
+		t.Errorf("requestTimeout() for conversions = %v, want %v", got, want)
+	}
+}
+
 // Helper to create an AdmissionReview
 func createAdmissionReview(uid string, object []byte) admissionv1.AdmissionReview {
 	review := admissionv1.AdmissionReview{
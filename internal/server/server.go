@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"time"
 
@@ -18,23 +19,35 @@ import (
 // This is defined here to match the public API type signature.
 type AdmitFunc = func(ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse
 
+// AdmitContextFunc is the context-aware function signature for handling admission requests.
+// This is defined here to match the public API type signature.
+type AdmitContextFunc = func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse
+
+// Middleware wraps an AdmitContextFunc.
+// This is defined here to match the public API type signature.
+type Middleware = func(next AdmitContextFunc) AdmitContextFunc
+
 // Config holds server configuration.
 type Config struct {
 	Port        int
 	HealthzPath string
 	ReadyzPath  string
+
+	// Listener, if set, is used instead of listening on Port.
+	Listener net.Listener
 }
 
 // Server is the webhook HTTP server.
 type Server struct {
 	server       *http.Server
-	certProvider *certprovider.Provider
+	certProvider certprovider.Source
 	mux          *http.ServeMux
 	config       Config
+	middlewares  []Middleware
 }
 
 // New creates a new webhook server.
-func New(certProvider *certprovider.Provider, config Config) *Server {
+func New(certProvider certprovider.Source, config Config) *Server {
 	mux := http.NewServeMux()
 
 	s := &Server{
@@ -50,12 +63,41 @@ func New(certProvider *certprovider.Provider, config Config) *Server {
 	return s
 }
 
+// Use appends global middlewares applied to every hook registered afterwards.
+func (s *Server) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
 // RegisterHook registers a webhook handler at the given path.
-func (s *Server) RegisterHook(path string, hookType string, admit AdmitFunc) {
-	s.mux.Handle(path, newAdmissionHandler(admit))
+func (s *Server) RegisterHook(path string, hookType string, admit AdmitFunc, middlewares ...Middleware) {
+	s.RegisterHookContext(path, hookType, func(_ context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		return admit(ar)
+	}, middlewares...)
+}
+
+// RegisterHookContext registers a context-aware webhook handler at the given path.
+// Global middlewares run first, followed by the hook middlewares, each in the
+// order given; the first middleware is the outermost.
+func (s *Server) RegisterHookContext(path string, hookType string, admit AdmitContextFunc, middlewares ...Middleware) {
+	all := make([]Middleware, 0, len(s.middlewares)+len(middlewares))
+	all = append(all, s.middlewares...)
+	all = append(all, middlewares...)
+
+	handler := newAdmissionHandlerContext(chain(admit, all))
+	handler.path = path
+	handler.hookType = hookType
+	s.mux.Handle(path, handler)
 	klog.V(2).Infof("Registered %s webhook at %s", hookType, path)
 }
 
+// chain composes middlewares around admit so that middlewares[0] is the outermost.
+func chain(admit AdmitContextFunc, middlewares []Middleware) AdmitContextFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		admit = middlewares[i](admit)
+	}
+	return admit
+}
+
 // Start starts the HTTPS server.
 func (s *Server) Start(ctx context.Context) error {
 	tlsConfig := &tls.Config{
@@ -75,8 +117,15 @@ func (s *Server) Start(ctx context.Context) error {
 
 	errChan := make(chan error, 1)
 	go func() {
-		klog.Infof("Starting webhook server on port %d", s.config.Port)
-		if err := s.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.config.Listener != nil {
+			klog.Infof("Starting webhook server on %s", s.config.Listener.Addr())
+			err = s.server.ServeTLS(s.config.Listener, "", "")
+		} else {
+			klog.Infof("Starting webhook server on port %d", s.config.Port)
+			err = s.server.ListenAndServeTLS("", "")
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
@@ -1,8 +1,12 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"sync/atomic"
 	"testing"
 
@@ -155,6 +159,78 @@ func TestServer_RegisterHook(t *testing.T) {
 	})
 }
 
+func TestServer_Middlewares(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next AdmitContextFunc) AdmitContextFunc {
+			return func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+				calls = append(calls, name+":before")
+				resp := next(ctx, ar)
+				calls = append(calls, name+":after")
+				return resp
+			}
+		}
+	}
+
+	server := &Server{mux: http.NewServeMux()}
+	server.Use(record("global1"), record("global2"))
+	server.RegisterHook("/validate", "Validating", func(ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		calls = append(calls, "handler")
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}, record("hook1"), record("hook2"))
+
+	review := createAdmissionReview("test-uid", nil)
+	body, _ := json.Marshal(review)
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	server.mux.ServeHTTP(rec, req)
+
+	want := []string{
+		"global1:before", "global2:before", "hook1:before", "hook2:before",
+		"handler",
+		"hook2:after", "hook1:after", "global2:after", "global1:after",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Middleware order:\n got %v\nwant %v", calls, want)
+	}
+}
+
+func TestServer_MiddlewareShortCircuit(t *testing.T) {
+	deny := func(next AdmitContextFunc) AdmitContextFunc {
+		return func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+			return &admissionv1.AdmissionResponse{Allowed: false}
+		}
+	}
+
+	called := false
+	server := &Server{mux: http.NewServeMux()}
+	server.RegisterHookContext("/validate", "Validating", func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		called = true
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}, deny)
+
+	review := createAdmissionReview("test-uid", nil)
+	body, _ := json.Marshal(review)
+	req := httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	server.mux.ServeHTTP(rec, req)
+
+	var resp admissionv1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if resp.Response.Allowed {
+		t.Error("Expected Allowed=false")
+	}
+	if called {
+		t.Error("Expected handler not to be called")
+	}
+}
+
 func TestServer_HealthEndpointsRegistered(t *testing.T) {
 	provider := &mockCertProvider{}
 	provider.ready.Store(true)
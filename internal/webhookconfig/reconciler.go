@@ -0,0 +1,528 @@
+package webhookconfig
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/jimyag/auto-cert-webhook/internal/cabundle"
+)
+
+const (
+	// ManagedByLabel is set on webhook configurations created by the reconciler.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedByValue is the value of ManagedByLabel.
+	ManagedByValue = "auto-cert-webhook"
+
+	// defaultServicePort matches the API server default for service references.
+	defaultServicePort int32 = 443
+	// defaultTimeoutSeconds matches the API server default for webhook timeouts.
+	defaultTimeoutSeconds int32 = 10
+
+	// defaultResyncPeriod is the interval at which the configurations are
+	// reconciled again.
+	defaultResyncPeriod = 10 * time.Minute
+
+	// reconcileBaseDelay and reconcileMaxDelay bound the exponential backoff
+	// of failed reconciles.
+	reconcileBaseDelay = 500 * time.Millisecond
+	reconcileMaxDelay  = 5 * time.Minute
+)
+
+// Webhook describes a single entry in a webhook configuration.
+type Webhook struct {
+	// Name is the fully-qualified webhook name, e.g. "mutate-pods.my-webhook.default.svc".
+	Name string
+
+	// Path is the URL path of the webhook on the service.
+	Path string
+
+	// Rules describes what operations on what resources the webhook cares about.
+	Rules []admissionregistrationv1.RuleWithOperations
+
+	// NamespaceSelector decides whether to run the webhook based on the object's namespace.
+	NamespaceSelector *metav1.LabelSelector
+
+	// ObjectSelector decides whether to run the webhook based on the object's labels.
+	ObjectSelector *metav1.LabelSelector
+
+	// FailurePolicy defines how errors from the webhook are handled.
+	FailurePolicy *admissionregistrationv1.FailurePolicyType
+
+	// SideEffects states whether the webhook has side effects.
+	SideEffects *admissionregistrationv1.SideEffectClass
+
+	// TimeoutSeconds specifies the timeout for the webhook call.
+	TimeoutSeconds *int32
+
+	// MatchPolicy defines how the Rules list is used to match incoming requests.
+	MatchPolicy *admissionregistrationv1.MatchPolicyType
+
+	// ReinvocationPolicy is only used for mutating webhooks.
+	ReinvocationPolicy *admissionregistrationv1.ReinvocationPolicyType
+
+	// AdmissionReviewVersions are the AdmissionReview versions the webhook
+	// accepts, in order of preference. Defaults to v1.
+	AdmissionReviewVersions []string
+}
+
+// Config holds the webhook configuration reconciler configuration.
+type Config struct {
+	// Name is the name of the Mutating and Validating webhook configurations.
+	Name string
+
+	// Namespace is the namespace of the webhook service and CA bundle configmap.
+	Namespace string
+
+	// ServiceName is the name of the webhook service.
+	ServiceName string
+
+	// ServicePort is the port of the webhook service.
+	ServicePort int32
+
+	// CABundleConfigMapName is the name of the CA bundle configmap.
+	CABundleConfigMapName string
+
+	// CABundleSecretName is the name of a secret holding the CA bundle in its
+	// ca.crt key, e.g. one issued by cert-manager. When set, it is used instead
+	// of CABundleConfigMapName.
+	CABundleSecretName string
+
+	// MutatingWebhooks are the entries of the MutatingWebhookConfiguration.
+	// The configuration is not managed when empty.
+	MutatingWebhooks []Webhook
+
+	// ValidatingWebhooks are the entries of the ValidatingWebhookConfiguration.
+	// The configuration is not managed when empty.
+	ValidatingWebhooks []Webhook
+}
+
+// Reconciler creates and reconciles webhook configurations from hook definitions.
+//
+// Reconciles run from a rate-limited work queue: failed reconciles are retried
+// with exponential backoff, and the configurations are reconciled again every
+// resync period.
+type Reconciler struct {
+	client   kubernetes.Interface
+	config   Config
+	caSource cabundle.Source
+
+	// queue holds the name of the configurations to reconcile, created by Start.
+	queue workqueue.TypedRateLimitingInterface[string]
+	// resyncPeriod is the interval at which the configurations are reconciled again.
+	resyncPeriod time.Duration
+}
+
+// NewReconciler creates a new webhook configuration reconciler.
+func NewReconciler(client kubernetes.Interface, config Config) *Reconciler {
+	if config.ServicePort == 0 {
+		config.ServicePort = defaultServicePort
+	}
+	caSource := cabundle.ConfigMapSource(config.CABundleConfigMapName)
+	if config.CABundleSecretName != "" {
+		caSource = cabundle.SecretSource(config.CABundleSecretName)
+	}
+	return &Reconciler{
+		client:       client,
+		config:       config,
+		caSource:     caSource,
+		resyncPeriod: defaultResyncPeriod,
+	}
+}
+
+// Start watches the CA bundle source and the webhook configurations and
+// reconciles them until the context is cancelled.
+func (r *Reconciler) Start(ctx context.Context) error {
+	r.queue = newReconcileQueue()
+	defer r.queue.ShutDown()
+
+	caFactory := informers.NewSharedInformerFactoryWithOptions(
+		r.client,
+		0,
+		informers.WithNamespace(r.config.Namespace),
+	)
+	caInformer := r.caSource.Informer(caFactory)
+
+	webhookFactory := informers.NewSharedInformerFactoryWithOptions(
+		r.client,
+		0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", r.config.Name).String()
+		}),
+	)
+	mutatingInformer := webhookFactory.Admissionregistration().V1().MutatingWebhookConfigurations().Informer()
+	validatingInformer := webhookFactory.Admissionregistration().V1().ValidatingWebhookConfigurations().Informer()
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			r.onEvent(obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			r.onEvent(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			r.onEvent(obj)
+		},
+	}
+
+	if _, err := caInformer.AddEventHandler(handler); err != nil {
+		return fmt.Errorf("failed to add CA bundle event handler: %w", err)
+	}
+	if len(r.config.MutatingWebhooks) > 0 {
+		if _, err := mutatingInformer.AddEventHandler(handler); err != nil {
+			return fmt.Errorf("failed to add mutating webhook configuration event handler: %w", err)
+		}
+	}
+	if len(r.config.ValidatingWebhooks) > 0 {
+		if _, err := validatingInformer.AddEventHandler(handler); err != nil {
+			return fmt.Errorf("failed to add validating webhook configuration event handler: %w", err)
+		}
+	}
+
+	caFactory.Start(ctx.Done())
+	webhookFactory.Start(ctx.Done())
+
+	synced := []cache.InformerSynced{caInformer.HasSynced}
+	if len(r.config.MutatingWebhooks) > 0 {
+		synced = append(synced, mutatingInformer.HasSynced)
+	}
+	if len(r.config.ValidatingWebhooks) > 0 {
+		synced = append(synced, validatingInformer.HasSynced)
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("failed to sync informer cache")
+	}
+
+	// Reconcile once even if none of the watched objects exist yet
+	r.enqueue()
+	go wait.UntilWithContext(ctx, r.runWorker, time.Second)
+	go wait.UntilWithContext(ctx, func(context.Context) { r.enqueue() }, r.resyncPeriod)
+
+	klog.Infof("Webhook configuration reconciler started for %s", r.config.Name)
+
+	<-ctx.Done()
+	return nil
+}
+
+// onEvent queues a reconcile when a watched object changes.
+func (r *Reconciler) onEvent(obj interface{}) {
+	if _, ok := r.caSource.FromObject(obj); ok {
+		r.enqueue()
+		return
+	}
+
+	switch o := obj.(type) {
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		if o.Name != r.config.Name {
+			return
+		}
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		if o.Name != r.config.Name {
+			return
+		}
+	default:
+		klog.Warningf("unexpected object type in webhook configuration reconciler: %T", obj)
+		return
+	}
+
+	r.enqueue()
+}
+
+// newReconcileQueue returns the work queue of the configurations to
+// reconcile, retrying failed reconciles with exponential backoff.
+func newReconcileQueue() workqueue.TypedRateLimitingInterface[string] {
+	return workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[string](reconcileBaseDelay, reconcileMaxDelay),
+		workqueue.TypedRateLimitingQueueConfig[string]{Name: "webhookconfig"},
+	)
+}
+
+// enqueue queues the webhook configurations for reconcile. Both are
+// reconciled together, so they share the configuration name as key.
+func (r *Reconciler) enqueue() {
+	r.queue.Add(r.config.Name)
+}
+
+// runWorker processes the queue until it is shut down.
+func (r *Reconciler) runWorker(ctx context.Context) {
+	for r.processNextWorkItem(ctx) {
+	}
+}
+
+// processNextWorkItem reconciles the webhook configurations and requeues them
+// with backoff if the reconcile failed. It returns false once the queue is
+// shut down.
+func (r *Reconciler) processNextWorkItem(ctx context.Context) bool {
+	name, shutdown := r.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer r.queue.Done(name)
+
+	if err := r.reconcile(ctx); err != nil {
+		klog.Errorf("Failed to reconcile webhook configuration %s, retrying: %v", name, err)
+		r.queue.AddRateLimited(name)
+		return true
+	}
+	r.queue.Forget(name)
+	return true
+}
+
+// reconcile ensures the webhook configurations match the desired state.
+func (r *Reconciler) reconcile(ctx context.Context) error {
+	caBundle, err := r.getCABundle(ctx)
+	if err != nil {
+		return err
+	}
+	if len(caBundle) == 0 {
+		klog.V(4).Infof("CA bundle %s in namespace %s not ready yet, skipping webhook configuration reconcile",
+			r.caSource, r.config.Namespace)
+		return nil
+	}
+
+	if len(r.config.MutatingWebhooks) > 0 {
+		if err := r.reconcileMutating(ctx, caBundle); err != nil {
+			return fmt.Errorf("failed to reconcile MutatingWebhookConfiguration %s: %w", r.config.Name, err)
+		}
+	}
+	if len(r.config.ValidatingWebhooks) > 0 {
+		if err := r.reconcileValidating(ctx, caBundle); err != nil {
+			return fmt.Errorf("failed to reconcile ValidatingWebhookConfiguration %s: %w", r.config.Name, err)
+		}
+	}
+	return nil
+}
+
+// getCABundle returns the CA bundle from its source, or nil if it does not exist yet.
+func (r *Reconciler) getCABundle(ctx context.Context) ([]byte, error) {
+	return r.caSource.Get(ctx, r.client, r.config.Namespace)
+}
+
+// webhookConfiguration is a MutatingWebhookConfiguration or ValidatingWebhookConfiguration.
+type webhookConfiguration interface {
+	*admissionregistrationv1.MutatingWebhookConfiguration | *admissionregistrationv1.ValidatingWebhookConfiguration
+	metav1.Object
+}
+
+// configurationClient is the part of the typed webhook configuration clients
+// used by reconcileConfiguration.
+type configurationClient[T webhookConfiguration] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Create(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error)
+	Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+}
+
+// reconcileMutating creates or updates the MutatingWebhookConfiguration.
+func (r *Reconciler) reconcileMutating(ctx context.Context, caBundle []byte) error {
+	desired := r.desiredMutating(caBundle)
+	return reconcileConfiguration(ctx, "MutatingWebhookConfiguration", r.client.AdmissionregistrationV1().MutatingWebhookConfigurations(), desired,
+		func(current *admissionregistrationv1.MutatingWebhookConfiguration) *admissionregistrationv1.MutatingWebhookConfiguration {
+			updated := current.DeepCopy()
+			updated.Webhooks = desired.Webhooks
+			return updated
+		})
+}
+
+// reconcileValidating creates or updates the ValidatingWebhookConfiguration.
+func (r *Reconciler) reconcileValidating(ctx context.Context, caBundle []byte) error {
+	desired := r.desiredValidating(caBundle)
+	return reconcileConfiguration(ctx, "ValidatingWebhookConfiguration", r.client.AdmissionregistrationV1().ValidatingWebhookConfigurations(), desired,
+		func(current *admissionregistrationv1.ValidatingWebhookConfiguration) *admissionregistrationv1.ValidatingWebhookConfiguration {
+			updated := current.DeepCopy()
+			updated.Webhooks = desired.Webhooks
+			return updated
+		})
+}
+
+// reconcileConfiguration creates the desired webhook configuration of kind,
+// or updates the current one if its webhooks or labels differ. withWebhooks
+// returns a copy of the current configuration with the desired webhooks.
+func reconcileConfiguration[T webhookConfiguration](ctx context.Context, kind string, client configurationClient[T], desired T, withWebhooks func(current T) T) error {
+	current, err := client.Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		if _, err := client.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		klog.Infof("Created %s %s", kind, desired.GetName())
+		return nil
+	}
+
+	updated := withWebhooks(current)
+	updated.SetLabels(mergeLabels(current.GetLabels(), desired.GetLabels()))
+	if equality.Semantic.DeepEqual(current, updated) {
+		return nil
+	}
+
+	if _, err := client.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.Infof("Updated %s %s", kind, desired.GetName())
+	return nil
+}
+
+// desiredMutating builds the desired MutatingWebhookConfiguration.
+func (r *Reconciler) desiredMutating(caBundle []byte) *admissionregistrationv1.MutatingWebhookConfiguration {
+	webhooks := make([]admissionregistrationv1.MutatingWebhook, 0, len(r.config.MutatingWebhooks))
+	for _, wh := range r.config.MutatingWebhooks {
+		reinvocationPolicy := admissionregistrationv1.NeverReinvocationPolicy
+		if wh.ReinvocationPolicy != nil {
+			reinvocationPolicy = *wh.ReinvocationPolicy
+		}
+
+		webhooks = append(webhooks, admissionregistrationv1.MutatingWebhook{
+			Name:                    wh.Name,
+			ClientConfig:            r.clientConfig(wh.Path, caBundle),
+			Rules:                   defaultRules(wh.Rules),
+			FailurePolicy:           defaultFailurePolicy(wh.FailurePolicy),
+			MatchPolicy:             defaultMatchPolicy(wh.MatchPolicy),
+			NamespaceSelector:       defaultSelector(wh.NamespaceSelector),
+			ObjectSelector:          defaultSelector(wh.ObjectSelector),
+			SideEffects:             defaultSideEffects(wh.SideEffects),
+			TimeoutSeconds:          defaultTimeoutSecondsFor(wh.TimeoutSeconds),
+			AdmissionReviewVersions: defaultAdmissionReviewVersions(wh.AdmissionReviewVersions),
+			ReinvocationPolicy:      &reinvocationPolicy,
+		})
+	}
+
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   r.config.Name,
+			Labels: map[string]string{ManagedByLabel: ManagedByValue},
+		},
+		Webhooks: webhooks,
+	}
+}
+
+// desiredValidating builds the desired ValidatingWebhookConfiguration.
+func (r *Reconciler) desiredValidating(caBundle []byte) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	webhooks := make([]admissionregistrationv1.ValidatingWebhook, 0, len(r.config.ValidatingWebhooks))
+	for _, wh := range r.config.ValidatingWebhooks {
+		webhooks = append(webhooks, admissionregistrationv1.ValidatingWebhook{
+			Name:                    wh.Name,
+			ClientConfig:            r.clientConfig(wh.Path, caBundle),
+			Rules:                   defaultRules(wh.Rules),
+			FailurePolicy:           defaultFailurePolicy(wh.FailurePolicy),
+			MatchPolicy:             defaultMatchPolicy(wh.MatchPolicy),
+			NamespaceSelector:       defaultSelector(wh.NamespaceSelector),
+			ObjectSelector:          defaultSelector(wh.ObjectSelector),
+			SideEffects:             defaultSideEffects(wh.SideEffects),
+			TimeoutSeconds:          defaultTimeoutSecondsFor(wh.TimeoutSeconds),
+			AdmissionReviewVersions: defaultAdmissionReviewVersions(wh.AdmissionReviewVersions),
+		})
+	}
+
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   r.config.Name,
+			Labels: map[string]string{ManagedByLabel: ManagedByValue},
+		},
+		Webhooks: webhooks,
+	}
+}
+
+// clientConfig builds the service reference for a webhook path.
+func (r *Reconciler) clientConfig(path string, caBundle []byte) admissionregistrationv1.WebhookClientConfig {
+	port := r.config.ServicePort
+	return admissionregistrationv1.WebhookClientConfig{
+		Service: &admissionregistrationv1.ServiceReference{
+			Namespace: r.config.Namespace,
+			Name:      r.config.ServiceName,
+			Path:      &path,
+			Port:      &port,
+		},
+		CABundle: caBundle,
+	}
+}
+
+// The default* helpers mirror the API server defaulting so that the desired
+// state compares equal to the stored object and reconciles do not loop.
+
+func defaultRules(rules []admissionregistrationv1.RuleWithOperations) []admissionregistrationv1.RuleWithOperations {
+	defaulted := make([]admissionregistrationv1.RuleWithOperations, 0, len(rules))
+	for _, rule := range rules {
+		rule = *rule.DeepCopy()
+		if rule.Scope == nil {
+			scope := admissionregistrationv1.AllScopes
+			rule.Scope = &scope
+		}
+		defaulted = append(defaulted, rule)
+	}
+	return defaulted
+}
+
+func defaultFailurePolicy(policy *admissionregistrationv1.FailurePolicyType) *admissionregistrationv1.FailurePolicyType {
+	defaulted := admissionregistrationv1.Fail
+	if policy != nil {
+		defaulted = *policy
+	}
+	return &defaulted
+}
+
+func defaultMatchPolicy(policy *admissionregistrationv1.MatchPolicyType) *admissionregistrationv1.MatchPolicyType {
+	defaulted := admissionregistrationv1.Equivalent
+	if policy != nil {
+		defaulted = *policy
+	}
+	return &defaulted
+}
+
+func defaultAdmissionReviewVersions(versions []string) []string {
+	if len(versions) == 0 {
+		return []string{"v1"}
+	}
+	return slices.Clone(versions)
+}
+
+func defaultSelector(selector *metav1.LabelSelector) *metav1.LabelSelector {
+	if selector == nil {
+		return &metav1.LabelSelector{}
+	}
+	return selector.DeepCopy()
+}
+
+func defaultSideEffects(sideEffects *admissionregistrationv1.SideEffectClass) *admissionregistrationv1.SideEffectClass {
+	defaulted := admissionregistrationv1.SideEffectClassNone
+	if sideEffects != nil {
+		defaulted = *sideEffects
+	}
+	return &defaulted
+}
+
+func defaultTimeoutSecondsFor(timeoutSeconds *int32) *int32 {
+	defaulted := defaultTimeoutSeconds
+	if timeoutSeconds != nil {
+		defaulted = *timeoutSeconds
+	}
+	return &defaulted
+}
+
+// mergeLabels returns current with the desired labels applied.
+func mergeLabels(current, desired map[string]string) map[string]string {
+	merged := make(map[string]string, len(current)+len(desired))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range desired {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,300 @@
+package webhookconfig
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newCABundleConfigMap(data string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca-bundle",
+			Namespace: "test-ns",
+		},
+		Data: map[string]string{
+			"ca-bundle.crt": data,
+		},
+	}
+}
+
+func newTestConfig() Config {
+	failurePolicy := admissionregistrationv1.Ignore
+	return Config{
+		Name:                  "test-webhook",
+		Namespace:             "test-ns",
+		ServiceName:           "test-svc",
+		CABundleConfigMapName: "ca-bundle",
+		MutatingWebhooks: []Webhook{
+			{
+				Name: "mutate-pods.test-svc.test-ns.svc",
+				Path: "/mutate-pods",
+				Rules: []admissionregistrationv1.RuleWithOperations{
+					{
+						Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{""},
+							APIVersions: []string{"v1"},
+							Resources:   []string{"pods"},
+						},
+					},
+				},
+				FailurePolicy: &failurePolicy,
+			},
+		},
+		ValidatingWebhooks: []Webhook{
+			{
+				Name:                    "validate-pods.test-svc.test-ns.svc",
+				Path:                    "/validate-pods",
+				AdmissionReviewVersions: []string{"v1", "v1beta1"},
+				Rules: []admissionregistrationv1.RuleWithOperations{
+					{
+						Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{""},
+							APIVersions: []string{"v1"},
+							Resources:   []string{"pods"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestReconciler_reconcile_CreatesConfigurations(t *testing.T) {
+	client := fake.NewClientset(newCABundleConfigMap("test-ca-bundle-data"))
+	reconciler := NewReconciler(client, newTestConfig())
+
+	ctx := context.Background()
+	if err := reconciler.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	mutating, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get MutatingWebhookConfiguration: %v", err)
+	}
+	if mutating.Labels[ManagedByLabel] != ManagedByValue {
+		t.Errorf("Expected managed-by label, got %v", mutating.Labels)
+	}
+	if len(mutating.Webhooks) != 1 {
+		t.Fatalf("Expected 1 mutating webhook, got %d", len(mutating.Webhooks))
+	}
+
+	wh := mutating.Webhooks[0]
+	if wh.Name != "mutate-pods.test-svc.test-ns.svc" {
+		t.Errorf("Name: got %q", wh.Name)
+	}
+	if string(wh.ClientConfig.CABundle) != "test-ca-bundle-data" {
+		t.Errorf("CABundle: got %q, want %q", string(wh.ClientConfig.CABundle), "test-ca-bundle-data")
+	}
+	svc := wh.ClientConfig.Service
+	if svc == nil || svc.Name != "test-svc" || svc.Namespace != "test-ns" || *svc.Path != "/mutate-pods" || *svc.Port != 443 {
+		t.Errorf("Unexpected service reference: %+v", svc)
+	}
+	if *wh.FailurePolicy != admissionregistrationv1.Ignore {
+		t.Errorf("FailurePolicy: got %v, want %v", *wh.FailurePolicy, admissionregistrationv1.Ignore)
+	}
+	if *wh.SideEffects != admissionregistrationv1.SideEffectClassNone {
+		t.Errorf("SideEffects: got %v, want None", *wh.SideEffects)
+	}
+	if *wh.TimeoutSeconds != 10 {
+		t.Errorf("TimeoutSeconds: got %d, want 10", *wh.TimeoutSeconds)
+	}
+	if *wh.ReinvocationPolicy != admissionregistrationv1.NeverReinvocationPolicy {
+		t.Errorf("ReinvocationPolicy: got %v, want Never", *wh.ReinvocationPolicy)
+	}
+	if !slices.Equal(wh.AdmissionReviewVersions, []string{"v1"}) {
+		t.Errorf("AdmissionReviewVersions: got %v, want [v1]", wh.AdmissionReviewVersions)
+	}
+
+	validating, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get ValidatingWebhookConfiguration: %v", err)
+	}
+	if len(validating.Webhooks) != 1 {
+		t.Fatalf("Expected 1 validating webhook, got %d", len(validating.Webhooks))
+	}
+	if *validating.Webhooks[0].FailurePolicy != admissionregistrationv1.Fail {
+		t.Errorf("FailurePolicy: got %v, want Fail", *validating.Webhooks[0].FailurePolicy)
+	}
+	if got := validating.Webhooks[0].AdmissionReviewVersions; !slices.Equal(got, []string{"v1", "v1beta1"}) {
+		t.Errorf("AdmissionReviewVersions: got %v, want [v1 v1beta1]", got)
+	}
+}
+
+func TestReconciler_reconcile_NoCABundle(t *testing.T) {
+	client := fake.NewClientset()
+	reconciler := NewReconciler(client, newTestConfig())
+
+	ctx := context.Background()
+	if err := reconciler.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	list, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("Expected no configuration before the CA bundle exists, got %d", len(list.Items))
+	}
+}
+
+func TestReconciler_reconcile_Idempotent(t *testing.T) {
+	client := fake.NewClientset(newCABundleConfigMap("test-ca-bundle-data"))
+	reconciler := NewReconciler(client, newTestConfig())
+
+	ctx := context.Background()
+	if err := reconciler.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	client.ClearActions()
+	if err := reconciler.reconcile(ctx); err != nil {
+		t.Fatalf("second reconcile failed: %v", err)
+	}
+
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "update" || action.GetVerb() == "create" {
+			t.Errorf("Expected no writes on second reconcile, got %s %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+}
+
+func TestReconciler_reconcile_RepairsDrift(t *testing.T) {
+	client := fake.NewClientset(newCABundleConfigMap("test-ca-bundle-data"))
+	reconciler := NewReconciler(client, newTestConfig())
+
+	ctx := context.Background()
+	if err := reconciler.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	// Simulate someone editing the configuration
+	current, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	current.Labels["team"] = "platform"
+	current.Webhooks[0].ClientConfig.CABundle = nil
+	current.Webhooks[0].Rules = nil
+	if _, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+
+	if err := reconciler.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	updated, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	if string(updated.Webhooks[0].ClientConfig.CABundle) != "test-ca-bundle-data" {
+		t.Errorf("CABundle not repaired: got %q", string(updated.Webhooks[0].ClientConfig.CABundle))
+	}
+	if len(updated.Webhooks[0].Rules) != 1 {
+		t.Errorf("Rules not repaired: got %+v", updated.Webhooks[0].Rules)
+	}
+	if updated.Labels["team"] != "platform" {
+		t.Error("Expected unrelated labels to be preserved")
+	}
+}
+
+func TestReconciler_reconcile_OnlyManagedTypes(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ValidatingWebhooks = nil
+
+	client := fake.NewClientset(newCABundleConfigMap("test-ca-bundle-data"))
+	reconciler := NewReconciler(client, cfg)
+
+	ctx := context.Background()
+	if err := reconciler.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	if _, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{}); err == nil {
+		t.Error("Expected ValidatingWebhookConfiguration not to be created")
+	}
+	if _, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected MutatingWebhookConfiguration to be created: %v", err)
+	}
+}
+
+func TestReconciler_reconcile_CABundleSecret(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CABundleSecretName = "webhook-tls"
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "webhook-tls",
+			Namespace: "test-ns",
+		},
+		Data: map[string][]byte{
+			"ca.crt": []byte("secret-ca-bundle-data"),
+		},
+	}
+	client := fake.NewClientset(newCABundleConfigMap("configmap-ca-bundle-data"), secret)
+	reconciler := NewReconciler(client, cfg)
+
+	ctx := context.Background()
+	if err := reconciler.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	validating, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get ValidatingWebhookConfiguration: %v", err)
+	}
+	if got := string(validating.Webhooks[0].ClientConfig.CABundle); got != "secret-ca-bundle-data" {
+		t.Errorf("CABundle: got %q, want %q", got, "secret-ca-bundle-data")
+	}
+}
+
+func TestReconciler_processNextWorkItem_Retry(t *testing.T) {
+	client := fake.NewClientset(newCABundleConfigMap("test-ca-bundle-data"))
+	var failures int
+	client.PrependReactor("create", "mutatingwebhookconfigurations", func(clienttesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			return false, nil, nil
+		}
+		failures++
+		return true, nil, apierrors.NewServiceUnavailable("etcd leader changed")
+	})
+
+	reconciler := NewReconciler(client, newTestConfig())
+	reconciler.queue = newReconcileQueue()
+	t.Cleanup(reconciler.queue.ShutDown)
+
+	ctx := context.Background()
+	reconciler.enqueue()
+	reconciler.processNextWorkItem(ctx)
+	if got := reconciler.queue.NumRequeues("test-webhook"); got != 1 {
+		t.Fatalf("NumRequeues after a failure: got %d, want 1", got)
+	}
+
+	// The failed reconcile is retried after the backoff without a new event.
+	start := time.Now()
+	reconciler.processNextWorkItem(ctx)
+	if elapsed := time.Since(start); elapsed < reconcileBaseDelay/2 {
+		t.Errorf("Retry after %v, want a backoff of about %v", elapsed, reconcileBaseDelay)
+	}
+	if got := reconciler.queue.NumRequeues("test-webhook"); got != 0 {
+		t.Errorf("NumRequeues after a success: got %d, want 0", got)
+	}
+
+	if _, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "test-webhook", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the MutatingWebhookConfiguration after the retry: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package autocertwebhook
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/clock"
+)
+
+// RunOption customizes how Run and RunWithContext set up the webhook.
+type RunOption func(*runOptions)
+
+// runOptions holds the settings applied by RunOption.
+type runOptions struct {
+	client          kubernetes.Interface
+	dynamicClient   dynamic.Interface
+	restConfig      *rest.Config
+	clock           clock.WithTicker
+	listener        net.Listener
+	metricsListener net.Listener
+}
+
+// WithKubeClient makes the webhook use the given client instead of building
+// one from a rest config. It takes precedence over WithRestConfig.
+func WithKubeClient(client kubernetes.Interface) RunOption {
+	return func(o *runOptions) {
+		o.client = client
+	}
+}
+
+// WithDynamicClient makes the webhook use the given dynamic client for
+// cert-manager resources, CustomResourceDefinitions and APIServices instead of
+// building one from a rest config. Required with WithKubeClient when
+// CertSource is CertManager or CABundleCRDs or CABundleAPIServices are set,
+// unless WithRestConfig is also given.
+func WithDynamicClient(client dynamic.Interface) RunOption {
+	return func(o *runOptions) {
+		o.dynamicClient = client
+	}
+}
+
+// WithRestConfig makes the webhook build its client from the given rest config
+// instead of Config.Kubeconfig, KUBECONFIG or the in-cluster config.
+func WithRestConfig(restConfig *rest.Config) RunOption {
+	return func(o *runOptions) {
+		o.restConfig = restConfig
+	}
+}
+
+// WithClock sets the clock that drives the certificate sync loop, the polling
+// of certificate files and event timestamps, e.g. a fake clock in tests.
+// Defaults to the real clock.
+func WithClock(clk clock.WithTicker) RunOption {
+	return func(o *runOptions) {
+		o.clock = clk
+	}
+}
+
+// WithListener makes the webhook server serve on the given listener instead
+// of listening on Config.Port. The listener is closed when the server stops.
+func WithListener(listener net.Listener) RunOption {
+	return func(o *runOptions) {
+		o.listener = listener
+	}
+}
+
+// WithMetricsListener makes the metrics server serve on the given listener
+// instead of listening on Config.MetricsPort. The listener is closed when the
+// server stops.
+func WithMetricsListener(listener net.Listener) RunOption {
+	return func(o *runOptions) {
+		o.metricsListener = listener
+	}
+}
+
+// newRunOptions applies opts to an empty runOptions.
+func newRunOptions(opts []RunOption) *runOptions {
+	o := &runOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// kubeClient returns the Kubernetes client to use, building one if none was injected.
+func (o *runOptions) kubeClient(cfg Config) (kubernetes.Interface, error) {
+	if o.client != nil {
+		return o.client, nil
+	}
+
+	restConfig, err := o.restConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	return client, nil
+}
+
+// dynamicClientFor returns the dynamic client to use, building one if none was injected.
+// A client is only built from an injected rest config or when no Kubernetes
+// client was injected, so that it never talks to a different cluster.
+func (o *runOptions) dynamicClientFor(cfg Config) (dynamic.Interface, error) {
+	if o.dynamicClient != nil {
+		return o.dynamicClient, nil
+	}
+	if o.client != nil && o.restConfig == nil {
+		return nil, fmt.Errorf("a dynamic client is required for cert source %s or caBundle syncing of CustomResourceDefinitions and APIServices with WithKubeClient, use WithDynamicClient or WithRestConfig", CertSourceCertManager)
+	}
+
+	restConfig, err := o.restConfigFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return client, nil
+}
+
+// restConfigFor returns the injected rest config, or loads it for cfg.
+func (o *runOptions) restConfigFor(cfg Config) (*rest.Config, error) {
+	if o.restConfig != nil {
+		return o.restConfig, nil
+	}
+	return loadRestConfig(cfg.Kubeconfig)
+}
+
+// loadRestConfig returns the rest config from:
+// 1. the kubeconfig file at path (Config.Kubeconfig / ACW_KUBECONFIG), if set
+// 2. the KUBECONFIG environment variable, if set
+// 3. the in-cluster config
+func loadRestConfig(path string) (*rest.Config, error) {
+	if path != "" {
+		restConfig, err := clientcmd.BuildConfigFromFlags("", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig %s: %w", path, err)
+		}
+		return restConfig, nil
+	}
+
+	if os.Getenv(clientcmd.RecommendedConfigPathEnvVar) != "" {
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig from %s: %w", clientcmd.RecommendedConfigPathEnvVar, err)
+		}
+		return restConfig, nil
+	}
+
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
+	}
+	return restConfig, nil
+}
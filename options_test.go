@@ -0,0 +1,195 @@
+package autocertwebhook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: kind
+  context:
+    cluster: kind
+    user: kind
+current-context: kind
+users:
+- name: kind
+  user:
+    token: test-token
+`
+
+func writeTestKubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func TestLoadRestConfig(t *testing.T) {
+	t.Run("explicit kubeconfig", func(t *testing.T) {
+		t.Setenv("KUBECONFIG", "")
+
+		restConfig, err := loadRestConfig(writeTestKubeconfig(t))
+		if err != nil {
+			t.Fatalf("loadRestConfig failed: %v", err)
+		}
+		if restConfig.Host != "https://127.0.0.1:6443" {
+			t.Errorf("Host: got %q, want %q", restConfig.Host, "https://127.0.0.1:6443")
+		}
+		if restConfig.BearerToken != "test-token" {
+			t.Errorf("BearerToken: got %q, want %q", restConfig.BearerToken, "test-token")
+		}
+	})
+
+	t.Run("KUBECONFIG fallback", func(t *testing.T) {
+		t.Setenv("KUBECONFIG", writeTestKubeconfig(t))
+
+		restConfig, err := loadRestConfig("")
+		if err != nil {
+			t.Fatalf("loadRestConfig failed: %v", err)
+		}
+		if restConfig.Host != "https://127.0.0.1:6443" {
+			t.Errorf("Host: got %q, want %q", restConfig.Host, "https://127.0.0.1:6443")
+		}
+	})
+
+	t.Run("missing kubeconfig", func(t *testing.T) {
+		if _, err := loadRestConfig(filepath.Join(t.TempDir(), "missing")); err == nil {
+			t.Error("expected error for missing kubeconfig")
+		}
+	})
+
+	t.Run("in-cluster without service account", func(t *testing.T) {
+		t.Setenv("KUBECONFIG", "")
+		t.Setenv("KUBERNETES_SERVICE_HOST", "")
+		t.Setenv("KUBERNETES_SERVICE_PORT", "")
+
+		if _, err := loadRestConfig(""); err == nil {
+			t.Error("expected error outside of a cluster")
+		}
+	})
+}
+
+func TestApplyEnvConfig_Kubeconfig(t *testing.T) {
+	t.Setenv("ACW_KUBECONFIG", "/tmp/env-kubeconfig")
+
+	cfg := Config{}
+	if err := applyEnvConfig(&cfg); err != nil {
+		t.Fatalf("applyEnvConfig failed: %v", err)
+	}
+	if cfg.Kubeconfig != "/tmp/env-kubeconfig" {
+		t.Errorf("Kubeconfig: got %q, want %q", cfg.Kubeconfig, "/tmp/env-kubeconfig")
+	}
+
+	cfg = Config{Kubeconfig: "/tmp/code-kubeconfig"}
+	if err := applyEnvConfig(&cfg); err != nil {
+		t.Fatalf("applyEnvConfig failed: %v", err)
+	}
+	if cfg.Kubeconfig != "/tmp/code-kubeconfig" {
+		t.Errorf("Kubeconfig: got %q, want %q", cfg.Kubeconfig, "/tmp/code-kubeconfig")
+	}
+}
+
+func TestRunOptions_KubeClient(t *testing.T) {
+	t.Run("injected client", func(t *testing.T) {
+		injected := fake.NewSimpleClientset()
+		options := newRunOptions([]RunOption{
+			WithRestConfig(&rest.Config{Host: "https://ignored:6443"}),
+			WithKubeClient(injected),
+		})
+
+		client, err := options.kubeClient(Config{})
+		if err != nil {
+			t.Fatalf("kubeClient failed: %v", err)
+		}
+		if client != injected {
+			t.Error("expected the injected client to be returned")
+		}
+	})
+
+	t.Run("injected rest config", func(t *testing.T) {
+		t.Setenv("KUBECONFIG", "")
+		options := newRunOptions([]RunOption{WithRestConfig(&rest.Config{Host: "https://127.0.0.1:6443"})})
+
+		client, err := options.kubeClient(Config{Kubeconfig: filepath.Join(t.TempDir(), "missing")})
+		if err != nil {
+			t.Fatalf("kubeClient failed: %v", err)
+		}
+		if client == nil {
+			t.Error("expected a client")
+		}
+	})
+
+	t.Run("kubeconfig from config", func(t *testing.T) {
+		options := newRunOptions(nil)
+
+		client, err := options.kubeClient(Config{Kubeconfig: writeTestKubeconfig(t)})
+		if err != nil {
+			t.Fatalf("kubeClient failed: %v", err)
+		}
+		if client == nil {
+			t.Error("expected a client")
+		}
+	})
+
+	t.Run("nil option", func(t *testing.T) {
+		options := newRunOptions([]RunOption{nil})
+		if options.client != nil || options.restConfig != nil {
+			t.Error("expected empty options")
+		}
+	})
+}
+
+func TestRunOptions_DynamicClient(t *testing.T) {
+	t.Run("injected dynamic client", func(t *testing.T) {
+		injected := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+		options := newRunOptions([]RunOption{
+			WithKubeClient(fake.NewSimpleClientset()),
+			WithDynamicClient(injected),
+		})
+
+		client, err := options.dynamicClientFor(Config{})
+		if err != nil {
+			t.Fatalf("dynamicClientFor failed: %v", err)
+		}
+		if client != injected {
+			t.Error("expected the injected dynamic client to be returned")
+		}
+	})
+
+	t.Run("injected kube client only", func(t *testing.T) {
+		options := newRunOptions([]RunOption{WithKubeClient(fake.NewSimpleClientset())})
+
+		if _, err := options.dynamicClientFor(Config{Kubeconfig: writeTestKubeconfig(t)}); err == nil {
+			t.Error("expected error without a dynamic client or rest config")
+		}
+	})
+
+	t.Run("injected rest config", func(t *testing.T) {
+		options := newRunOptions([]RunOption{
+			WithKubeClient(fake.NewSimpleClientset()),
+			WithRestConfig(&rest.Config{Host: "https://127.0.0.1:6443"}),
+		})
+
+		client, err := options.dynamicClientFor(Config{})
+		if err != nil {
+			t.Fatalf("dynamicClientFor failed: %v", err)
+		}
+		if client == nil {
+			t.Error("expected a dynamic client")
+		}
+	})
+}
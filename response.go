@@ -4,32 +4,145 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/appscode/jsonpatch"
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
 )
 
+const (
+	// MaxWarningLength is the maximum length of a single warning in characters.
+	// The API server truncates longer warnings.
+	MaxWarningLength = 256
+
+	// MaxTotalWarningsSize is the maximum combined size of all warnings in bytes.
+	// The API server drops warnings beyond this size.
+	MaxTotalWarningsSize = 4 * 1024
+)
+
+// ResponseOption customizes an admission response built by the response helpers.
+// If an option returns an error, the error is logged and the option is
+// ignored; the allow or deny decision of the response is always kept.
+type ResponseOption func(resp *admissionv1.AdmissionResponse) error
+
+// WithWarnings adds warnings that are returned to the API client.
+// Warnings are adjusted to the limits of the API server instead of failing
+// the response: non-printable characters are replaced by spaces, warnings
+// longer than MaxWarningLength characters are truncated, and empty warnings
+// or warnings beyond MaxTotalWarningsSize bytes in total are dropped.
+func WithWarnings(warnings ...string) ResponseOption {
+	return func(resp *admissionv1.AdmissionResponse) error {
+		total := 0
+		for _, warning := range resp.Warnings {
+			total += len(warning)
+		}
+
+		for _, warning := range warnings {
+			sanitized := sanitizeWarning(warning)
+			switch {
+			case sanitized == "":
+				klog.Warningf("Dropping empty admission warning %q", warning)
+				continue
+			case total+len(sanitized) > MaxTotalWarningsSize:
+				klog.Warningf("Dropping admission warning %q, warnings exceed %d bytes in total", warning, MaxTotalWarningsSize)
+				continue
+			case sanitized != warning:
+				klog.Warningf("Admission warning %q adjusted to %q", warning, sanitized)
+			}
+			total += len(sanitized)
+			resp.Warnings = append(resp.Warnings, sanitized)
+		}
+		return nil
+	}
+}
+
+// WithAuditAnnotation adds an audit annotation to the response.
+// The API server records the annotation as "<webhook-name>/<key>", so the key
+// must be a valid label name without a prefix, e.g. "policy-version". An
+// invalid key is logged and the annotation is dropped.
+func WithAuditAnnotation(key, value string) ResponseOption {
+	return func(resp *admissionv1.AdmissionResponse) error {
+		if err := validateAuditAnnotationKey(key); err != nil {
+			return err
+		}
+		if resp.AuditAnnotations == nil {
+			resp.AuditAnnotations = make(map[string]string)
+		}
+		resp.AuditAnnotations[key] = value
+		return nil
+	}
+}
+
+// sanitizeWarning adjusts a single warning to the API server limits. It
+// returns "" if the warning has nothing printable.
+func sanitizeWarning(warning string) string {
+	warning = strings.ToValidUTF8(warning, string(utf8.RuneError))
+	warning = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, warning)
+	if strings.TrimSpace(warning) == "" {
+		return ""
+	}
+	if utf8.RuneCountInString(warning) > MaxWarningLength {
+		warning = string([]rune(warning)[:MaxWarningLength])
+	}
+	return warning
+}
+
+// validateAuditAnnotationKey validates key as the name part of a qualified
+// name, since the API server adds the webhook name as prefix.
+func validateAuditAnnotationKey(key string) error {
+	if strings.Contains(key, "/") {
+		return fmt.Errorf("invalid audit annotation key %q: must not contain '/'", key)
+	}
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("invalid audit annotation key %q: %s", key, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// applyOptions applies opts to resp. Options returning an error are logged
+// and skipped, so an invalid option never changes the admission decision.
+func applyOptions(resp *admissionv1.AdmissionResponse, opts []ResponseOption) *admissionv1.AdmissionResponse {
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(resp); err != nil {
+			klog.Errorf("Ignoring invalid admission response option: %v", err)
+		}
+	}
+	return resp
+}
+
 // Allowed returns an admission response that allows the request.
-func Allowed() *admissionv1.AdmissionResponse {
-	return &admissionv1.AdmissionResponse{
+func Allowed(opts ...ResponseOption) *admissionv1.AdmissionResponse {
+	return applyOptions(&admissionv1.AdmissionResponse{
 		Allowed: true,
-	}
+	}, opts)
 }
 
 // AllowedWithMessage returns an admission response that allows the request with a message.
-func AllowedWithMessage(message string) *admissionv1.AdmissionResponse {
-	return &admissionv1.AdmissionResponse{
+func AllowedWithMessage(message string, opts ...ResponseOption) *admissionv1.AdmissionResponse {
+	return applyOptions(&admissionv1.AdmissionResponse{
 		Allowed: true,
 		Result: &metav1.Status{
 			Message: message,
 		},
-	}
+	}, opts)
 }
 
 // Denied returns an admission response that denies the request.
-func Denied(message string) *admissionv1.AdmissionResponse {
-	return &admissionv1.AdmissionResponse{
+func Denied(message string, opts ...ResponseOption) *admissionv1.AdmissionResponse {
+	return applyOptions(&admissionv1.AdmissionResponse{
 		Allowed: false,
 		Result: &metav1.Status{
 			Status:  metav1.StatusFailure,
@@ -37,12 +150,12 @@ func Denied(message string) *admissionv1.AdmissionResponse {
 			Reason:  metav1.StatusReasonForbidden,
 			Code:    http.StatusForbidden,
 		},
-	}
+	}, opts)
 }
 
 // DeniedWithReason returns an admission response that denies the request with a specific reason.
-func DeniedWithReason(message string, reason metav1.StatusReason, code int32) *admissionv1.AdmissionResponse {
-	return &admissionv1.AdmissionResponse{
+func DeniedWithReason(message string, reason metav1.StatusReason, code int32, opts ...ResponseOption) *admissionv1.AdmissionResponse {
+	return applyOptions(&admissionv1.AdmissionResponse{
 		Allowed: false,
 		Result: &metav1.Status{
 			Status:  metav1.StatusFailure,
@@ -50,12 +163,12 @@ func DeniedWithReason(message string, reason metav1.StatusReason, code int32) *a
 			Reason:  reason,
 			Code:    code,
 		},
-	}
+	}, opts)
 }
 
 // Errored returns an admission response for an error.
-func Errored(err error) *admissionv1.AdmissionResponse {
-	return &admissionv1.AdmissionResponse{
+func Errored(err error, opts ...ResponseOption) *admissionv1.AdmissionResponse {
+	return applyOptions(&admissionv1.AdmissionResponse{
 		Allowed: false,
 		Result: &metav1.Status{
 			Status:  metav1.StatusFailure,
@@ -63,75 +176,61 @@ func Errored(err error) *admissionv1.AdmissionResponse {
 			Reason:  metav1.StatusReasonInternalError,
 			Code:    http.StatusInternalServerError,
 		},
-	}
+	}, opts)
 }
 
 // ErroredWithCode returns an admission response for an error with a specific code.
-func ErroredWithCode(err error, code int32) *admissionv1.AdmissionResponse {
-	return &admissionv1.AdmissionResponse{
+func ErroredWithCode(err error, code int32, opts ...ResponseOption) *admissionv1.AdmissionResponse {
+	return applyOptions(&admissionv1.AdmissionResponse{
 		Allowed: false,
 		Result: &metav1.Status{
 			Status:  metav1.StatusFailure,
 			Message: err.Error(),
 			Code:    code,
 		},
-	}
+	}, opts)
 }
 
 // PatchResponse creates a patch response from the original and modified objects.
-func PatchResponse(original, modified interface{}) *admissionv1.AdmissionResponse {
+func PatchResponse(original, modified interface{}, opts ...ResponseOption) *admissionv1.AdmissionResponse {
 	originalBytes, err := json.Marshal(original)
 	if err != nil {
-		return Errored(fmt.Errorf("failed to marshal original object: %w", err))
+		return Errored(fmt.Errorf("failed to marshal original object: %w", err), opts...)
 	}
 
 	modifiedBytes, err := json.Marshal(modified)
 	if err != nil {
-		return Errored(fmt.Errorf("failed to marshal modified object: %w", err))
+		return Errored(fmt.Errorf("failed to marshal modified object: %w", err), opts...)
 	}
 
-	return PatchResponseFromRaw(originalBytes, modifiedBytes)
+	return PatchResponseFromRaw(originalBytes, modifiedBytes, opts...)
 }
 
 // PatchResponseFromRaw creates a patch response from raw JSON bytes.
-func PatchResponseFromRaw(original, modified []byte) *admissionv1.AdmissionResponse {
+func PatchResponseFromRaw(original, modified []byte, opts ...ResponseOption) *admissionv1.AdmissionResponse {
 	patches, err := jsonpatch.CreatePatch(original, modified)
 	if err != nil {
-		return Errored(fmt.Errorf("failed to create patch: %w", err))
+		return Errored(fmt.Errorf("failed to create patch: %w", err), opts...)
 	}
 
-	if len(patches) == 0 {
-		return Allowed()
-	}
-
-	patchBytes, err := json.Marshal(patches)
-	if err != nil {
-		return Errored(fmt.Errorf("failed to marshal patch: %w", err))
-	}
-
-	patchType := admissionv1.PatchTypeJSONPatch
-	return &admissionv1.AdmissionResponse{
-		Allowed:   true,
-		Patch:     patchBytes,
-		PatchType: &patchType,
-	}
+	return PatchResponseFromPatches(patches, opts...)
 }
 
 // PatchResponseFromPatches creates a patch response from pre-built patches.
-func PatchResponseFromPatches(patches []jsonpatch.JsonPatchOperation) *admissionv1.AdmissionResponse {
+func PatchResponseFromPatches(patches []jsonpatch.JsonPatchOperation, opts ...ResponseOption) *admissionv1.AdmissionResponse {
 	if len(patches) == 0 {
-		return Allowed()
+		return Allowed(opts...)
 	}
 
 	patchBytes, err := json.Marshal(patches)
 	if err != nil {
-		return Errored(fmt.Errorf("failed to marshal patch: %w", err))
+		return Errored(fmt.Errorf("failed to marshal patch: %w", err), opts...)
 	}
 
 	patchType := admissionv1.PatchTypeJSONPatch
-	return &admissionv1.AdmissionResponse{
+	return applyOptions(&admissionv1.AdmissionResponse{
 		Allowed:   true,
 		Patch:     patchBytes,
 		PatchType: &patchType,
-	}
+	}, opts)
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/appscode/jsonpatch"
@@ -221,11 +223,14 @@ func TestPatchResponseFromRaw(t *testing.T) {
 		original := []byte(`{invalid}`)
 		modified := []byte(`{"name":"test"}`)
 
-		resp := PatchResponseFromRaw(original, modified)
+		resp := PatchResponseFromRaw(original, modified, WithWarnings("check the object"))
 
 		if resp.Allowed {
 			t.Error("PatchResponseFromRaw() with invalid JSON should return Allowed=false")
 		}
+		if len(resp.Warnings) != 1 || resp.Warnings[0] != "check the object" {
+			t.Errorf("Warnings: got %v, want [check the object]", resp.Warnings)
+		}
 	})
 }
 
@@ -282,11 +287,14 @@ func TestPatchResponse_UnmarshalableModified(t *testing.T) {
 	original := testObj{Name: "test"}
 	modified := make(chan int)
 
-	resp := PatchResponse(original, modified)
+	resp := PatchResponse(original, modified, WithAuditAnnotation("reason", "test"))
 
 	if resp.Allowed {
 		t.Error("PatchResponse() with unmarshalable modified should return Allowed=false")
 	}
+	if resp.AuditAnnotations["reason"] != "test" {
+		t.Errorf("AuditAnnotations: got %v, want reason=test", resp.AuditAnnotations)
+	}
 	if resp.Result == nil {
 		t.Fatal("Expected non-nil Result")
 	}
@@ -294,3 +302,106 @@ func TestPatchResponse_UnmarshalableModified(t *testing.T) {
 		t.Errorf("Expected code %d, got %d", http.StatusInternalServerError, resp.Result.Code)
 	}
 }
+
+func TestResponseOptions(t *testing.T) {
+	t.Run("warnings and audit annotations", func(t *testing.T) {
+		resp := Allowed(
+			WithWarnings("field spec.foo is deprecated", "use spec.bar instead"),
+			WithAuditAnnotation("policy-version", "v2"),
+		)
+
+		if !resp.Allowed {
+			t.Error("Allowed() should return Allowed=true")
+		}
+		if len(resp.Warnings) != 2 {
+			t.Fatalf("Warnings: got %d, want 2", len(resp.Warnings))
+		}
+		if resp.Warnings[1] != "use spec.bar instead" {
+			t.Errorf("Warnings[1]: got %q, want %q", resp.Warnings[1], "use spec.bar instead")
+		}
+		if resp.AuditAnnotations["policy-version"] != "v2" {
+			t.Errorf("AuditAnnotations: got %v", resp.AuditAnnotations)
+		}
+	})
+
+	t.Run("applies to every constructor", func(t *testing.T) {
+		opt := WithWarnings("deprecated")
+		patches := []jsonpatch.JsonPatchOperation{
+			{Operation: "add", Path: "/labels", Value: map[string]string{"key": "value"}},
+		}
+
+		responses := map[string]*admissionv1.AdmissionResponse{
+			"AllowedWithMessage":       AllowedWithMessage("ok", opt),
+			"Denied":                   Denied("no", opt),
+			"DeniedWithReason":         DeniedWithReason("no", metav1.StatusReasonBadRequest, http.StatusBadRequest, opt),
+			"Errored":                  Errored(errors.New("boom"), opt),
+			"ErroredWithCode":          ErroredWithCode(errors.New("boom"), http.StatusBadGateway, opt),
+			"PatchResponse":            PatchResponse(map[string]string{}, map[string]string{"a": "b"}, opt),
+			"PatchResponseFromRaw":     PatchResponseFromRaw([]byte(`{}`), []byte(`{}`), opt),
+			"PatchResponseFromPatches": PatchResponseFromPatches(patches, opt),
+		}
+		for name, resp := range responses {
+			if len(resp.Warnings) != 1 || resp.Warnings[0] != "deprecated" {
+				t.Errorf("%s: Warnings: got %v, want [deprecated]", name, resp.Warnings)
+			}
+		}
+	})
+
+	t.Run("invalid warnings are adjusted", func(t *testing.T) {
+		var manyWarnings []string
+		for i := 0; i <= MaxTotalWarningsSize/MaxWarningLength; i++ {
+			manyWarnings = append(manyWarnings, strings.Repeat("a", MaxWarningLength))
+		}
+
+		tests := []struct {
+			name     string
+			warnings []string
+			want     []string
+		}{
+			{"empty warning", []string{"", "kept"}, []string{"kept"}},
+			{"non-printable warning", []string{"line\nbreak"}, []string{"line break"}},
+			{"long warning", []string{strings.Repeat("ä", MaxWarningLength+1)}, []string{strings.Repeat("ä", MaxWarningLength)}},
+			{"warnings total size", manyWarnings, manyWarnings[:len(manyWarnings)-1]},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := Allowed(WithWarnings(tt.warnings...))
+				if !resp.Allowed {
+					t.Error("Expected Allowed=true")
+				}
+				if !reflect.DeepEqual(resp.Warnings, tt.want) {
+					t.Errorf("Warnings: got %q, want %q", resp.Warnings, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("invalid annotation keys are dropped", func(t *testing.T) {
+		for _, key := range []string{"Not Valid!", "", "example.com/policy", strings.Repeat("a", 64)} {
+			resp := Allowed(WithAuditAnnotation(key, "v"), WithAuditAnnotation("policy-version", "v2"))
+			if !resp.Allowed {
+				t.Errorf("%q: expected Allowed=true", key)
+			}
+			if want := map[string]string{"policy-version": "v2"}; !reflect.DeepEqual(resp.AuditAnnotations, want) {
+				t.Errorf("%q: AuditAnnotations: got %v, want %v", key, resp.AuditAnnotations, want)
+			}
+		}
+	})
+
+	t.Run("invalid option keeps the decision", func(t *testing.T) {
+		invalid := func(*admissionv1.AdmissionResponse) error { return errors.New("invalid") }
+		if resp := Allowed(invalid); !resp.Allowed || resp.Result != nil {
+			t.Errorf("Allowed: got %+v, want an allowed response", resp)
+		}
+		if resp := Denied("no", invalid); resp.Allowed || resp.Result.Code != http.StatusForbidden {
+			t.Errorf("Denied: got %+v, want a denied response", resp)
+		}
+	})
+
+	t.Run("nil option", func(t *testing.T) {
+		resp := Allowed(nil)
+		if !resp.Allowed {
+			t.Error("Allowed(nil) should return Allowed=true")
+		}
+	})
+}
@@ -3,23 +3,28 @@ package autocertwebhook
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"reflect"
+	"slices"
 	"strings"
 	"syscall"
 
 	"github.com/kelseyhightower/envconfig"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/jimyag/auto-cert-webhook/internal/cabundle"
 	"github.com/jimyag/auto-cert-webhook/internal/certmanager"
+	"github.com/jimyag/auto-cert-webhook/internal/certmanagerio"
 	"github.com/jimyag/auto-cert-webhook/internal/certprovider"
 	"github.com/jimyag/auto-cert-webhook/internal/leaderelection"
 	"github.com/jimyag/auto-cert-webhook/internal/metrics"
 	"github.com/jimyag/auto-cert-webhook/internal/server"
+	"github.com/jimyag/auto-cert-webhook/internal/webhookconfig"
 )
 
 const (
@@ -36,82 +41,63 @@ const (
 
 // Run starts the webhook server with the given Admission implementation.
 // This is the main entry point for using this library.
-func Run(admission Admission) error {
+func Run(admission Admission, opts ...RunOption) error {
 	// Setup signal handling
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	return RunWithContext(ctx, admission)
+	return RunWithContext(ctx, admission, opts...)
 }
 
 // RunWithContext starts the webhook server with the given context.
-func RunWithContext(ctx context.Context, admission Admission) error {
-	// Get user configuration
-	cfg := admission.Configure()
-	hooks := admission.Webhooks()
+func RunWithContext(ctx context.Context, admission Admission, opts ...RunOption) error {
+	options := newRunOptions(opts)
 
-	// Apply environment variables (priority: code > env > default)
-	if err := applyEnvConfig(&cfg); err != nil {
+	cfg, err := ResolveConfig(admission)
+	if err != nil {
 		return err
 	}
 
-	if cfg.Name == "" {
-		return fmt.Errorf("webhook name is required in Configure() or ACW_NAME environment variable")
-	}
-
+	hooks := admission.Webhooks()
 	if len(hooks) == 0 {
 		return fmt.Errorf("at least one webhook hook is required in Webhooks()")
 	}
 
-	// Validate hooks
-	seenPaths := make(map[string]int)
-	for i, hook := range hooks {
-		if hook.Path == "" {
-			return fmt.Errorf("hook[%d]: path is required", i)
-		}
-		if hook.Path[0] != '/' {
-			return fmt.Errorf("hook[%d]: path must start with '/'", i)
-		}
-		if prev, exists := seenPaths[hook.Path]; exists {
-			return fmt.Errorf("hook[%d]: path %q already defined by hook[%d]", i, hook.Path, prev)
-		}
-		seenPaths[hook.Path] = i
-		if hook.Admit == nil {
-			return fmt.Errorf("hook[%d]: admit function is required", i)
-		}
-		if hook.Type != Mutating && hook.Type != Validating {
-			return fmt.Errorf("hook[%d]: type must be Mutating or Validating", i)
-		}
-	}
-
-	// Apply defaults for any remaining unset values
-	applyDefaults(&cfg)
-
-	// Validate certificate durations
-	if err := validateCertDurations(&cfg); err != nil {
+	if err := validateHooks(hooks); err != nil {
 		return err
 	}
 
 	klog.Infof("Starting webhook %s in namespace %s", cfg.Name, cfg.Namespace)
 
 	// Create Kubernetes client
-	k8sCfg, err := rest.InClusterConfig()
+	client, err := options.kubeClient(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to get in-cluster config: %w", err)
+		return err
 	}
 
-	client, err := kubernetes.NewForConfig(k8sCfg)
-	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	// Determine webhook refs for CA bundle syncer
+	webhookRefs := append(determineWebhookRefs(cfg.Name, hooks), determineResourceRefs(cfg, hooks)...)
+
+	// Create dynamic client for cert-manager resources, CRDs and APIServices
+	var dynamicClient dynamic.Interface
+	if needsDynamicClient(cfg, webhookRefs) {
+		dynamicClient, err = options.dynamicClientFor(cfg)
+		if err != nil {
+			return err
+		}
 	}
 
-	errCh := make(chan error, 7) // Buffer for process-wide senders: certificate provider, server, metrics server, leader metrics observer, leader election, and leader-scoped components that only report non-cancellation errors.
+	errCh := make(chan error, 8) // Buffer for process-wide senders: certificate provider, server, metrics server, leader metrics observer, leader election, and leader-scoped components that only report non-cancellation errors.
 
-	// Determine webhook refs for CA bundle syncer
-	webhookRefs := determineWebhookRefs(cfg.Name, hooks)
+	// Determine webhook configurations managed from hook definitions
+	webhookConfig := determineWebhookConfig(cfg, hooks)
 
 	// Create certificate provider (runs on all pods)
-	certProvider := certprovider.New(client, cfg.Namespace, cfg.CertSecretName)
+	certProvider := newCertSource(client, cfg, options.clock)
+	observer, _ := admission.(CertificateObserver)
+	if observer != nil {
+		certProvider.OnReload(observer.OnServingCertReloaded)
+	}
 
 	// Start certificate provider in background
 	go func() {
@@ -123,11 +109,17 @@ func RunWithContext(ctx context.Context, admission Admission) error {
 		Port:        cfg.Port,
 		HealthzPath: cfg.HealthzPath,
 		ReadyzPath:  cfg.ReadyzPath,
+		Listener:    options.listener,
 	})
 
+	// Register global middlewares before the hooks they wrap
+	if provider, ok := admission.(MiddlewareProvider); ok {
+		srv.Use(toServerMiddlewares(provider.Middlewares())...)
+	}
+
 	// Register webhook handlers
 	for _, hook := range hooks {
-		srv.RegisterHook(hook.Path, string(hook.Type), hook.Admit)
+		registerHook(srv, hook)
 		klog.Infof("Registered %s webhook at path %s", hook.Type, hook.Path)
 	}
 
@@ -140,8 +132,9 @@ func RunWithContext(ctx context.Context, admission Admission) error {
 	metricsEnabled := cfg.MetricsEnabled == nil || *cfg.MetricsEnabled
 	if metricsEnabled {
 		metricsSrv := metrics.NewServer(metrics.ServerConfig{
-			Port: cfg.MetricsPort,
-			Path: cfg.MetricsPath,
+			Port:     cfg.MetricsPort,
+			Path:     cfg.MetricsPath,
+			Listener: options.metricsListener,
 		})
 		go func() {
 			reportAsyncError(ctx, errCh, "metrics server", metricsSrv.Start(ctx))
@@ -165,8 +158,10 @@ func RunWithContext(ctx context.Context, admission Admission) error {
 			}, leaderelection.Callbacks{
 				OnStartedLeading: func(leaderCtx context.Context) {
 					klog.Info("Became leader, starting certificate management")
-					certMgr, caBundleSyncer := newLeaderComponents(client, cfg, webhookRefs)
-					startCertManagement(leaderCtx, certMgr, caBundleSyncer, errCh)
+					certMgr, caBundleSyncer := newLeaderComponents(client, dynamicClient, cfg, webhookRefs, options.clock)
+					observeLeaderComponents(observer, certMgr, caBundleSyncer)
+					certificate := newLeaderCertificateReconciler(dynamicClient, cfg, options.clock)
+					startCertManagement(leaderCtx, certMgr, certificate, newLeaderCAFilePublisher(client, cfg, options.clock), caBundleSyncer, newWebhookConfigReconciler(client, webhookConfig), errCh)
 				},
 				OnStoppedLeading: func() {
 					klog.Info("Lost leadership")
@@ -177,8 +172,10 @@ func RunWithContext(ctx context.Context, admission Admission) error {
 		// Run without leader election (single replica mode)
 		klog.Info("Running without leader election")
 		setSingleReplicaLeaderMetrics(cfg)
-		certMgr, caBundleSyncer := newLeaderComponents(client, cfg, webhookRefs)
-		startCertManagement(ctx, certMgr, caBundleSyncer, errCh)
+		certMgr, caBundleSyncer := newLeaderComponents(client, dynamicClient, cfg, webhookRefs, options.clock)
+		observeLeaderComponents(observer, certMgr, caBundleSyncer)
+		certificate := newLeaderCertificateReconciler(dynamicClient, cfg, options.clock)
+		startCertManagement(ctx, certMgr, certificate, newLeaderCAFilePublisher(client, cfg, options.clock), caBundleSyncer, newWebhookConfigReconciler(client, webhookConfig), errCh)
 	}
 
 	// Wait for context cancellation or error
@@ -192,6 +189,45 @@ func RunWithContext(ctx context.Context, admission Admission) error {
 	}
 }
 
+// ResolveConfig returns the effective configuration for admission: the values
+// from Configure(), overridden by environment variables where unset, with
+// defaults applied and validated. RunWithContext uses the same configuration.
+func ResolveConfig(admission Admission) (Config, error) {
+	// Get user configuration
+	cfg := admission.Configure()
+
+	// Apply environment variables (priority: code > env > default)
+	if err := applyEnvConfig(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.Name == "" {
+		return Config{}, fmt.Errorf("webhook name is required in Configure() or ACW_NAME environment variable")
+	}
+
+	// Apply defaults for any remaining unset values
+	applyDefaults(&cfg)
+
+	// Validate certificate durations
+	if err := validateCertDurations(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := validateCertSource(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := validateKeySpecs(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := validateCertNames(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
 // applyEnvConfig applies configuration from environment variables using envconfig.
 // Priority: code > env > default (defaults are set via struct tags)
 func applyEnvConfig(cfg *Config) error {
@@ -295,27 +331,176 @@ func getNamespace() string {
 	return defaultNamespace
 }
 
-func startCertManagement(ctx context.Context, certMgr *certmanager.Manager, caBundleSyncer *cabundle.Syncer, errCh chan error) {
-	go func() {
-		reportAsyncError(ctx, errCh, "certificate manager", certMgr.Start(ctx))
-	}()
+func startCertManagement(ctx context.Context, certMgr *certmanager.Manager, certificate *certmanagerio.Reconciler, caFile *cabundle.FilePublisher, caBundleSyncer *cabundle.Syncer, reconciler *webhookconfig.Reconciler, errCh chan error) {
+	if certMgr != nil {
+		go func() {
+			reportAsyncError(ctx, errCh, "certificate manager", certMgr.Start(ctx))
+		}()
+	}
+
+	if caFile != nil {
+		go func() {
+			reportAsyncError(ctx, errCh, "CA file publisher", caFile.Start(ctx))
+		}()
+	}
+
+	if certificate != nil {
+		go func() {
+			reportAsyncError(ctx, errCh, "cert-manager certificate reconciler", certificate.Start(ctx))
+		}()
+	}
 
 	go func() {
 		reportAsyncError(ctx, errCh, "CA bundle syncer", caBundleSyncer.Start(ctx))
 	}()
+
+	if reconciler != nil {
+		go func() {
+			reportAsyncError(ctx, errCh, "webhook configuration reconciler", reconciler.Start(ctx))
+		}()
+	}
+}
+
+// newWebhookConfigReconciler returns a reconciler for the managed webhook
+// configurations, or nil if no hook defines rules.
+func newWebhookConfigReconciler(client kubernetes.Interface, webhookConfig webhookconfig.Config) *webhookconfig.Reconciler {
+	if len(webhookConfig.MutatingWebhooks) == 0 && len(webhookConfig.ValidatingWebhooks) == 0 {
+		return nil
+	}
+	return webhookconfig.NewReconciler(client, webhookConfig)
+}
+
+// newCertSource returns the serving certificate source selected by cfg.CertSource.
+// Certificates issued by cert-manager are read from the same Secret as
+// generated ones.
+func newCertSource(client kubernetes.Interface, cfg Config, clk clock.WithTicker) certprovider.Source {
+	if cfg.CertSource == CertSourceFile {
+		fileProvider := certprovider.NewFile(cfg.CertFile, cfg.KeyFile, cfg.CertPollInterval)
+		if clk != nil {
+			fileProvider.UseClock(clk)
+		}
+		return fileProvider
+	}
+	provider := certprovider.New(client, cfg.Namespace, cfg.CertSecretName)
+	if isExternalCA(cfg) {
+		provider.WatchCA(cfg.CASecretName)
+	}
+	return provider
+}
+
+// isExternalCA reports whether serving certificates are signed by a provided CA.
+func isExternalCA(cfg Config) bool {
+	return cfg.ExternalCA != nil && *cfg.ExternalCA
 }
 
-func newLeaderComponents(client kubernetes.Interface, cfg Config, webhookRefs []cabundle.WebhookRef) (*certmanager.Manager, *cabundle.Syncer) {
-	certMgr := certmanager.New(client, newLeaderCertManagerConfig(cfg))
+// newLeaderComponents returns the leader-scoped components. The certificate
+// manager is nil when certificates are not generated by the framework.
+func newLeaderComponents(client kubernetes.Interface, dynamicClient dynamic.Interface, cfg Config, webhookRefs []cabundle.WebhookRef, clk clock.WithTicker) (*certmanager.Manager, *cabundle.Syncer) {
+	var certMgr *certmanager.Manager
+	if cfg.CertSource != CertSourceFile && cfg.CertSource != CertSourceCertManager {
+		certMgrCfg := newLeaderCertManagerConfig(cfg)
+		certMgrCfg.Clock = clk
+		certMgr = certmanager.New(client, certMgrCfg)
+	}
 	syncerCfg := newLeaderSyncerConfig(cfg, webhookRefs)
-	caBundleSyncer := cabundle.NewSyncer(client, syncerCfg.Namespace, syncerCfg.CABundleConfigMapName, syncerCfg.WebhookRefs)
+	caBundleSource := cabundle.ConfigMapSource(syncerCfg.CABundleConfigMapName)
+	if syncerCfg.CABundleSecretName != "" {
+		caBundleSource = cabundle.SecretSource(syncerCfg.CABundleSecretName)
+	}
+	caBundleSyncer := cabundle.NewSyncerFromSource(client, syncerCfg.Namespace, caBundleSource, syncerCfg.WebhookRefs)
+	caBundleSyncer.MatchService(cfg.ServiceName)
+	if dynamicClient != nil {
+		caBundleSyncer.UseDynamicClient(dynamicClient)
+	}
 	return certMgr, caBundleSyncer
 }
 
+// observeLeaderComponents forwards the certificate lifecycle events of the
+// leader components to observer, if any.
+func observeLeaderComponents(observer CertificateObserver, certMgr *certmanager.Manager, caBundleSyncer *cabundle.Syncer) {
+	if observer == nil {
+		return
+	}
+	if certMgr != nil {
+		certMgr.OnCARotated(observer.OnCARotated)
+	}
+	caBundleSyncer.OnSynced(func(ref cabundle.WebhookRef, err error) {
+		observer.OnCABundleSynced(toWebhookRef(ref), err)
+	})
+}
+
+// toWebhookRef converts a CA bundle syncer reference to the public type.
+func toWebhookRef(ref cabundle.WebhookRef) WebhookRef {
+	var hookType HookType
+	switch ref.Type {
+	case cabundle.ValidatingWebhook:
+		hookType = Validating
+	case cabundle.MutatingWebhook:
+		hookType = Mutating
+	case cabundle.CRDConversionWebhook:
+		hookType = Conversion
+	}
+	return WebhookRef{Kind: ref.Type.Kind(), Name: ref.Name, Type: hookType, WebhookName: ref.WebhookName}
+}
+
+// newLeaderCertificateReconciler returns the reconciler for the cert-manager
+// Certificate, or nil unless certificates are issued by cert-manager.
+func newLeaderCertificateReconciler(client dynamic.Interface, cfg Config, clk clock.WithTicker) *certmanagerio.Reconciler {
+	if cfg.CertSource != CertSourceCertManager {
+		return nil
+	}
+	return certmanagerio.New(client, certmanagerio.Config{
+		Namespace:       cfg.Namespace,
+		ServiceName:     cfg.ServiceName,
+		ClusterDomain:   cfg.ClusterDomain,
+		ExtraDNSNames:   cfg.ExtraDNSNames,
+		IPAddresses:     cfg.IPAddresses,
+		CommonName:      cfg.CertCommonName,
+		Organizations:   cfg.CertOrganizations,
+		CertificateName: cfg.Name,
+		SecretName:      cfg.CertSecretName,
+		IssuerName:      cfg.CertManagerIssuerName,
+		IssuerKind:      cfg.CertManagerIssuerKind,
+		IssuerGroup:     cfg.CertManagerIssuerGroup,
+		Duration:        cfg.CertValidity,
+		RenewBefore:     cfg.CertValidity - cfg.CertRefresh,
+		Key:             certKeySpec(cfg),
+		SyncInterval:    cfg.CertSyncInterval,
+		Clock:           clk,
+	})
+}
+
+// newLeaderCAFilePublisher returns the publisher of CAFile to the CA bundle
+// configmap, or nil unless certificates are loaded from files with a CA file.
+func newLeaderCAFilePublisher(client kubernetes.Interface, cfg Config, clk clock.WithTicker) *cabundle.FilePublisher {
+	if cfg.CertSource != CertSourceFile || cfg.CAFile == "" {
+		return nil
+	}
+	publisher := cabundle.NewFilePublisher(client, cfg.Namespace, cfg.CABundleConfigMapName, cfg.CAFile, cfg.CertPollInterval)
+	if clk != nil {
+		publisher.UseClock(clk)
+	}
+	return publisher
+}
+
+// caBundleSecretName returns the name of the secret holding the CA bundle in
+// its ca.crt key, or "" when the CA bundle is read from the configmap.
+func caBundleSecretName(cfg Config) string {
+	if cfg.CertSource == CertSourceCertManager {
+		return cfg.CertSecretName
+	}
+	return ""
+}
+
 func newLeaderCertManagerConfig(cfg Config) certmanager.Config {
-	return certmanager.Config{
+	certMgrCfg := certmanager.Config{
 		Namespace:             cfg.Namespace,
 		ServiceName:           cfg.ServiceName,
+		ClusterDomain:         cfg.ClusterDomain,
+		ExtraDNSNames:         cfg.ExtraDNSNames,
+		IPAddresses:           cfg.IPAddresses,
+		CommonName:            cfg.CertCommonName,
+		Organizations:         cfg.CertOrganizations,
 		CASecretName:          cfg.CASecretName,
 		CertSecretName:        cfg.CertSecretName,
 		CABundleConfigMapName: cfg.CABundleConfigMapName,
@@ -324,12 +509,30 @@ func newLeaderCertManagerConfig(cfg Config) certmanager.Config {
 		CertValidity:          cfg.CertValidity,
 		CertRefresh:           cfg.CertRefresh,
 		SyncInterval:          cfg.CertSyncInterval,
+		CAKey:                 caKeySpec(cfg),
+		CertKey:               certKeySpec(cfg),
+		ExternalCA:            isExternalCA(cfg),
+		CABundleFullChain:     cfg.CABundleFullChain != nil && *cfg.CABundleFullChain,
 	}
+	if cfg.CertSource == CertSourceCSR {
+		certMgrCfg.SignerName = cfg.CSRSignerName
+		certMgrCfg.CSRAutoApprove = cfg.CSRAutoApprove != nil && *cfg.CSRAutoApprove
+	}
+	return certMgrCfg
+}
+
+func caKeySpec(cfg Config) certmanager.KeySpec {
+	return certmanager.KeySpec{Algorithm: certmanager.KeyAlgorithm(cfg.CAKeyAlgorithm), Size: cfg.CAKeySize}
+}
+
+func certKeySpec(cfg Config) certmanager.KeySpec {
+	return certmanager.KeySpec{Algorithm: certmanager.KeyAlgorithm(cfg.CertKeyAlgorithm), Size: cfg.CertKeySize}
 }
 
 type leaderSyncerConfig struct {
 	Namespace             string
 	CABundleConfigMapName string
+	CABundleSecretName    string
 	WebhookRefs           []cabundle.WebhookRef
 }
 
@@ -337,6 +540,7 @@ func newLeaderSyncerConfig(cfg Config, webhookRefs []cabundle.WebhookRef) leader
 	return leaderSyncerConfig{
 		Namespace:             cfg.Namespace,
 		CABundleConfigMapName: cfg.CABundleConfigMapName,
+		CABundleSecretName:    caBundleSecretName(cfg),
 		WebhookRefs:           append([]cabundle.WebhookRef(nil), webhookRefs...),
 	}
 }
@@ -381,16 +585,190 @@ func validateCertDurations(cfg *Config) error {
 	return nil
 }
 
-// determineWebhookRefs determines webhook references for CA bundle syncing.
+// validateCertSource validates the certificate source configuration.
+func validateCertSource(cfg *Config) error {
+	if isExternalCA(*cfg) && cfg.CertSource != "" && cfg.CertSource != CertSourceSelfSigned {
+		return fmt.Errorf("external CA requires cert source %s, got %s", CertSourceSelfSigned, cfg.CertSource)
+	}
+	if cfg.CABundleFullChain != nil && *cfg.CABundleFullChain && !isExternalCA(*cfg) {
+		return fmt.Errorf("CA bundle full chain requires an external CA")
+	}
+	if cfg.CAFile != "" && cfg.CertSource != CertSourceFile {
+		return fmt.Errorf("CA file requires cert source %s, got %s", CertSourceFile, cfg.CertSource)
+	}
+
+	switch cfg.CertSource {
+	case "", CertSourceSelfSigned:
+		return nil
+	case CertSourceFile:
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return fmt.Errorf("cert file and key file are required for cert source %s", CertSourceFile)
+		}
+		if cfg.CertPollInterval <= 0 {
+			return fmt.Errorf("cert poll interval must be positive, got %v", cfg.CertPollInterval)
+		}
+		return nil
+	case CertSourceCertManager:
+		if cfg.CertManagerIssuerName == "" && cfg.CertManagerIssuerKind != "" && cfg.CertManagerIssuerKind != certmanagerio.KindIssuer {
+			return fmt.Errorf("cert-manager issuer name is required for issuer kind %s", cfg.CertManagerIssuerKind)
+		}
+		return nil
+	case CertSourceCSR:
+		if cfg.CSRSignerName == "" {
+			return fmt.Errorf("CSR signer name is required for cert source %s", CertSourceCSR)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown cert source %q, must be %s, %s, %s or %s", cfg.CertSource, CertSourceSelfSigned, CertSourceFile, CertSourceCertManager, CertSourceCSR)
+	}
+}
+
+// validateKeySpecs validates the key algorithms and sizes of generated certificates.
+func validateKeySpecs(cfg *Config) error {
+	if err := caKeySpec(*cfg).Validate(); err != nil {
+		return fmt.Errorf("invalid CA key: %w", err)
+	}
+	if err := certKeySpec(*cfg).Validate(); err != nil {
+		return fmt.Errorf("invalid cert key: %w", err)
+	}
+	return nil
+}
+
+// validateCertNames validates the extra DNS names and IP addresses of the serving certificate.
+func validateCertNames(cfg *Config) error {
+	for _, name := range cfg.ExtraDNSNames {
+		if name == "" {
+			return fmt.Errorf("extra DNS names must not be empty")
+		}
+		if net.ParseIP(name) != nil {
+			return fmt.Errorf("extra DNS name %q is an IP address, use IP addresses instead", name)
+		}
+	}
+	for _, ip := range cfg.IPAddresses {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid IP address %q", ip)
+		}
+	}
+	return nil
+}
+
+// validateHooks validates the hook definitions returned by Webhooks().
+func validateHooks(hooks []Hook) error {
+	seenPaths := make(map[string]int)
+	for i, hook := range hooks {
+		if hook.Path == "" {
+			return fmt.Errorf("hook[%d]: path is required", i)
+		}
+		if hook.Path[0] != '/' {
+			return fmt.Errorf("hook[%d]: path must start with '/'", i)
+		}
+		if prev, exists := seenPaths[hook.Path]; exists {
+			return fmt.Errorf("hook[%d]: path %q already defined by hook[%d]", i, hook.Path, prev)
+		}
+		seenPaths[hook.Path] = i
+		if hook.Type == Conversion {
+			if err := validateConversionHook(hook); err != nil {
+				return fmt.Errorf("hook[%d]: %w", i, err)
+			}
+			continue
+		}
+		if hook.Admit == nil && hook.AdmitContext == nil {
+			return fmt.Errorf("hook[%d]: admit function is required", i)
+		}
+		if hook.Admit != nil && hook.AdmitContext != nil {
+			return fmt.Errorf("hook[%d]: only one of Admit or AdmitContext may be set", i)
+		}
+		if hook.Type != Mutating && hook.Type != Validating {
+			return fmt.Errorf("hook[%d]: type must be Mutating, Validating or Conversion", i)
+		}
+		if hook.Convert != nil || len(hook.CustomResourceDefinitions) > 0 {
+			return fmt.Errorf("hook[%d]: convert function and custom resource definitions are only supported for Conversion hooks", i)
+		}
+		if hook.ReinvocationPolicy != nil && hook.Type != Mutating {
+			return fmt.Errorf("hook[%d]: reinvocation policy is only supported for Mutating hooks", i)
+		}
+		for _, version := range hook.AdmissionReviewVersions {
+			if version != "v1" && version != "v1beta1" {
+				return fmt.Errorf("hook[%d]: unsupported admission review version %q", i, version)
+			}
+		}
+		if hook.ConfigurationName != "" && len(hook.Rules) > 0 {
+			return fmt.Errorf("hook[%d]: configuration name is not supported for hooks with rules", i)
+		}
+	}
+
+	// A managed webhook configuration owns all of its entries, so hooks of the
+	// same type must either all define rules or none of them.
+	for i, hook := range hooks {
+		if len(hook.Rules) == 0 && isManagedHookType(hooks, hook.Type) {
+			return fmt.Errorf("hook[%d]: rules are required because other %s hooks define rules", i, hook.Type)
+		}
+	}
+	return nil
+}
+
+// validateConversionHook validates the fields of a Conversion hook, which
+// only supports Convert and CustomResourceDefinitions.
+func validateConversionHook(hook Hook) error {
+	if hook.Convert == nil {
+		return fmt.Errorf("convert function is required for Conversion hooks")
+	}
+	if hook.Admit != nil || hook.AdmitContext != nil || len(hook.Middlewares) > 0 {
+		return fmt.Errorf("admit functions and middlewares are not supported for Conversion hooks")
+	}
+	if hook.ConfigurationName != "" || hook.WebhookName != "" || len(hook.Rules) > 0 {
+		return fmt.Errorf("webhook configuration fields are not supported for Conversion hooks")
+	}
+	return nil
+}
+
+// isManagedHookType reports whether any hook of the given type defines rules.
+func isManagedHookType(hooks []Hook, hookType HookType) bool {
+	for _, hook := range hooks {
+		if hook.Type == hookType && len(hook.Rules) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// registerHook registers the hook's handler on the server.
+func registerHook(srv *server.Server, hook Hook) {
+	if hook.Type == Conversion {
+		srv.RegisterConversion(hook.Path, hook.Convert)
+		return
+	}
+	middlewares := toServerMiddlewares(hook.Middlewares)
+	if hook.AdmitContext != nil {
+		srv.RegisterHookContext(hook.Path, string(hook.Type), hook.AdmitContext, middlewares...)
+		return
+	}
+	srv.RegisterHook(hook.Path, string(hook.Type), hook.Admit, middlewares...)
+}
+
+// toServerMiddlewares converts public middlewares to the server signature.
+func toServerMiddlewares(middlewares []Middleware) []server.Middleware {
+	converted := make([]server.Middleware, 0, len(middlewares))
+	for _, mw := range middlewares {
+		if mw == nil {
+			continue
+		}
+		converted = append(converted, func(next server.AdmitContextFunc) server.AdmitContextFunc {
+			return mw(next)
+		})
+	}
+	return converted
+}
+
+// determineWebhookRefs determines webhook references for CA bundle syncing:
+// the entries of the configurations targeted by hooks without rules.
 func determineWebhookRefs(name string, hooks []Hook) []cabundle.WebhookRef {
 	var refs []cabundle.WebhookRef
-	seen := make(map[HookType]bool)
-
 	for _, hook := range hooks {
-		if seen[hook.Type] {
+		// Managed configurations get their caBundle from the reconciler.
+		if len(hook.Rules) > 0 {
 			continue
 		}
-		seen[hook.Type] = true
 
 		var webhookType cabundle.WebhookType
 		switch hook.Type {
@@ -401,11 +779,109 @@ func determineWebhookRefs(name string, hooks []Hook) []cabundle.WebhookRef {
 		default:
 			continue
 		}
-		refs = append(refs, cabundle.WebhookRef{
-			Name: name,
-			Type: webhookType,
-		})
+		ref := cabundle.WebhookRef{
+			Name:        name,
+			Type:        webhookType,
+			WebhookName: hook.WebhookName,
+		}
+		if hook.ConfigurationName != "" {
+			ref.Name = hook.ConfigurationName
+		}
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+
+	// A reference to all entries of a configuration covers the named ones.
+	return slices.DeleteFunc(slices.Clone(refs), func(ref cabundle.WebhookRef) bool {
+		return ref.WebhookName != "" && slices.Contains(refs, cabundle.WebhookRef{Name: ref.Name, Type: ref.Type})
+	})
+}
+
+// determineResourceRefs determines the references of the CustomResourceDefinitions
+// and APIServices whose caBundle is synced: those in the configuration and
+// those converted by Conversion hooks.
+func determineResourceRefs(cfg Config, hooks []Hook) []cabundle.WebhookRef {
+	crds := slices.Clone(cfg.CABundleCRDs)
+	for _, hook := range hooks {
+		if hook.Type == Conversion {
+			crds = append(crds, hook.CustomResourceDefinitions...)
+		}
 	}
 
+	var refs []cabundle.WebhookRef
+	for _, name := range crds {
+		ref := cabundle.WebhookRef{Name: name, Type: cabundle.CRDConversionWebhook}
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	for _, name := range cfg.CABundleAPIServices {
+		refs = append(refs, cabundle.WebhookRef{Name: name, Type: cabundle.APIServiceWebhook})
+	}
 	return refs
 }
+
+// needsDynamicClient reports whether a dynamic client is required, for
+// cert-manager resources or CustomResourceDefinitions and APIServices.
+func needsDynamicClient(cfg Config, webhookRefs []cabundle.WebhookRef) bool {
+	return cfg.CertSource == CertSourceCertManager || slices.ContainsFunc(webhookRefs, func(ref cabundle.WebhookRef) bool {
+		return ref.Type == cabundle.CRDConversionWebhook || ref.Type == cabundle.APIServiceWebhook
+	})
+}
+
+// determineWebhookConfig builds the webhook configuration reconciler config
+// from the hooks that define rules.
+func determineWebhookConfig(cfg Config, hooks []Hook) webhookconfig.Config {
+	webhookConfig := webhookconfig.Config{
+		Name:                  cfg.Name,
+		Namespace:             cfg.Namespace,
+		ServiceName:           cfg.ServiceName,
+		ServicePort:           cfg.ServicePort,
+		CABundleConfigMapName: cfg.CABundleConfigMapName,
+		CABundleSecretName:    caBundleSecretName(cfg),
+	}
+
+	for _, hook := range hooks {
+		if len(hook.Rules) == 0 {
+			continue
+		}
+
+		webhook := webhookconfig.Webhook{
+			Name:                    hook.WebhookName,
+			Path:                    hook.Path,
+			Rules:                   hook.Rules,
+			NamespaceSelector:       hook.NamespaceSelector,
+			ObjectSelector:          hook.ObjectSelector,
+			FailurePolicy:           hook.FailurePolicy,
+			SideEffects:             hook.SideEffects,
+			TimeoutSeconds:          hook.TimeoutSeconds,
+			MatchPolicy:             hook.MatchPolicy,
+			ReinvocationPolicy:      hook.ReinvocationPolicy,
+			AdmissionReviewVersions: hook.AdmissionReviewVersions,
+		}
+		if webhook.Name == "" {
+			webhook.Name = defaultWebhookName(cfg, hook.Path)
+		}
+
+		switch hook.Type {
+		case Mutating:
+			webhookConfig.MutatingWebhooks = append(webhookConfig.MutatingWebhooks, webhook)
+		case Validating:
+			webhookConfig.ValidatingWebhooks = append(webhookConfig.ValidatingWebhooks, webhook)
+		}
+	}
+
+	return webhookConfig
+}
+
+// defaultWebhookName derives a fully-qualified webhook entry name from the hook path,
+// e.g. "/mutate-pods" becomes "mutate-pods.<ServiceName>.<Namespace>.svc".
+func defaultWebhookName(cfg Config, path string) string {
+	prefix := strings.ToLower(strings.Trim(path, "/"))
+	prefix = strings.NewReplacer("/", "-", "_", "-", ".", "-").Replace(prefix)
+	if prefix == "" {
+		prefix = "webhook"
+	}
+	return fmt.Sprintf("%s.%s.%s.svc", prefix, cfg.ServiceName, cfg.Namespace)
+}
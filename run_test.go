@@ -6,12 +6,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/jimyag/auto-cert-webhook/internal/cabundle"
+	"github.com/jimyag/auto-cert-webhook/internal/certmanager"
+	"github.com/jimyag/auto-cert-webhook/internal/certprovider"
 	"github.com/jimyag/auto-cert-webhook/internal/metrics"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -229,6 +235,53 @@ func TestApplyDefaults_DynamicValues(t *testing.T) {
 	})
 }
 
+type configOnlyAdmission struct {
+	cfg Config
+}
+
+func (a configOnlyAdmission) Configure() Config { return a.cfg }
+
+func (a configOnlyAdmission) Webhooks() []Hook { return nil }
+
+func TestResolveConfig(t *testing.T) {
+	t.Run("applies env and defaults", func(t *testing.T) {
+		t.Setenv("ACW_NAME", "env-webhook")
+
+		cfg, err := ResolveConfig(configOnlyAdmission{})
+		if err != nil {
+			t.Fatalf("ResolveConfig failed: %v", err)
+		}
+		if cfg.Name != "env-webhook" {
+			t.Errorf("Name: got %q, want %q", cfg.Name, "env-webhook")
+		}
+		if cfg.ServiceName != "env-webhook" {
+			t.Errorf("ServiceName: got %q, want %q", cfg.ServiceName, "env-webhook")
+		}
+		if cfg.Port != 8443 {
+			t.Errorf("Port: got %d, want %d", cfg.Port, 8443)
+		}
+	})
+
+	t.Run("name required", func(t *testing.T) {
+		t.Setenv("ACW_NAME", "")
+
+		if _, err := ResolveConfig(configOnlyAdmission{}); err == nil {
+			t.Error("expected error without a name")
+		}
+	})
+
+	t.Run("invalid durations", func(t *testing.T) {
+		_, err := ResolveConfig(configOnlyAdmission{cfg: Config{
+			Name:       "test",
+			CAValidity: time.Hour,
+			CARefresh:  2 * time.Hour,
+		}})
+		if err == nil {
+			t.Error("expected error for refresh longer than validity")
+		}
+	})
+}
+
 func TestGetNamespace(t *testing.T) {
 	defer func() {
 		os.Unsetenv("ACW_NAMESPACE")
@@ -270,7 +323,7 @@ func TestGetNamespace(t *testing.T) {
 func TestApplyEnvConfig_AllFieldTypes(t *testing.T) {
 	defer func() {
 		for _, key := range []string{
-			"ACW_NAME", "ACW_PORT", "ACW_CA_VALIDITY", "ACW_METRICS_ENABLED",
+			"ACW_NAME", "ACW_PORT", "ACW_CA_VALIDITY", "ACW_METRICS_ENABLED", "ACW_EXTRA_DNS_NAMES",
 		} {
 			os.Unsetenv(key)
 		}
@@ -319,6 +372,18 @@ func TestApplyEnvConfig_AllFieldTypes(t *testing.T) {
 			t.Errorf("MetricsEnabled: got %v, want false", cfg.MetricsEnabled)
 		}
 	})
+
+	t.Run("string slice field", func(t *testing.T) {
+		os.Setenv("ACW_EXTRA_DNS_NAMES", "webhook.example.com,webhook.example.org")
+		cfg := Config{}
+		if err := applyEnvConfig(&cfg); err != nil {
+			t.Fatalf("applyEnvConfig failed: %v", err)
+		}
+		want := []string{"webhook.example.com", "webhook.example.org"}
+		if !reflect.DeepEqual(cfg.ExtraDNSNames, want) {
+			t.Errorf("ExtraDNSNames: got %q, want %q", cfg.ExtraDNSNames, want)
+		}
+	})
 }
 
 func TestConfigPriority_Integration(t *testing.T) {
@@ -435,6 +500,326 @@ func TestDeepCopyConfig(t *testing.T) {
 	})
 }
 
+func TestValidateCertSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"default", Config{}, false},
+		{"self-signed", Config{CertSource: CertSourceSelfSigned}, false},
+		{"file", Config{CertSource: CertSourceFile, CertFile: "/tls/tls.crt", KeyFile: "/tls/tls.key", CertPollInterval: time.Second}, false},
+		{"file without key", Config{CertSource: CertSourceFile, CertFile: "/tls/tls.crt", CertPollInterval: time.Second}, true},
+		{"file without poll interval", Config{CertSource: CertSourceFile, CertFile: "/tls/tls.crt", KeyFile: "/tls/tls.key"}, true},
+		{"file with CA file", Config{CertSource: CertSourceFile, CertFile: "/tls/tls.crt", KeyFile: "/tls/tls.key", CAFile: "/tls/ca.crt", CertPollInterval: time.Second}, false},
+		{"CA file without file", Config{CertSource: CertSourceSelfSigned, CAFile: "/tls/ca.crt"}, true},
+		{"cert-manager", Config{CertSource: CertSourceCertManager}, false},
+		{"cert-manager with cluster issuer", Config{CertSource: CertSourceCertManager, CertManagerIssuerName: "ca", CertManagerIssuerKind: "ClusterIssuer"}, false},
+		{"cert-manager cluster issuer without name", Config{CertSource: CertSourceCertManager, CertManagerIssuerKind: "ClusterIssuer"}, true},
+		{"csr", Config{CertSource: CertSourceCSR, CSRSignerName: "example.com/webhook-serving"}, false},
+		{"csr without signer name", Config{CertSource: CertSourceCSR}, true},
+		{"unknown", Config{CertSource: "Vault"}, true},
+		{"external CA", Config{ExternalCA: ptr(true), CABundleFullChain: ptr(true)}, false},
+		{"external CA with file", Config{ExternalCA: ptr(true), CertSource: CertSourceFile, CertFile: "/tls/tls.crt", KeyFile: "/tls/tls.key"}, true},
+		{"full chain without external CA", Config{CABundleFullChain: ptr(true)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCertSource(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCertSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateKeySpecs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"default", Config{}, false},
+		{"rsa 4096", Config{CAKeyAlgorithm: KeyAlgorithmRSA, CAKeySize: 4096}, false},
+		{"ecdsa p-256", Config{CAKeyAlgorithm: KeyAlgorithmECDSA, CertKeyAlgorithm: KeyAlgorithmECDSA}, false},
+		{"ed25519", Config{CAKeyAlgorithm: KeyAlgorithmEd25519, CertKeyAlgorithm: KeyAlgorithmEd25519}, false},
+		{"rsa too small", Config{CAKeyAlgorithm: KeyAlgorithmRSA, CAKeySize: 1024}, true},
+		{"ecdsa unknown curve", Config{CertKeyAlgorithm: KeyAlgorithmECDSA, CertKeySize: 224}, true},
+		{"ed25519 with size", Config{CertKeyAlgorithm: KeyAlgorithmEd25519, CertKeySize: 256}, true},
+		{"unknown", Config{CAKeyAlgorithm: "DSA"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKeySpecs(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateKeySpecs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCertNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"default", Config{}, false},
+		{"extra names", Config{ExtraDNSNames: []string{"webhook.example.com"}, IPAddresses: []string{"10.0.0.1", "fd00::1"}}, false},
+		{"empty dns name", Config{ExtraDNSNames: []string{""}}, true},
+		{"ip as dns name", Config{ExtraDNSNames: []string{"10.0.0.1"}}, true},
+		{"invalid ip", Config{IPAddresses: []string{"webhook.example.com"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCertNames(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCertNames() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewCertSource(t *testing.T) {
+	client := fake.NewClientset()
+
+	if _, ok := newCertSource(client, Config{Namespace: "default", CertSecretName: "test-cert"}, nil).(*certprovider.Provider); !ok {
+		t.Error("expected secret certificate provider by default")
+	}
+
+	if provider, ok := newCertSource(client, Config{Namespace: "default", CertSecretName: "test-cert", ExternalCA: ptr(true)}, nil).(*certprovider.Provider); !ok || provider.Ready() {
+		t.Error("expected secret certificate provider that is not ready before the external CA is loaded")
+	}
+
+	cfg := Config{CertSource: CertSourceFile, CertFile: "/tls/tls.crt", KeyFile: "/tls/tls.key"}
+	if _, ok := newCertSource(client, cfg, nil).(*certprovider.FileProvider); !ok {
+		t.Error("expected file certificate provider for cert source File")
+	}
+
+	certMgr, syncer := newLeaderComponents(client, nil, cfg, nil, nil)
+	if certMgr != nil {
+		t.Error("expected no certificate manager for cert source File")
+	}
+	if syncer == nil {
+		t.Error("expected CA bundle syncer for cert source File")
+	}
+	if newLeaderCertificateReconciler(nil, cfg, nil) != nil {
+		t.Error("expected no cert-manager certificate reconciler for cert source File")
+	}
+	if newLeaderCAFilePublisher(client, cfg, nil) != nil {
+		t.Error("expected no CA file publisher without CA file")
+	}
+
+	cfg.CAFile = "/tls/ca.crt"
+	if newLeaderCAFilePublisher(client, cfg, nil) == nil {
+		t.Error("expected CA file publisher for CA file")
+	}
+}
+
+func TestNewCertSource_CertManager(t *testing.T) {
+	client := fake.NewClientset()
+	cfg := Config{
+		Name:           "test",
+		Namespace:      "default",
+		CertSource:     CertSourceCertManager,
+		CertSecretName: "test-cert",
+		CertValidity:   24 * time.Hour,
+		CertRefresh:    12 * time.Hour,
+	}
+
+	if _, ok := newCertSource(client, cfg, nil).(*certprovider.Provider); !ok {
+		t.Error("expected secret certificate provider for cert source CertManager")
+	}
+
+	certMgr, syncer := newLeaderComponents(client, nil, cfg, nil, nil)
+	if certMgr != nil {
+		t.Error("expected no certificate manager for cert source CertManager")
+	}
+	if syncer == nil {
+		t.Error("expected CA bundle syncer for cert source CertManager")
+	}
+	if newLeaderCertificateReconciler(nil, cfg, nil) == nil {
+		t.Error("expected cert-manager certificate reconciler for cert source CertManager")
+	}
+
+	if got := newLeaderSyncerConfig(cfg, nil).CABundleSecretName; got != "test-cert" {
+		t.Errorf("syncer CABundleSecretName: got %q, want %q", got, "test-cert")
+	}
+	if got := determineWebhookConfig(cfg, nil).CABundleSecretName; got != "test-cert" {
+		t.Errorf("webhook config CABundleSecretName: got %q, want %q", got, "test-cert")
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	admit := func(ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse { return Allowed() }
+	admitContext := func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		return Allowed()
+	}
+	convert := func(ctx context.Context, obj *unstructured.Unstructured, desiredAPIVersion string) (*unstructured.Unstructured, error) {
+		return obj, nil
+	}
+
+	tests := []struct {
+		name    string
+		hooks   []Hook
+		wantErr string
+	}{
+		{
+			name:  "admit",
+			hooks: []Hook{{Path: "/mutate", Type: Mutating, Admit: admit}},
+		},
+		{
+			name:  "admit context",
+			hooks: []Hook{{Path: "/validate", Type: Validating, AdmitContext: admitContext}},
+		},
+		{
+			name:    "missing path",
+			hooks:   []Hook{{Type: Mutating, Admit: admit}},
+			wantErr: "path is required",
+		},
+		{
+			name:    "relative path",
+			hooks:   []Hook{{Path: "mutate", Type: Mutating, Admit: admit}},
+			wantErr: "path must start with '/'",
+		},
+		{
+			name: "duplicate path",
+			hooks: []Hook{
+				{Path: "/mutate", Type: Mutating, Admit: admit},
+				{Path: "/mutate", Type: Mutating, Admit: admit},
+			},
+			wantErr: "already defined",
+		},
+		{
+			name:    "missing admit function",
+			hooks:   []Hook{{Path: "/mutate", Type: Mutating}},
+			wantErr: "admit function is required",
+		},
+		{
+			name:    "both admit functions",
+			hooks:   []Hook{{Path: "/mutate", Type: Mutating, Admit: admit, AdmitContext: admitContext}},
+			wantErr: "only one of Admit or AdmitContext",
+		},
+		{
+			name:  "conversion",
+			hooks: []Hook{{Path: "/convert", Type: Conversion, Convert: convert, CustomResourceDefinitions: []string{"widgets.example.com"}}},
+		},
+		{
+			name:    "conversion without convert function",
+			hooks:   []Hook{{Path: "/convert", Type: Conversion}},
+			wantErr: "convert function is required",
+		},
+		{
+			name:    "conversion with admit function",
+			hooks:   []Hook{{Path: "/convert", Type: Conversion, Convert: convert, Admit: admit}},
+			wantErr: "not supported for Conversion hooks",
+		},
+		{
+			name:    "conversion with webhook name",
+			hooks:   []Hook{{Path: "/convert", Type: Conversion, Convert: convert, WebhookName: "convert.example.com"}},
+			wantErr: "not supported for Conversion hooks",
+		},
+		{
+			name:    "convert function on admission hook",
+			hooks:   []Hook{{Path: "/mutate", Type: Mutating, Admit: admit, Convert: convert}},
+			wantErr: "only supported for Conversion hooks",
+		},
+		{
+			name:    "unknown type",
+			hooks:   []Hook{{Path: "/mutate", Type: "Unknown", Admit: admit}},
+			wantErr: "type must be",
+		},
+		{
+			name: "rules on some hooks of a type",
+			hooks: []Hook{
+				{Path: "/mutate-pods", Type: Mutating, Admit: admit, Rules: podRules()},
+				{Path: "/mutate-services", Type: Mutating, Admit: admit},
+			},
+			wantErr: "rules are required",
+		},
+		{
+			name: "rules per type",
+			hooks: []Hook{
+				{Path: "/mutate-pods", Type: Mutating, Admit: admit, Rules: podRules()},
+				{Path: "/validate-pods", Type: Validating, Admit: admit},
+			},
+		},
+		{
+			name:    "reinvocation policy on validating hook",
+			hooks:   []Hook{{Path: "/validate", Type: Validating, Admit: admit, ReinvocationPolicy: ptr(admissionregistrationv1.IfNeededReinvocationPolicy)}},
+			wantErr: "reinvocation policy",
+		},
+		{
+			name:  "admission review versions",
+			hooks: []Hook{{Path: "/validate", Type: Validating, Admit: admit, Rules: podRules(), AdmissionReviewVersions: []string{"v1", "v1beta1"}}},
+		},
+		{
+			name:    "unsupported admission review version",
+			hooks:   []Hook{{Path: "/validate", Type: Validating, Admit: admit, Rules: podRules(), AdmissionReviewVersions: []string{"v2"}}},
+			wantErr: "admission review version",
+		},
+		{
+			name:  "configuration name",
+			hooks: []Hook{{Path: "/validate", Type: Validating, Admit: admit, ConfigurationName: "shared", WebhookName: "validate.example.com"}},
+		},
+		{
+			name:    "configuration name with rules",
+			hooks:   []Hook{{Path: "/validate", Type: Validating, Admit: admit, Rules: podRules(), ConfigurationName: "shared"}},
+			wantErr: "configuration name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHooks(tt.hooks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateHooks() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateHooks() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToServerMiddlewares(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next AdmitContextFunc) AdmitContextFunc {
+			return func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+				calls = append(calls, name)
+				return next(ctx, ar)
+			}
+		}
+	}
+
+	converted := toServerMiddlewares([]Middleware{record("first"), nil, record("second")})
+	if len(converted) != 2 {
+		t.Fatalf("Expected nil middlewares to be skipped, got %d", len(converted))
+	}
+
+	admit := func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+		calls = append(calls, "handler")
+		return Allowed()
+	}
+	for i := len(converted) - 1; i >= 0; i-- {
+		admit = converted[i](admit)
+	}
+
+	if resp := admit(context.Background(), admissionv1.AdmissionReview{}); !resp.Allowed {
+		t.Error("Expected Allowed=true")
+	}
+	if strings.Join(calls, ",") != "first,second,handler" {
+		t.Errorf("Unexpected call order: %v", calls)
+	}
+}
+
 func TestDetermineWebhookRefs(t *testing.T) {
 	t.Run("mutating only", func(t *testing.T) {
 		hooks := []Hook{
@@ -521,8 +906,193 @@ func TestDetermineWebhookRefs(t *testing.T) {
 			t.Errorf("Expected 0 refs, got %d", len(refs))
 		}
 	})
+
+	t.Run("configuration and webhook names", func(t *testing.T) {
+		hooks := []Hook{
+			{Path: "/validate-pods", Type: Validating, ConfigurationName: "shared", WebhookName: "pods.example.com"},
+			{Path: "/validate-services", Type: Validating, ConfigurationName: "shared", WebhookName: "services.example.com"},
+			{Path: "/validate-nodes", Type: Validating, ConfigurationName: "shared", WebhookName: "services.example.com"},
+			{Path: "/mutate", Type: Mutating},
+		}
+
+		refs := determineWebhookRefs("my-webhook", hooks)
+
+		want := []cabundle.WebhookRef{
+			{Name: "shared", Type: cabundle.ValidatingWebhook, WebhookName: "pods.example.com"},
+			{Name: "shared", Type: cabundle.ValidatingWebhook, WebhookName: "services.example.com"},
+			{Name: "my-webhook", Type: cabundle.MutatingWebhook},
+		}
+		if !reflect.DeepEqual(refs, want) {
+			t.Errorf("refs: got %+v, want %+v", refs, want)
+		}
+	})
+
+	t.Run("named entries covered by configuration", func(t *testing.T) {
+		hooks := []Hook{
+			{Path: "/validate-pods", Type: Validating, WebhookName: "pods.example.com"},
+			{Path: "/validate-services", Type: Validating},
+		}
+
+		refs := determineWebhookRefs("my-webhook", hooks)
+
+		want := []cabundle.WebhookRef{{Name: "my-webhook", Type: cabundle.ValidatingWebhook}}
+		if !reflect.DeepEqual(refs, want) {
+			t.Errorf("refs: got %+v, want %+v", refs, want)
+		}
+	})
+
+	t.Run("managed types skipped", func(t *testing.T) {
+		hooks := []Hook{
+			{Path: "/mutate", Type: Mutating, Rules: podRules()},
+			{Path: "/validate", Type: Validating},
+		}
+
+		refs := determineWebhookRefs("my-webhook", hooks)
+
+		if len(refs) != 1 {
+			t.Fatalf("Expected 1 ref, got %d", len(refs))
+		}
+		if refs[0].Type != cabundle.ValidatingWebhook {
+			t.Errorf("Type: got %v, want %v", refs[0].Type, cabundle.ValidatingWebhook)
+		}
+	})
+}
+
+func TestDetermineResourceRefs(t *testing.T) {
+	cfg := Config{
+		CABundleCRDs:        []string{"widgets.example.com"},
+		CABundleAPIServices: []string{"v1beta1.metrics.example.com"},
+	}
+
+	hooks := []Hook{
+		{Path: "/validate", Type: Validating},
+		{Path: "/convert", Type: Conversion, CustomResourceDefinitions: []string{"widgets.example.com", "gadgets.example.com"}},
+	}
+
+	refs := determineResourceRefs(cfg, hooks)
+
+	want := []cabundle.WebhookRef{
+		{Name: "widgets.example.com", Type: cabundle.CRDConversionWebhook},
+		{Name: "gadgets.example.com", Type: cabundle.CRDConversionWebhook},
+		{Name: "v1beta1.metrics.example.com", Type: cabundle.APIServiceWebhook},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("refs: got %+v, want %+v", refs, want)
+	}
+	if !needsDynamicClient(cfg, refs) {
+		t.Error("expected a dynamic client to be needed for CRDs and APIServices")
+	}
+	if needsDynamicClient(Config{CertSource: CertSourceSelfSigned}, determineWebhookRefs("my-webhook", hooks)) {
+		t.Error("expected no dynamic client to be needed for webhook configurations")
+	}
+}
+
+func TestToWebhookRef(t *testing.T) {
+	tests := []struct {
+		ref  cabundle.WebhookRef
+		want WebhookRef
+	}{
+		{
+			ref:  cabundle.WebhookRef{Name: "my-webhook", Type: cabundle.MutatingWebhook, WebhookName: "pods.example.com"},
+			want: WebhookRef{Kind: "MutatingWebhookConfiguration", Name: "my-webhook", Type: Mutating, WebhookName: "pods.example.com"},
+		},
+		{
+			ref:  cabundle.WebhookRef{Name: "widgets.example.com", Type: cabundle.CRDConversionWebhook},
+			want: WebhookRef{Kind: "CustomResourceDefinition", Name: "widgets.example.com", Type: Conversion},
+		},
+		{
+			ref:  cabundle.WebhookRef{Name: "v1beta1.metrics.example.com", Type: cabundle.APIServiceWebhook},
+			want: WebhookRef{Kind: "APIService", Name: "v1beta1.metrics.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := toWebhookRef(tt.ref); got != tt.want {
+			t.Errorf("toWebhookRef(%+v): got %+v, want %+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestDetermineWebhookConfig(t *testing.T) {
+	cfg := Config{
+		Name:                  "my-webhook",
+		Namespace:             "test-ns",
+		ServiceName:           "my-svc",
+		ServicePort:           8443,
+		CABundleConfigMapName: "my-webhook-ca-bundle",
+	}
+	timeout := int32(5)
+	hooks := []Hook{
+		{Path: "/mutate-pods", Type: Mutating, Rules: podRules(), TimeoutSeconds: &timeout},
+		{Path: "/validate/pods_v1", Type: Validating, Rules: podRules(), WebhookName: "pods.example.com", AdmissionReviewVersions: []string{"v1", "v1beta1"}},
+		{Path: "/validate-unmanaged", Type: Validating},
+	}
+
+	webhookConfig := determineWebhookConfig(cfg, hooks)
+
+	if webhookConfig.Name != "my-webhook" || webhookConfig.Namespace != "test-ns" || webhookConfig.ServiceName != "my-svc" {
+		t.Errorf("Unexpected config: %+v", webhookConfig)
+	}
+	if webhookConfig.ServicePort != 8443 {
+		t.Errorf("ServicePort: got %d, want %d", webhookConfig.ServicePort, 8443)
+	}
+	if webhookConfig.CABundleConfigMapName != "my-webhook-ca-bundle" {
+		t.Errorf("CABundleConfigMapName: got %q", webhookConfig.CABundleConfigMapName)
+	}
+	if len(webhookConfig.MutatingWebhooks) != 1 {
+		t.Fatalf("Expected 1 mutating webhook, got %d", len(webhookConfig.MutatingWebhooks))
+	}
+	if got := webhookConfig.MutatingWebhooks[0].Name; got != "mutate-pods.my-svc.test-ns.svc" {
+		t.Errorf("Mutating webhook name: got %q", got)
+	}
+	if got := webhookConfig.MutatingWebhooks[0].TimeoutSeconds; got == nil || *got != 5 {
+		t.Errorf("TimeoutSeconds: got %v, want 5", got)
+	}
+	if len(webhookConfig.ValidatingWebhooks) != 1 {
+		t.Fatalf("Expected 1 validating webhook, got %d", len(webhookConfig.ValidatingWebhooks))
+	}
+	if got := webhookConfig.ValidatingWebhooks[0].Name; got != "pods.example.com" {
+		t.Errorf("Validating webhook name: got %q", got)
+	}
+	if got := webhookConfig.ValidatingWebhooks[0].AdmissionReviewVersions; !reflect.DeepEqual(got, []string{"v1", "v1beta1"}) {
+		t.Errorf("AdmissionReviewVersions: got %v, want [v1 v1beta1]", got)
+	}
+}
+
+func TestDefaultWebhookName(t *testing.T) {
+	cfg := Config{ServiceName: "my-svc", Namespace: "test-ns"}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/mutate-pods", "mutate-pods.my-svc.test-ns.svc"},
+		{"/validate/Pods_v1", "validate-pods-v1.my-svc.test-ns.svc"},
+		{"/", "webhook.my-svc.test-ns.svc"},
+	}
+
+	for _, tt := range tests {
+		if got := defaultWebhookName(cfg, tt.path); got != tt.want {
+			t.Errorf("defaultWebhookName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
 }
 
+func podRules() []admissionregistrationv1.RuleWithOperations {
+	return []admissionregistrationv1.RuleWithOperations{
+		{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"pods"},
+			},
+		},
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
 func TestNewLeaderComponentsReturnsFreshInstances(t *testing.T) {
 	cfg := Config{
 		Namespace:             "test-ns",
@@ -539,8 +1109,8 @@ func TestNewLeaderComponentsReturnsFreshInstances(t *testing.T) {
 	webhookRefs := []cabundle.WebhookRef{{Name: "test", Type: cabundle.ValidatingWebhook}}
 	client := fake.NewClientset()
 
-	certMgr1, syncer1 := newLeaderComponents(client, cfg, webhookRefs)
-	certMgr2, syncer2 := newLeaderComponents(client, cfg, webhookRefs)
+	certMgr1, syncer1 := newLeaderComponents(client, nil, cfg, webhookRefs, nil)
+	certMgr2, syncer2 := newLeaderComponents(client, nil, cfg, webhookRefs, nil)
 
 	if certMgr1 == certMgr2 {
 		t.Fatal("expected fresh cert manager instance per call")
@@ -565,7 +1135,22 @@ func TestNewLeaderComponentConfigs(t *testing.T) {
 	}
 	webhookRefs := []cabundle.WebhookRef{{Name: "test", Type: cabundle.ValidatingWebhook}}
 
+	cfg.CertKeyAlgorithm = KeyAlgorithmECDSA
+	cfg.CertKeySize = 384
+	cfg.ClusterDomain = "cluster.local"
+	cfg.ExtraDNSNames = []string{"webhook.example.com"}
+	cfg.IPAddresses = []string{"10.0.0.1"}
+
 	certCfg := newLeaderCertManagerConfig(cfg)
+	if certCfg.CertKey.Algorithm != certmanager.KeyAlgorithmECDSA || certCfg.CertKey.Size != 384 {
+		t.Fatalf("cert manager cert key = %v, want ECDSA-384", certCfg.CertKey)
+	}
+	if certCfg.ClusterDomain != cfg.ClusterDomain {
+		t.Fatalf("cert manager cluster domain = %q, want %q", certCfg.ClusterDomain, cfg.ClusterDomain)
+	}
+	if !reflect.DeepEqual(certCfg.ExtraDNSNames, cfg.ExtraDNSNames) || !reflect.DeepEqual(certCfg.IPAddresses, cfg.IPAddresses) {
+		t.Fatalf("cert manager extra names = %q %q, want %q %q", certCfg.ExtraDNSNames, certCfg.IPAddresses, cfg.ExtraDNSNames, cfg.IPAddresses)
+	}
 	if certCfg.Namespace != cfg.Namespace {
 		t.Fatalf("cert manager namespace = %q, want %q", certCfg.Namespace, cfg.Namespace)
 	}
@@ -575,6 +1160,18 @@ func TestNewLeaderComponentConfigs(t *testing.T) {
 	if certCfg.CABundleConfigMapName != cfg.CABundleConfigMapName {
 		t.Fatalf("cert manager configmap name = %q, want %q", certCfg.CABundleConfigMapName, cfg.CABundleConfigMapName)
 	}
+	if certCfg.SignerName != "" {
+		t.Fatalf("cert manager signer name = %q, want empty for cert source %s", certCfg.SignerName, cfg.CertSource)
+	}
+
+	csrCfg := cfg
+	csrCfg.CertSource = CertSourceCSR
+	csrCfg.CSRSignerName = "example.com/webhook-serving"
+	csrCfg.CSRAutoApprove = ptr(true)
+	certCfg = newLeaderCertManagerConfig(csrCfg)
+	if certCfg.SignerName != csrCfg.CSRSignerName || !certCfg.CSRAutoApprove {
+		t.Fatalf("cert manager CSR config = %q %v, want %q true", certCfg.SignerName, certCfg.CSRAutoApprove, csrCfg.CSRSignerName)
+	}
 
 	syncerCfg := newLeaderSyncerConfig(cfg, webhookRefs)
 	if syncerCfg.Namespace != cfg.Namespace {
@@ -0,0 +1,129 @@
+package autocertwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TypedValidateFunc handles a validating admission request with decoded objects.
+// obj is the zero value for DELETE requests and oldObj is the zero value
+// unless the request carries an old object (UPDATE and DELETE).
+type TypedValidateFunc[T runtime.Object] func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj T) *admissionv1.AdmissionResponse
+
+// TypedMutateFunc handles a mutating admission request with decoded objects.
+// obj is a private copy that may be modified in place and returned; the
+// framework diffs the returned object against the request object into a
+// JSON patch.
+type TypedMutateFunc[T runtime.Object] func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj T) (T, error)
+
+// TypedValidatingHook creates a Validating hook that decodes Object and
+// OldObject into T before calling validate.
+// T must be a pointer to a struct, e.g. *corev1.Pod.
+func TypedValidatingHook[T runtime.Object](path string, validate TypedValidateFunc[T]) Hook {
+	return Hook{
+		Path: path,
+		Type: Validating,
+		AdmitContext: func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+			obj, oldObj, err := decodeTypedObjects[T](ar.Request)
+			if err != nil {
+				return Errored(err)
+			}
+			return validate(ctx, ar.Request, obj, oldObj)
+		},
+	}
+}
+
+// TypedMutatingHook creates a Mutating hook that decodes Object and OldObject
+// into T, calls mutate, and returns the difference between the raw request
+// object and the returned object as a JSON patch via PatchResponseFromRaw.
+// Fields of the request object that T does not know are removed by the patch.
+// T must be a pointer to a struct, e.g. *corev1.Pod or a custom resource type.
+func TypedMutatingHook[T runtime.Object](path string, mutate TypedMutateFunc[T]) Hook {
+	return Hook{
+		Path: path,
+		Type: Mutating,
+		AdmitContext: func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+			obj, oldObj, err := decodeTypedObjects[T](ar.Request)
+			if err != nil {
+				return Errored(err)
+			}
+			if isNilObject(obj) {
+				// Nothing to mutate, e.g. DELETE requests.
+				return Allowed()
+			}
+
+			modified, err := mutate(ctx, ar.Request, obj, oldObj)
+			if err != nil {
+				return Errored(err)
+			}
+			if isNilObject(modified) {
+				return Allowed()
+			}
+
+			marshaled, err := json.Marshal(modified)
+			if err != nil {
+				return Errored(fmt.Errorf("failed to marshal modified object: %w", err))
+			}
+			return PatchResponseFromRaw(ar.Request.Object.Raw, marshaled)
+		},
+	}
+}
+
+// decodeTypedObjects decodes the request's Object and OldObject into T.
+func decodeTypedObjects[T runtime.Object](req *admissionv1.AdmissionRequest) (T, T, error) {
+	var obj, oldObj T
+	if req == nil {
+		return obj, oldObj, fmt.Errorf("admission request is nil")
+	}
+
+	if len(req.Object.Raw) > 0 {
+		decoded, err := decodeTypedObject[T](req.Object.Raw)
+		if err != nil {
+			return obj, oldObj, fmt.Errorf("failed to decode object: %w", err)
+		}
+		obj = decoded
+	}
+
+	if len(req.OldObject.Raw) > 0 {
+		decoded, err := decodeTypedObject[T](req.OldObject.Raw)
+		if err != nil {
+			return obj, oldObj, fmt.Errorf("failed to decode old object: %w", err)
+		}
+		oldObj = decoded
+	}
+
+	return obj, oldObj, nil
+}
+
+// decodeTypedObject decodes raw into a new T. Objects are decoded as plain
+// JSON, so T does not need to be registered in a scheme.
+func decodeTypedObject[T runtime.Object](raw []byte) (T, error) {
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return zero, fmt.Errorf("typed hook object must be a pointer to a struct, got %v", typ)
+	}
+
+	obj, ok := reflect.New(typ.Elem()).Interface().(T)
+	if !ok {
+		return zero, fmt.Errorf("failed to create object of type %v", typ)
+	}
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return zero, err
+	}
+	return obj, nil
+}
+
+// isNilObject reports whether obj is nil or a typed nil pointer.
+func isNilObject(obj runtime.Object) bool {
+	if obj == nil {
+		return true
+	}
+	v := reflect.ValueOf(obj)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,263 @@
+package autocertwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/appscode/jsonpatch"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newTypedReview(t *testing.T, operation admissionv1.Operation, obj, oldObj *corev1.Pod) admissionv1.AdmissionReview {
+	t.Helper()
+
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Operation: operation,
+	}
+	if obj != nil {
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatalf("Failed to marshal object: %v", err)
+		}
+		req.Object = runtime.RawExtension{Raw: raw}
+	}
+	if oldObj != nil {
+		raw, err := json.Marshal(oldObj)
+		if err != nil {
+			t.Fatalf("Failed to marshal old object: %v", err)
+		}
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+
+	return admissionv1.AdmissionReview{Request: req}
+}
+
+func newTestPod(name string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+	}
+}
+
+func TestTypedValidatingHook(t *testing.T) {
+	t.Run("decodes object and old object", func(t *testing.T) {
+		var gotObj, gotOld *corev1.Pod
+		hook := TypedValidatingHook("/validate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) *admissionv1.AdmissionResponse {
+			gotObj, gotOld = obj, oldObj
+			return Allowed()
+		})
+
+		if hook.Type != Validating {
+			t.Errorf("Type: got %q, want %q", hook.Type, Validating)
+		}
+		if hook.Path != "/validate-pods" {
+			t.Errorf("Path: got %q, want %q", hook.Path, "/validate-pods")
+		}
+
+		review := newTypedReview(t, admissionv1.Update, newTestPod("new", nil), newTestPod("old", nil))
+		resp := hook.AdmitContext(context.Background(), review)
+
+		if !resp.Allowed {
+			t.Error("Expected Allowed=true")
+		}
+		if gotObj == nil || gotObj.Name != "new" {
+			t.Errorf("Expected decoded object named %q, got %+v", "new", gotObj)
+		}
+		if gotOld == nil || gotOld.Name != "old" {
+			t.Errorf("Expected decoded old object named %q, got %+v", "old", gotOld)
+		}
+	})
+
+	t.Run("nil object for delete", func(t *testing.T) {
+		var gotObj, gotOld *corev1.Pod
+		hook := TypedValidatingHook("/validate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) *admissionv1.AdmissionResponse {
+			gotObj, gotOld = obj, oldObj
+			return Denied("no deletes")
+		})
+
+		review := newTypedReview(t, admissionv1.Delete, nil, newTestPod("old", nil))
+		resp := hook.AdmitContext(context.Background(), review)
+
+		if resp.Allowed {
+			t.Error("Expected Allowed=false")
+		}
+		if gotObj != nil {
+			t.Errorf("Expected nil object, got %+v", gotObj)
+		}
+		if gotOld == nil || gotOld.Name != "old" {
+			t.Errorf("Expected decoded old object named %q, got %+v", "old", gotOld)
+		}
+	})
+
+	t.Run("invalid object", func(t *testing.T) {
+		called := false
+		hook := TypedValidatingHook("/validate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) *admissionv1.AdmissionResponse {
+			called = true
+			return Allowed()
+		})
+
+		review := admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{
+			Object: runtime.RawExtension{Raw: []byte(`{invalid`)},
+		}}
+		resp := hook.AdmitContext(context.Background(), review)
+
+		if resp.Allowed {
+			t.Error("Expected Allowed=false")
+		}
+		if called {
+			t.Error("Expected validate not to be called")
+		}
+	})
+}
+
+func TestTypedMutatingHook(t *testing.T) {
+	t.Run("diffs returned object into patch", func(t *testing.T) {
+		hook := TypedMutatingHook("/mutate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) (*corev1.Pod, error) {
+			if obj.Labels == nil {
+				obj.Labels = map[string]string{}
+			}
+			obj.Labels["mutated"] = "true"
+			return obj, nil
+		})
+
+		if hook.Type != Mutating {
+			t.Errorf("Type: got %q, want %q", hook.Type, Mutating)
+		}
+
+		review := newTypedReview(t, admissionv1.Create, newTestPod("test", nil), nil)
+		resp := hook.AdmitContext(context.Background(), review)
+
+		if !resp.Allowed {
+			t.Fatalf("Expected Allowed=true, got %+v", resp.Result)
+		}
+		if resp.PatchType == nil || *resp.PatchType != admissionv1.PatchTypeJSONPatch {
+			t.Error("Expected PatchType=JSONPatch")
+		}
+
+		var patches []jsonpatch.JsonPatchOperation
+		if err := json.Unmarshal(resp.Patch, &patches); err != nil {
+			t.Fatalf("Failed to unmarshal patch: %v", err)
+		}
+		if len(patches) != 1 || patches[0].Path != "/metadata/labels" {
+			t.Errorf("Expected single patch on /metadata/labels, got %+v", patches)
+		}
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		hook := TypedMutatingHook("/mutate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) (*corev1.Pod, error) {
+			return obj, nil
+		})
+
+		review := newTypedReview(t, admissionv1.Create, newTestPod("test", map[string]string{"a": "b"}), nil)
+		resp := hook.AdmitContext(context.Background(), review)
+
+		if !resp.Allowed {
+			t.Error("Expected Allowed=true")
+		}
+		if resp.Patch != nil {
+			t.Errorf("Expected nil patch, got %s", resp.Patch)
+		}
+	})
+
+	t.Run("error from mutate", func(t *testing.T) {
+		hook := TypedMutatingHook("/mutate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) (*corev1.Pod, error) {
+			return nil, errors.New("boom")
+		})
+
+		review := newTypedReview(t, admissionv1.Create, newTestPod("test", nil), nil)
+		resp := hook.AdmitContext(context.Background(), review)
+
+		if resp.Allowed {
+			t.Error("Expected Allowed=false")
+		}
+		if resp.Result == nil || resp.Result.Message != "boom" {
+			t.Errorf("Expected error message %q, got %+v", "boom", resp.Result)
+		}
+	})
+
+	t.Run("delete is allowed without calling mutate", func(t *testing.T) {
+		called := false
+		hook := TypedMutatingHook("/mutate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) (*corev1.Pod, error) {
+			called = true
+			return obj, nil
+		})
+
+		review := newTypedReview(t, admissionv1.Delete, nil, newTestPod("old", nil))
+		resp := hook.AdmitContext(context.Background(), review)
+
+		if !resp.Allowed {
+			t.Error("Expected Allowed=true")
+		}
+		if called {
+			t.Error("Expected mutate not to be called")
+		}
+	})
+}
+
+// testWidget is a custom resource type that is not registered in any scheme.
+type testWidget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              testWidgetSpec `json:"spec"`
+}
+
+type testWidgetSpec struct {
+	Size     int64 `json:"size"`
+	Replicas int64 `json:"replicas,omitempty"`
+}
+
+func (w *testWidget) DeepCopyObject() runtime.Object {
+	out := *w
+	w.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	return &out
+}
+
+func TestTypedMutatingHook_CustomResource(t *testing.T) {
+	hook := TypedMutatingHook("/mutate-widgets", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *testWidget) (*testWidget, error) {
+		obj.Spec.Replicas = obj.Spec.Size
+		return obj, nil
+	})
+
+	raw := []byte(`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"test","namespace":"default"},"spec":{"size":3}}`)
+	review := admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+	resp := hook.AdmitContext(context.Background(), review)
+
+	if !resp.Allowed {
+		t.Fatalf("Expected Allowed=true, got %+v", resp.Result)
+	}
+
+	// The patch is computed against the raw request, so apiVersion and kind
+	// are kept.
+	var patches []jsonpatch.JsonPatchOperation
+	if err := json.Unmarshal(resp.Patch, &patches); err != nil {
+		t.Fatalf("Failed to unmarshal patch: %v", err)
+	}
+	if len(patches) != 1 || patches[0].Operation != "add" || patches[0].Path != "/spec/replicas" {
+		t.Errorf("Expected single add on /spec/replicas, got %+v", patches)
+	}
+}
+
+func TestTypedHook_PassesValidation(t *testing.T) {
+	hooks := []Hook{
+		TypedMutatingHook("/mutate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) (*corev1.Pod, error) {
+			return obj, nil
+		}),
+		TypedValidatingHook("/validate-pods", func(ctx context.Context, req *admissionv1.AdmissionRequest, obj, oldObj *corev1.Pod) *admissionv1.AdmissionResponse {
+			return Allowed()
+		}),
+	}
+
+	if err := validateHooks(hooks); err != nil {
+		t.Fatalf("validateHooks() error = %v", err)
+	}
+}
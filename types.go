@@ -3,9 +3,14 @@
 package autocertwebhook
 
 import (
+	"context"
+	"crypto/x509"
 	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 // HookType defines the type of admission webhook.
@@ -16,21 +21,147 @@ const (
 	Mutating HookType = "Mutating"
 	// Validating indicates a validating admission webhook.
 	Validating HookType = "Validating"
+	// Conversion indicates a CustomResourceDefinition conversion webhook.
+	Conversion HookType = "Conversion"
+)
+
+// CertSource defines where the serving certificate comes from.
+type CertSource string
+
+const (
+	// CertSourceSelfSigned generates and rotates a self-signed CA and serving
+	// certificate stored in Secrets.
+	CertSourceSelfSigned CertSource = "SelfSigned"
+	// CertSourceFile loads the serving certificate from files managed outside
+	// the framework, e.g. by the cert-manager CSI driver or a Vault Agent sidecar.
+	CertSourceFile CertSource = "File"
+	// CertSourceCertManager delegates issuance to cert-manager: the framework
+	// creates a cert-manager.io/v1 Certificate for the service DNS names and
+	// serves the certificate from the resulting Secret.
+	CertSourceCertManager CertSource = "CertManager"
+	// CertSourceCSR requests the serving certificate through the Kubernetes
+	// certificates.k8s.io/v1 CertificateSigningRequest API with a custom
+	// signer. The caBundle is read from the CA bundle configmap, which must be
+	// populated with the signer's CA outside the framework.
+	CertSourceCSR CertSource = "CSR"
+)
+
+// KeyAlgorithm defines the public key algorithm of generated certificates.
+type KeyAlgorithm string
+
+const (
+	// KeyAlgorithmRSA generates RSA keys.
+	KeyAlgorithmRSA KeyAlgorithm = "RSA"
+	// KeyAlgorithmECDSA generates ECDSA keys on the NIST curve matching the key size.
+	KeyAlgorithmECDSA KeyAlgorithm = "ECDSA"
+	// KeyAlgorithmEd25519 generates Ed25519 keys.
+	KeyAlgorithmEd25519 KeyAlgorithm = "Ed25519"
 )
 
 // AdmitFunc is the function signature for handling admission requests.
 type AdmitFunc func(ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse
 
+// AdmitContextFunc is the context-aware variant of AdmitFunc.
+// The context is derived from the HTTP request, so it is cancelled when the
+// API server disconnects, and carries a deadline taken from the "timeout"
+// query parameter the API server sends (the webhook's timeoutSeconds).
+type AdmitContextFunc func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse
+
+// ConvertFunc converts a custom resource to desiredAPIVersion for a
+// CustomResourceDefinition conversion webhook. It is called once per object
+// of a ConversionReview, and only for objects not already in the desired
+// version. The returned object must have desiredAPIVersion as apiVersion and
+// keep the metadata of obj except for labels and annotations. The context
+// carries the deadline of the API server request.
+type ConvertFunc func(ctx context.Context, obj *unstructured.Unstructured, desiredAPIVersion string) (*unstructured.Unstructured, error)
+
+// Middleware wraps an admission handler, e.g. for logging, panic recovery,
+// namespace exclusion or metrics. Middlewares can be applied globally by
+// implementing MiddlewareProvider, or per Hook via Hook.Middlewares.
+type Middleware func(next AdmitContextFunc) AdmitContextFunc
+
 // Hook defines a single admission webhook endpoint.
 type Hook struct {
 	// Path is the URL path for this webhook, e.g., "/mutate-pods".
 	Path string
 
-	// Type is the webhook type: Mutating or Validating.
+	// Type is the webhook type: Mutating, Validating or Conversion.
 	Type HookType
 
 	// Admit handles the admission request.
+	// Exactly one of Admit or AdmitContext must be set, except for
+	// Conversion hooks.
 	Admit AdmitFunc
+
+	// AdmitContext handles the admission request with a context that is
+	// bounded by the API server's webhook timeout.
+	// Exactly one of Admit or AdmitContext must be set, except for
+	// Conversion hooks.
+	AdmitContext AdmitContextFunc
+
+	// Convert converts the objects of an apiextensions.k8s.io ConversionReview.
+	// Required for Conversion hooks, and the only field they may set besides
+	// Path, Type and CustomResourceDefinitions.
+	Convert ConvertFunc
+
+	// CustomResourceDefinitions are the names of the CustomResourceDefinitions
+	// converted by a Conversion hook, e.g. "widgets.example.com". Their
+	// conversion webhook gets the caBundle synced, see Config.CABundleCRDs.
+	CustomResourceDefinitions []string
+
+	// Middlewares wrap this hook's handler. They run after the global
+	// middlewares from MiddlewareProvider, in the order given; the first
+	// middleware is the outermost. Middlewares do not apply to Conversion hooks.
+	Middlewares []Middleware
+
+	// ConfigurationName is the name of the existing webhook configuration
+	// holding this hook's entry, for hooks without Rules. Only entries
+	// referencing ServiceName in Namespace get their caBundle synced, so the
+	// configuration can be shared with other webhooks.
+	// If empty, defaults to Config.Name.
+	ConfigurationName string
+
+	// The fields below are optional. When Rules is set, the leader creates and
+	// continuously reconciles the webhook configuration named Config.Name for
+	// this hook's type, including caBundle and the service reference.
+	// Either all hooks of a type set Rules or none of them do.
+
+	// WebhookName is the name of the webhook entry in the generated configuration.
+	// If empty, defaults to "<path>.<ServiceName>.<Namespace>.svc".
+	// For hooks without Rules, it restricts the caBundle sync to the named
+	// entry of the existing configuration, which may then also use a URL
+	// instead of the service; if empty, all entries referencing the service
+	// are synced.
+	WebhookName string
+
+	// Rules describes what operations on what resources the webhook cares about.
+	Rules []admissionregistrationv1.RuleWithOperations
+
+	// NamespaceSelector decides whether to run the webhook based on the object's namespace.
+	NamespaceSelector *metav1.LabelSelector
+
+	// ObjectSelector decides whether to run the webhook based on the object's labels.
+	ObjectSelector *metav1.LabelSelector
+
+	// FailurePolicy defines how errors from the webhook are handled. Defaults to Fail.
+	FailurePolicy *admissionregistrationv1.FailurePolicyType
+
+	// SideEffects states whether the webhook has side effects. Defaults to None.
+	SideEffects *admissionregistrationv1.SideEffectClass
+
+	// TimeoutSeconds specifies the timeout for the webhook call. Defaults to 10.
+	TimeoutSeconds *int32
+
+	// MatchPolicy defines how Rules match incoming requests. Defaults to Equivalent.
+	MatchPolicy *admissionregistrationv1.MatchPolicyType
+
+	// ReinvocationPolicy applies to Mutating hooks only. Defaults to Never.
+	ReinvocationPolicy *admissionregistrationv1.ReinvocationPolicyType
+
+	// AdmissionReviewVersions are the AdmissionReview versions the API server
+	// may send, in order of preference: "v1" and "v1beta1" are supported.
+	// Defaults to v1.
+	AdmissionReviewVersions []string
 }
 
 // Config contains all configuration for the webhook server.
@@ -39,7 +170,8 @@ type Hook struct {
 //
 // IMPORTANT: The framework expects the MutatingWebhookConfiguration and/or
 // ValidatingWebhookConfiguration resources to have the same name as Config.Name.
-// The framework will automatically patch the caBundle field of these resources.
+// The framework will automatically patch the caBundle field of these resources,
+// or create them entirely when the hooks define Rules.
 type Config struct {
 	// Name is the webhook name, used for generating certificate resources.
 	// This will be used as prefix for Secret, ConfigMap, and Lease names.
@@ -58,10 +190,22 @@ type Config struct {
 	// Env: ACW_SERVICE_NAME
 	ServiceName string `envconfig:"SERVICE_NAME"`
 
+	// Kubeconfig is the path to a kubeconfig file used to connect to the cluster,
+	// e.g. for running the webhook locally against a kind cluster.
+	// If empty, the KUBECONFIG environment variable is used when set,
+	// otherwise the in-cluster config.
+	// Env: ACW_KUBECONFIG
+	Kubeconfig string `envconfig:"KUBECONFIG"`
+
 	// Port is the port the webhook server listens on.
 	// Env: ACW_PORT
 	Port int `envconfig:"PORT" default:"8443"`
 
+	// ServicePort is the port of the Kubernetes service for the webhook.
+	// Used for the service reference of generated webhook configurations.
+	// Env: ACW_SERVICE_PORT
+	ServicePort int32 `envconfig:"SERVICE_PORT" default:"443"`
+
 	// MetricsEnabled enables the metrics server.
 	// Env: ACW_METRICS_ENABLED
 	MetricsEnabled *bool `envconfig:"METRICS_ENABLED" default:"true"`
@@ -97,6 +241,17 @@ type Config struct {
 	// Env: ACW_CA_BUNDLE_CONFIGMAP_NAME
 	CABundleConfigMapName string `envconfig:"CA_BUNDLE_CONFIGMAP_NAME"`
 
+	// CABundleCRDs are the names of CustomResourceDefinitions whose conversion
+	// webhook gets the caBundle synced. The conversion webhook must reference
+	// ServiceName in Namespace, or use a URL.
+	// Env: ACW_CA_BUNDLE_CRDS (comma separated)
+	CABundleCRDs []string `envconfig:"CA_BUNDLE_CRDS"`
+
+	// CABundleAPIServices are the names of aggregated APIServices served by
+	// ServiceName in Namespace that get the caBundle synced.
+	// Env: ACW_CA_BUNDLE_API_SERVICES (comma separated)
+	CABundleAPIServices []string `envconfig:"CA_BUNDLE_API_SERVICES"`
+
 	// CAValidity is the validity duration of the CA certificate.
 	// Env: ACW_CA_VALIDITY (e.g., "48h")
 	CAValidity time.Duration `envconfig:"CA_VALIDITY" default:"48h"`
@@ -117,6 +272,119 @@ type Config struct {
 	// Env: ACW_CERT_SYNC_INTERVAL (e.g., "1m")
 	CertSyncInterval time.Duration `envconfig:"CERT_SYNC_INTERVAL" default:"1m"`
 
+	// CAKeyAlgorithm is the key algorithm of the generated CA certificate.
+	// Env: ACW_CA_KEY_ALGORITHM (e.g., "ECDSA")
+	CAKeyAlgorithm KeyAlgorithm `envconfig:"CA_KEY_ALGORITHM" default:"RSA"`
+
+	// CAKeySize is the key size of the generated CA certificate: the modulus
+	// size in bits for RSA (default 2048) or the curve size for ECDSA
+	// (256, 384 or 521, default 256). Must be unset for Ed25519.
+	// Env: ACW_CA_KEY_SIZE
+	CAKeySize int `envconfig:"CA_KEY_SIZE"`
+
+	// CertKeyAlgorithm is the key algorithm of the generated serving certificate,
+	// or of the Certificate requested from cert-manager.
+	// Env: ACW_CERT_KEY_ALGORITHM (e.g., "ECDSA")
+	CertKeyAlgorithm KeyAlgorithm `envconfig:"CERT_KEY_ALGORITHM" default:"RSA"`
+
+	// CertKeySize is the key size of the generated serving certificate,
+	// see CAKeySize.
+	// Env: ACW_CERT_KEY_SIZE
+	CertKeySize int `envconfig:"CERT_KEY_SIZE"`
+
+	// ClusterDomain is the cluster DNS domain. The fully qualified service name
+	// "<ServiceName>.<Namespace>.svc.<ClusterDomain>" is added to the serving certificate.
+	// Env: ACW_CLUSTER_DOMAIN
+	ClusterDomain string `envconfig:"CLUSTER_DOMAIN" default:"cluster.local"`
+
+	// ExtraDNSNames are additional DNS names of the serving certificate,
+	// e.g. for webhook configurations that use a URL instead of the service.
+	// Env: ACW_EXTRA_DNS_NAMES (comma separated)
+	ExtraDNSNames []string `envconfig:"EXTRA_DNS_NAMES"`
+
+	// IPAddresses are the IP addresses of the serving certificate,
+	// e.g. for webhooks running on the host network.
+	// Env: ACW_IP_ADDRESSES (comma separated)
+	IPAddresses []string `envconfig:"IP_ADDRESSES"`
+
+	// CertCommonName is the subject common name of the serving certificate.
+	// If empty, defaults to ServiceName.
+	// Env: ACW_CERT_COMMON_NAME
+	CertCommonName string `envconfig:"CERT_COMMON_NAME"`
+
+	// CertOrganizations are the subject organizations of the serving certificate.
+	// Env: ACW_CERT_ORGANIZATIONS (comma separated)
+	CertOrganizations []string `envconfig:"CERT_ORGANIZATIONS"`
+
+	// ExternalCA signs the serving certificate with a CA provided in
+	// CASecretName instead of generating one, e.g. an organization
+	// intermediate CA. The secret is never modified: tls.crt holds the signing
+	// certificate followed by its chain, tls.key the signing key and the
+	// optional ca.crt the root certificate. Pods are not ready while the CA is
+	// missing, invalid or expired. Requires CertSource SelfSigned.
+	// Env: ACW_EXTERNAL_CA
+	ExternalCA *bool `envconfig:"EXTERNAL_CA"`
+
+	// CABundleFullChain adds the chain of the external CA, including ca.crt,
+	// to the caBundle instead of only the signing certificate.
+	// Env: ACW_CA_BUNDLE_FULL_CHAIN
+	CABundleFullChain *bool `envconfig:"CA_BUNDLE_FULL_CHAIN"`
+
+	// CertSource selects where the serving certificate comes from.
+	// With CertSourceFile, certificate generation is disabled and the caBundle
+	// is read from CAFile, or must be managed externally.
+	// With CertSourceCertManager, the certificate is issued by cert-manager into
+	// CertSecretName and the caBundle is read from the Secret's ca.crt key.
+	// Env: ACW_CERT_SOURCE (e.g., "File")
+	CertSource CertSource `envconfig:"CERT_SOURCE" default:"SelfSigned"`
+
+	// CertFile is the path to the PEM encoded serving certificate.
+	// Required when CertSource is File.
+	// Env: ACW_CERT_FILE
+	CertFile string `envconfig:"CERT_FILE"`
+
+	// KeyFile is the path to the PEM encoded serving key.
+	// Required when CertSource is File.
+	// Env: ACW_KEY_FILE
+	KeyFile string `envconfig:"KEY_FILE"`
+
+	// CAFile is the path to the PEM encoded CA bundle that signed CertFile,
+	// e.g. the ca.crt mounted next to it. The leader publishes it to
+	// CABundleConfigMapName, from which the caBundle is synced.
+	// Only supported when CertSource is File.
+	// Env: ACW_CA_FILE
+	CAFile string `envconfig:"CA_FILE"`
+
+	// CertPollInterval is the interval between checks of CertFile, KeyFile and CAFile for changes.
+	// Env: ACW_CERT_POLL_INTERVAL (e.g., "10s")
+	CertPollInterval time.Duration `envconfig:"CERT_POLL_INTERVAL" default:"10s"`
+
+	// CertManagerIssuerName is the name of the cert-manager issuer that signs
+	// the serving certificate when CertSource is CertManager.
+	// If empty, a self-signed Issuer named "<Name>-selfsigned" is created.
+	// Env: ACW_CERT_MANAGER_ISSUER_NAME
+	CertManagerIssuerName string `envconfig:"CERT_MANAGER_ISSUER_NAME"`
+
+	// CertManagerIssuerKind is the kind of the issuer, e.g. "Issuer" or "ClusterIssuer".
+	// Env: ACW_CERT_MANAGER_ISSUER_KIND
+	CertManagerIssuerKind string `envconfig:"CERT_MANAGER_ISSUER_KIND" default:"Issuer"`
+
+	// CertManagerIssuerGroup is the API group of the issuer, for external issuers.
+	// Env: ACW_CERT_MANAGER_ISSUER_GROUP
+	CertManagerIssuerGroup string `envconfig:"CERT_MANAGER_ISSUER_GROUP" default:"cert-manager.io"`
+
+	// CSRSignerName is the signerName of the CertificateSigningRequests
+	// submitted when CertSource is CSR, e.g. "example.com/webhook-serving".
+	// Required when CertSource is CSR.
+	// Env: ACW_CSR_SIGNER_NAME
+	CSRSignerName string `envconfig:"CSR_SIGNER_NAME"`
+
+	// CSRAutoApprove approves the submitted CertificateSigningRequests instead
+	// of waiting for an external approver. Requires permission to approve for
+	// CSRSignerName.
+	// Env: ACW_CSR_AUTO_APPROVE
+	CSRAutoApprove *bool `envconfig:"CSR_AUTO_APPROVE"`
+
 	// LeaderElection enables leader election for certificate rotation.
 	// Env: ACW_LEADER_ELECTION
 	LeaderElection *bool `envconfig:"LEADER_ELECTION"`
@@ -147,3 +415,48 @@ type Admission interface {
 	// Webhooks returns all webhook definitions.
 	Webhooks() []Hook
 }
+
+// MiddlewareProvider is an optional interface an Admission implementation can
+// satisfy to apply middlewares to every admission hook.
+type MiddlewareProvider interface {
+	// Middlewares returns the global middlewares, outermost first.
+	Middlewares() []Middleware
+}
+
+// WebhookRef identifies a webhook configuration, CustomResourceDefinition or
+// APIService whose caBundle is synced.
+type WebhookRef struct {
+	// Kind is the kind of the object, e.g. "ValidatingWebhookConfiguration",
+	// "CustomResourceDefinition" or "APIService".
+	Kind string
+	// Name is the name of the object.
+	Name string
+	// Type is the type of the webhook configuration, Conversion for
+	// CustomResourceDefinitions and empty for APIServices.
+	Type HookType
+	// WebhookName is the name of the synced webhook entry, or empty when all
+	// entries referencing the service are synced.
+	WebhookName string
+}
+
+// CertificateObserver is an optional interface an Admission implementation can
+// satisfy to be notified of certificate lifecycle events. Callbacks are called
+// synchronously by the component reporting the event, possibly concurrently,
+// and must not block.
+type CertificateObserver interface {
+	// OnServingCertReloaded is called on every replica after the serving
+	// certificate was loaded or reloaded.
+	OnServingCertReloaded(leaf *x509.Certificate)
+
+	// OnCARotated is called on the leader after it generated a new CA, or
+	// after the external CA was replaced.
+	OnCARotated(ca *x509.Certificate)
+
+	// OnCABundleSynced is called on the leader after the caBundle was patched
+	// into a webhook configuration, CustomResourceDefinition or APIService,
+	// with the error if patching failed. Failed syncs are retried with
+	// backoff, and each attempt is reported. Objects that already hold the
+	// CA bundle, e.g. on a periodic resync, are not patched or reported.
+	// Configurations managed from Hook.Rules are not reported.
+	OnCABundleSynced(ref WebhookRef, err error)
+}
@@ -0,0 +1,307 @@
+// Package webhooktest runs a complete webhook in-process for integration tests.
+//
+// Start boots the full stack (certificate management, caBundle syncing,
+// webhook and metrics servers) against a fake clientset, waits until the
+// webhook is ready and returns an HTTPS client that trusts the generated CA.
+package webhooktest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	autocertwebhook "github.com/jimyag/auto-cert-webhook"
+	"github.com/jimyag/auto-cert-webhook/internal/cabundle"
+	"github.com/jimyag/auto-cert-webhook/internal/dynamictest"
+)
+
+const (
+	// readyTimeout bounds how long Start waits for the webhook to become ready.
+	readyTimeout = 30 * time.Second
+
+	// readyPollInterval is the interval between readiness checks.
+	readyPollInterval = 100 * time.Millisecond
+)
+
+// Server is a webhook running in-process against a fake clientset.
+type Server struct {
+	// URL is the base URL of the webhook server, e.g. "https://127.0.0.1:41234".
+	URL string
+
+	// MetricsURL is the base URL of the metrics server.
+	// Empty when metrics are disabled.
+	MetricsURL string
+
+	// Client is an HTTPS client that trusts the webhook's generated CA.
+	Client *http.Client
+
+	// KubeClient is the fake clientset the webhook runs against.
+	KubeClient *fake.Clientset
+
+	// DynamicClient is the fake dynamic client the webhook runs against,
+	// serving CustomResourceDefinitions and APIServices.
+	DynamicClient *dynamicfake.FakeDynamicClient
+
+	// Config is the resolved webhook configuration.
+	Config autocertwebhook.Config
+
+	// done is closed once RunWithContext returned runErr.
+	done   chan struct{}
+	runErr error
+}
+
+// Start runs admission in the background and blocks until it is ready to
+// serve admission requests. The webhook is stopped when the test ends.
+// opts are applied after the harness options, e.g. WithClock; they must not
+// replace the client or listeners.
+func Start(t testing.TB, admission autocertwebhook.Admission, opts ...autocertwebhook.RunOption) *Server {
+	t.Helper()
+
+	cfg, err := autocertwebhook.ResolveConfig(admission)
+	if err != nil {
+		t.Fatalf("failed to resolve webhook config: %v", err)
+	}
+	if cfg.CertSource != autocertwebhook.CertSourceSelfSigned {
+		t.Fatalf("webhooktest only supports the %s cert source, got %s", autocertwebhook.CertSourceSelfSigned, cfg.CertSource)
+	}
+
+	client := fake.NewClientset()
+
+	dynamicClient := dynamictest.NewClient(map[schema.GroupVersionResource]string{
+		cabundle.CustomResourceDefinitionResource: "CustomResourceDefinition",
+		cabundle.APIServiceResource:               "APIService",
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	srv := &Server{
+		URL:           "https://" + listener.Addr().String(),
+		KubeClient:    client,
+		DynamicClient: dynamicClient,
+		Config:        cfg,
+		done:          make(chan struct{}),
+	}
+
+	runOpts := []autocertwebhook.RunOption{
+		autocertwebhook.WithKubeClient(client),
+		autocertwebhook.WithDynamicClient(dynamicClient),
+		autocertwebhook.WithListener(listener),
+	}
+	if cfg.MetricsEnabled == nil || *cfg.MetricsEnabled {
+		metricsListener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			listener.Close()
+			t.Fatalf("failed to listen for metrics: %v", err)
+		}
+		srv.MetricsURL = "http://" + metricsListener.Addr().String()
+		runOpts = append(runOpts, autocertwebhook.WithMetricsListener(metricsListener))
+	}
+	runOpts = append(runOpts, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer close(srv.done)
+		srv.runErr = autocertwebhook.RunWithContext(ctx, admission, runOpts...)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-srv.done
+		if srv.runErr != nil {
+			t.Errorf("webhook stopped with error: %v", srv.runErr)
+		}
+	})
+
+	if err := srv.waitReady(ctx); err != nil {
+		t.Fatalf("webhook did not become ready: %v", err)
+	}
+	return srv
+}
+
+// Review sends req to the hook at path wrapped in an admission.k8s.io/v1
+// AdmissionReview and returns the response.
+func (s *Server) Review(t testing.TB, path string, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	t.Helper()
+
+	body, err := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionv1.SchemeGroupVersion.String(),
+			Kind:       "AdmissionReview",
+		},
+		Request: req,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal admission review: %v", err)
+	}
+
+	resp, err := s.Client.Post(s.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to send admission review: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d from %s", resp.StatusCode, path)
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		t.Fatalf("failed to decode admission review: %v", err)
+	}
+	if review.Response == nil {
+		t.Fatalf("admission review from %s has no response", path)
+	}
+	return review.Response
+}
+
+// Convert sends objects to the Conversion hook at path wrapped in an
+// apiextensions.k8s.io/v1 ConversionReview and returns the converted objects,
+// or an error with the message of a failed conversion.
+func (s *Server) Convert(t testing.TB, path, desiredAPIVersion string, objects ...*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	t.Helper()
+
+	rawObjects := make([]runtime.RawExtension, 0, len(objects))
+	for _, obj := range objects {
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			t.Fatalf("failed to marshal object: %v", err)
+		}
+		rawObjects = append(rawObjects, runtime.RawExtension{Raw: data})
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "ConversionReview",
+		"request": map[string]interface{}{
+			"uid":               "webhooktest",
+			"desiredAPIVersion": desiredAPIVersion,
+			"objects":           rawObjects,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal conversion review: %v", err)
+	}
+
+	resp, err := s.Client.Post(s.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to send conversion review: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d from %s", resp.StatusCode, path)
+	}
+
+	var review struct {
+		Response *struct {
+			ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+			Result           metav1.Status          `json:"result"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		t.Fatalf("failed to decode conversion review: %v", err)
+	}
+	if review.Response == nil {
+		t.Fatalf("conversion review from %s has no response", path)
+	}
+	if review.Response.Result.Status != metav1.StatusSuccess {
+		return nil, errors.New(review.Response.Result.Message)
+	}
+
+	converted := make([]*unstructured.Unstructured, 0, len(review.Response.ConvertedObjects))
+	for _, raw := range review.Response.ConvertedObjects {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+			t.Fatalf("failed to decode converted object: %v", err)
+		}
+		converted = append(converted, obj)
+	}
+	return converted, nil
+}
+
+// waitReady polls the readiness endpoint until it succeeds with a client
+// that trusts the CA bundle published by the webhook.
+func (s *Server) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, readyPollInterval, true, func(ctx context.Context) (bool, error) {
+		select {
+		case <-s.done:
+			return false, fmt.Errorf("webhook exited: %v", s.runErr)
+		default:
+		}
+
+		client, err := s.newClient(ctx)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+s.Config.ReadyzPath, nil)
+		if err != nil {
+			return false, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("readiness check returned status %d", resp.StatusCode)
+			return false, nil
+		}
+
+		s.Client = client
+		return true, nil
+	})
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("%w (last error: %v)", err, lastErr)
+	}
+	return err
+}
+
+// newClient returns an HTTPS client that trusts the current CA bundle and
+// verifies the serving certificate against the webhook's service DNS name.
+func (s *Server) newClient(ctx context.Context) (*http.Client, error) {
+	cm, err := s.KubeClient.CoreV1().ConfigMaps(s.Config.Namespace).Get(ctx, s.Config.CABundleConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CA bundle configmap: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(cm.Data["ca-bundle.crt"])) {
+		return nil, fmt.Errorf("CA bundle configmap %s/%s has no certificates yet", s.Config.Namespace, s.Config.CABundleConfigMapName)
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    pool,
+				ServerName: fmt.Sprintf("%s.%s.svc", s.Config.ServiceName, s.Config.Namespace),
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	}, nil
+}
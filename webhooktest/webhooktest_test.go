@@ -0,0 +1,267 @@
+package webhooktest
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	testingclock "k8s.io/utils/clock/testing"
+
+	autocertwebhook "github.com/jimyag/auto-cert-webhook"
+	"github.com/jimyag/auto-cert-webhook/internal/cabundle"
+)
+
+type testWebhook struct{}
+
+func (w *testWebhook) Configure() autocertwebhook.Config {
+	return autocertwebhook.Config{
+		Name:      "test-webhook",
+		Namespace: "webhook-system",
+	}
+}
+
+func (w *testWebhook) Webhooks() []autocertwebhook.Hook {
+	return []autocertwebhook.Hook{
+		{
+			Path: "/validate",
+			Type: autocertwebhook.Validating,
+			AdmitContext: func(ctx context.Context, ar admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+				if ar.Request.Namespace == "forbidden" {
+					return autocertwebhook.Denied("namespace is forbidden")
+				}
+				return autocertwebhook.Allowed()
+			},
+		},
+	}
+}
+
+func TestStart(t *testing.T) {
+	srv := Start(t, &testWebhook{})
+
+	if srv.Config.ServiceName != "test-webhook" {
+		t.Errorf("ServiceName: got %q, want %q", srv.Config.ServiceName, "test-webhook")
+	}
+
+	resp := srv.Review(t, "/validate", &admissionv1.AdmissionRequest{
+		UID:       "allowed-uid",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Namespace: "default",
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"test"}}`)},
+	})
+	if !resp.Allowed {
+		t.Errorf("Expected Allowed=true, got %+v", resp.Result)
+	}
+	if resp.UID != "allowed-uid" {
+		t.Errorf("Expected UID %q, got %q", "allowed-uid", resp.UID)
+	}
+
+	resp = srv.Review(t, "/validate", &admissionv1.AdmissionRequest{
+		UID:       "denied-uid",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Namespace: "forbidden",
+		Operation: admissionv1.Create,
+	})
+	if resp.Allowed {
+		t.Error("Expected Allowed=false")
+	}
+
+	// The certificate was generated in the fake cluster
+	secret, err := srv.KubeClient.CoreV1().Secrets("webhook-system").Get(context.Background(), "test-webhook-cert", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get serving certificate secret: %v", err)
+	}
+	if len(secret.Data["tls.crt"]) == 0 {
+		t.Error("Expected serving certificate to be populated")
+	}
+
+	metricsResp, err := http.Get(srv.MetricsURL + srv.Config.MetricsPath)
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	metricsResp.Body.Close()
+	if metricsResp.StatusCode != http.StatusOK {
+		t.Errorf("Expected metrics status %d, got %d", http.StatusOK, metricsResp.StatusCode)
+	}
+}
+
+func TestStart_WithClock(t *testing.T) {
+	srv := Start(t, &testWebhook{}, autocertwebhook.WithClock(testingclock.NewFakeClock(time.Now())))
+
+	resp := srv.Review(t, "/validate", &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Namespace: "default",
+		Operation: admissionv1.Create,
+	})
+	if !resp.Allowed {
+		t.Errorf("Expected Allowed=true, got %+v", resp.Result)
+	}
+}
+
+// observingWebhook records the certificate lifecycle events it observes.
+type observingWebhook struct {
+	testWebhook
+
+	mu       sync.Mutex
+	reloaded []*x509.Certificate
+	rotated  []*x509.Certificate
+	synced   []autocertwebhook.WebhookRef
+}
+
+func (w *observingWebhook) OnServingCertReloaded(leaf *x509.Certificate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reloaded = append(w.reloaded, leaf)
+}
+
+func (w *observingWebhook) OnCARotated(ca *x509.Certificate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rotated = append(w.rotated, ca)
+}
+
+func (w *observingWebhook) OnCABundleSynced(ref autocertwebhook.WebhookRef, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err == nil {
+		w.synced = append(w.synced, ref)
+	}
+}
+
+func TestStart_CertificateObserver(t *testing.T) {
+	observer := &observingWebhook{}
+	srv := Start(t, observer)
+
+	// The caBundle is patched into the configuration once it exists.
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-webhook"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: "validate.example.com",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Namespace: "webhook-system", Name: "test-webhook"},
+			},
+		}},
+	}
+	if _, err := srv.KubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Create(context.Background(), config, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create webhook configuration: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		observer.mu.Lock()
+		done := len(observer.reloaded) > 0 && len(observer.rotated) > 0 && len(observer.synced) > 0
+		reloaded, rotated, synced := len(observer.reloaded), len(observer.rotated), observer.synced
+		observer.mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected all events, got %d reloads, %d CA rotations and %d syncs", reloaded, rotated, len(synced))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	want := autocertwebhook.WebhookRef{Kind: "ValidatingWebhookConfiguration", Name: "test-webhook", Type: autocertwebhook.Validating}
+	if observer.synced[0] != want {
+		t.Errorf("synced ref: got %+v, want %+v", observer.synced[0], want)
+	}
+	if err := observer.reloaded[len(observer.reloaded)-1].CheckSignatureFrom(observer.rotated[0]); err != nil {
+		t.Errorf("Expected the reloaded serving certificate to be signed by the rotated CA: %v", err)
+	}
+}
+
+type conversionWebhook struct{}
+
+func (w *conversionWebhook) Configure() autocertwebhook.Config {
+	return autocertwebhook.Config{
+		Name:      "test-webhook",
+		Namespace: "webhook-system",
+	}
+}
+
+func (w *conversionWebhook) Webhooks() []autocertwebhook.Hook {
+	return []autocertwebhook.Hook{
+		{
+			Path:                      "/convert",
+			Type:                      autocertwebhook.Conversion,
+			CustomResourceDefinitions: []string{"widgets.example.com"},
+			Convert: func(ctx context.Context, obj *unstructured.Unstructured, desiredAPIVersion string) (*unstructured.Unstructured, error) {
+				if desiredAPIVersion != "example.com/v2" {
+					return nil, fmt.Errorf("unsupported version %s", desiredAPIVersion)
+				}
+				out := obj.DeepCopy()
+				out.SetAPIVersion(desiredAPIVersion)
+				return out, nil
+			},
+		},
+	}
+}
+
+func TestStart_Conversion(t *testing.T) {
+	srv := Start(t, &conversionWebhook{})
+
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "test", "namespace": "default"},
+	}}
+	converted, err := srv.Convert(t, "/convert", "example.com/v2", widget)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(converted) != 1 || converted[0].GetAPIVersion() != "example.com/v2" {
+		t.Errorf("Expected a single example.com/v2 object, got %v", converted)
+	}
+
+	if _, err := srv.Convert(t, "/convert", "example.com/v3", widget); err == nil || !strings.Contains(err.Error(), "unsupported version") {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+
+	// The caBundle is injected into the CustomResourceDefinition once it exists.
+	crds := srv.DynamicClient.Resource(cabundle.CustomResourceDefinitionResource)
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "widgets.example.com"},
+		"spec": map[string]interface{}{
+			"conversion": map[string]interface{}{
+				"strategy": "Webhook",
+				"webhook": map[string]interface{}{
+					"clientConfig": map[string]interface{}{
+						"service": map[string]interface{}{"namespace": "webhook-system", "name": "test-webhook", "path": "/convert"},
+					},
+				},
+			},
+		},
+	}}
+	if _, err := crds.Create(context.Background(), crd, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create CRD: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		current, err := crds.Get(context.Background(), "widgets.example.com", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get CRD: %v", err)
+		}
+		if caBundle, _, _ := unstructured.NestedString(current.Object, "spec", "conversion", "webhook", "clientConfig", "caBundle"); caBundle != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the caBundle to be injected into the CRD")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}